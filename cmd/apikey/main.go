@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"backend-fileprocessing/internal/auth"
+)
+
+// Gera uma chave de API para um cliente. A chave em texto puro é exibida uma única vez;
+// o servidor guarda apenas a entrada com o hash (API_KEYS ou API_KEY_STORE).
+func main() {
+	id := flag.String("id", "", "identificador do cliente (aparece nos logs e no relatório de uso)")
+	scopes := flag.String("scopes", auth.ScopeProcess, "escopos separados por vírgula (process, admin)")
+	expires := flag.String("expires", "", "data de expiração AAAA-MM-DD (vazio: não expira)")
+	maxSizeMB := flag.Int("max-size-mb", 0, "tamanho máximo de arquivo em MB (0: limite global)")
+	flag.Parse()
+
+	if *id == "" {
+		log.Fatal("Informe o identificador do cliente com -id")
+	}
+
+	key, hash, err := auth.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	maxSize := ""
+	if *maxSizeMB > 0 {
+		maxSize = fmt.Sprint(*maxSizeMB)
+	}
+	entry := strings.Join([]string{*id, hash, strings.ReplaceAll(*scopes, ",", "|"), *expires, maxSize}, ":")
+	if _, err := auth.ParseKeyEntry(entry); err != nil {
+		log.Fatalf("Parâmetros inválidos: %v", err)
+	}
+
+	fmt.Printf("Chave (entregue ao cliente, não é armazenada): %s\n", key)
+	fmt.Printf("Entrada para API_KEYS: %s\n", strings.TrimRight(entry, ":"))
+}
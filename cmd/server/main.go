@@ -1,11 +1,18 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "backend-fileprocessing/docs"
 	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/logging"
 	"backend-fileprocessing/internal/server"
 
 	"github.com/joho/godotenv"
@@ -17,31 +24,67 @@ import (
 // @host localhost:9091
 // @BasePath /
 // @schemes http
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	// Carregar variáveis de ambiente do arquivo .env (se existir)
 	// Isso facilita desenvolvimento local - em produção use variáveis de ambiente reais
 	if err := godotenv.Load(); err != nil {
-		log.Printf("⚠️ Arquivo .env não encontrado (isso é normal em produção)")
+		slog.Info("arquivo .env não encontrado (isso é normal em produção)")
 	}
 
 	// Carregar configurações
 	cfg := config.Load()
 
-	router := server.NewRouter(cfg)
+	// O router instala o log estruturado; as linhas seguintes já saem no formato configurado
+	router, closeServices := server.NewRouterWithCleanup(cfg)
+
+	shutdownTracing, err := server.SetupTracing(cfg)
+	if err != nil {
+		logging.Fatal("configuração de tracing inválida", "error", err)
+	}
 
 	// Iniciar servidor
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = cfg.Port
 	}
+	srv := &http.Server{Addr: ":" + port, Handler: router}
+
+	slog.Info("servidor iniciado",
+		"port", port,
+		"health", "http://localhost:"+port+"/api/v1/health",
+		"swagger", "http://localhost:"+port+"/swagger/index.html",
+	)
 
-	log.Printf("🚀 Servidor iniciado na porta %s", port)
-	log.Printf("📁 Serviço de processamento de arquivos ativo")
-	log.Printf("🔗 Health check: http://localhost:%s/api/v1/health", port)
-	log.Printf("📤 Processar arquivo: http://localhost:%s/api/v1/files/process", port)
-	log.Printf("📚 Documentação Swagger: http://localhost:%s/swagger/index.html", port)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("erro ao iniciar servidor", "error", err)
+		}
+	}()
+
+	// Ao receber SIGINT/SIGTERM: para de aceitar conexões e espera as requisições em andamento,
+	// depois descarta os artefatos e por último envia os spans pendentes (inclusive os dessas requisições)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	slog.Info("encerrando servidor", "timeout", cfg.ShutdownTimeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("requisições ainda em andamento ao encerrar", "error", err)
+	}
+	closeServices()
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Erro ao iniciar servidor:", err)
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Warn("erro ao enviar os últimos spans", "error", err)
 	}
+	slog.Info("servidor encerrado")
 }
@@ -31,10 +31,36 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "file",
-                        "description": "Arquivo para processar (PDF, imagem, TXT, DOCX)",
+                        "description": "Arquivo para processar (PDF, imagem, TXT, DOCX, XML de NF-e/CT-e/NFS-e)",
                         "name": "file",
                         "in": "formData",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "off",
+                            "detect",
+                            "mask",
+                            "tokenize"
+                        ],
+                        "type": "string",
+                        "description": "Dados pessoais no texto: off, detect, mask ou tokenize (padrão: PII_MODE)",
+                        "name": "pii",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Gerar cópia do PDF ou da imagem com os dados pessoais tarjados",
+                        "name": "redact",
+                        "in": "formData"
+                    }
+                ],
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
                     }
                 ],
                 "responses": {
@@ -50,11 +76,97 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.Response"
                         }
                     },
+                    "401": {
+                        "description": "Credencial ausente, inválida ou expirada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Credencial sem o escopo necessário",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Arquivo acima do limite do tipo ou da credencial",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Arquivo recusado pela verificação de malware (MALWARE_DETECTED)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições, de processamentos simultâneos ou volume diário excedido (header Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
                     "500": {
                         "description": "Erro interno do servidor",
                         "schema": {
                             "$ref": "#/definitions/models.Response"
                         }
+                    },
+                    "503": {
+                        "description": "Scanner de malware indisponível (SCAN_FAILED)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/files/artifacts/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Baixa a cópia tarjada indicada em data.redacted.url; disponível até expiresAt e só para o cliente que a gerou",
+                "produces": [
+                    "application/pdf",
+                    "image/png"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Baixar arquivo gerado",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Id do arquivo gerado",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Credencial ausente, inválida ou expirada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Arquivo expirado, inexistente ou de outro cliente (ARTIFACT_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
                     }
                 }
             }
@@ -125,6 +237,14 @@ const docTemplate = `{
                     "health"
                 ],
                 "summary": "Status detalhado",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Status do serviço",
@@ -139,6 +259,98 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    },
+                    "401": {
+                        "description": "Credencial ausente, inválida ou expirada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Credencial sem o escopo necessário",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições excedido (header Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/usage": {
+            "get": {
+                "description": "Retorna tokens consumidos e custo estimado agregados por dia, cliente e modelo",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Relatório de uso",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Primeiro dia (AAAA-MM-DD, padrão: to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Último dia (AAAA-MM-DD, padrão: hoje em UTC)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtrar por cliente (sem escopo admin, apenas o próprio cliente)",
+                        "name": "client",
+                        "in": "query"
+                    }
+                ],
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Relatório de uso",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "success": {
+                                    "type": "boolean"
+                                },
+                                "data": {
+                                    "$ref": "#/definitions/models.UsageReport"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Período inválido",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Credencial ausente, inválida ou expirada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de requisições excedido (header Retry-After)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Response"
+                        }
                     }
                 }
             }
@@ -162,6 +374,23 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.ChunkFailure": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "firstPage": {
+                    "type": "integer"
+                },
+                "lastPage": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.Error": {
             "type": "object",
             "properties": {
@@ -173,6 +402,9 @@ const docTemplate = `{
                 },
                 "message": {
                     "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
                 }
             }
         },
@@ -213,6 +445,68 @@ const docTemplate = `{
                 },
                 "processingTime": {
                     "type": "string"
+                },
+                "scan": {
+                    "$ref": "#/definitions/models.ScanVerdict"
+                },
+                "usage": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TokenUsage"
+                    }
+                }
+            }
+        },
+        "models.PIIFinding": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "replacement": {
+                    "description": "Máscara ou token colocado no lugar do valor",
+                    "type": "string"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "cpf, cnpj, email, phone, card ou address",
+                    "type": "string"
+                }
+            }
+        },
+        "models.PIIReport": {
+            "type": "object",
+            "properties": {
+                "findings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PIIFinding"
+                    }
+                },
+                "mode": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Page": {
+            "type": "object",
+            "properties": {
+                "endOffset": {
+                    "type": "integer"
+                },
+                "number": {
+                    "type": "integer"
+                },
+                "startOffset": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
                 }
             }
         },
@@ -222,9 +516,31 @@ const docTemplate = `{
                 "data": {
                     "type": "object",
                     "properties": {
+                        "document": {
+                            "description": "Dados estruturados de NF-e/CT-e/NFS-e (apenas para XML fiscal)",
+                            "type": "object"
+                        },
+                        "failedChunks": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChunkFailure"
+                            }
+                        },
                         "info": {
                             "$ref": "#/definitions/models.Info"
                         },
+                        "pages": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Page"
+                            }
+                        },
+                        "pii": {
+                            "$ref": "#/definitions/models.PIIReport"
+                        },
+                        "redacted": {
+                            "$ref": "#/definitions/models.Artifact"
+                        },
                         "text": {
                             "type": "string"
                         }
@@ -238,6 +554,97 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Artifact": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "fileName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "pages": {
+                    "type": "integer"
+                },
+                "regions": {
+                    "description": "Quantidade de regiões tarjadas",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.APIKeyUsage": {
+            "type": "object",
+            "properties": {
+                "cooldownUntil": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "rateLimited": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "successes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ModelHealth": {
+            "type": "object",
+            "properties": {
+                "apiVersion": {
+                    "type": "string"
+                },
+                "consecutiveFailures": {
+                    "type": "integer"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastFailureAt": {
+                    "type": "string"
+                },
+                "lastSuccessAt": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "openUntil": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "successes": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.StatusResponse": {
             "type": "object",
             "properties": {
@@ -250,6 +657,18 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "gemini": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ModelHealth"
+                    }
+                },
+                "geminiKeys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIKeyUsage"
+                    }
+                },
                 "service": {
                     "type": "string"
                 },
@@ -264,6 +683,117 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ScanVerdict": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "string"
+                },
+                "scanner": {
+                    "description": "clamd ou none (verificação desativada)",
+                    "type": "string"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "clean, infected, not_scanned ou error (scanner indisponível)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TokenUsage": {
+            "type": "object",
+            "properties": {
+                "costUSD": {
+                    "type": "number"
+                },
+                "estimated": {
+                    "type": "boolean"
+                },
+                "inputTokens": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "outputTokens": {
+                    "type": "integer"
+                },
+                "totalTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UsageItem": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "type": "string"
+                },
+                "costUSD": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "inputTokens": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "outputTokens": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UsageReport": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UsageItem"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "totals": {
+                    "$ref": "#/definitions/models.UsageTotals"
+                }
+            }
+        },
+        "models.UsageTotals": {
+            "type": "object",
+            "properties": {
+                "costUSD": {
+                    "type": "number"
+                },
+                "inputTokens": {
+                    "type": "integer"
+                },
+                "outputTokens": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.SupportedTypes": {
             "type": "object",
             "properties": {
@@ -284,9 +814,29 @@ const docTemplate = `{
                 },
                 "maxSizeBytes": {
                     "type": "integer"
+                },
+                "maxSizeByType": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Chave de API do cliente (escopos: process, admin)",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Token JWT (RS256/ES256) do provedor OIDC: \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
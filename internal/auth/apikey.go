@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyPrefix prefixo das chaves geradas (facilita identificar vazamentos)
+const keyPrefix = "fp_"
+
+// storeCheckInterval intervalo mínimo entre verificações de alteração do arquivo de chaves
+const storeCheckInterval = 5 * time.Second
+
+// APIKey chave de API guardada apenas como hash SHA-256
+type APIKey struct {
+	ID          string
+	Hash        string
+	Scopes      []string
+	ExpiresAt   *time.Time
+	MaxFileSize int64
+}
+
+// KeyStore origem das chaves de API, consultada pelo hash da chave recebida
+type KeyStore interface {
+	// Find devolve a chave com o hash informado ou nil se não existir
+	Find(hash string) (*APIKey, error)
+}
+
+// HashKey hash SHA-256 (hex) de uma chave em texto puro
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey gera uma nova chave aleatória e o hash a ser configurado no servidor
+func GenerateKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("erro ao gerar chave: %v", err)
+	}
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashKey(key), nil
+}
+
+// ParseKeyEntry interpreta uma entrada "id:hash:escopos:expira:max_mb"; escopos separados por
+// "|", expira no formato AAAA-MM-DD (a chave deixa de valer nesse dia, em UTC). Só id e hash são obrigatórios.
+func ParseKeyEntry(entry string) (*APIKey, error) {
+	fields := strings.Split(entry, ":")
+	for len(fields) < 5 {
+		fields = append(fields, "")
+	}
+	if len(fields) > 5 {
+		return nil, fmt.Errorf("campos demais (use id:hash:escopos:expira:max_mb)")
+	}
+
+	key := &APIKey{
+		ID:   strings.TrimSpace(fields[0]),
+		Hash: strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fields[1]), "sha256=")),
+	}
+	if key.ID == "" {
+		return nil, fmt.Errorf("id vazio")
+	}
+	if err := validHash(key.Hash); err != nil {
+		return nil, err
+	}
+	for _, scope := range strings.Split(fields[2], "|") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			key.Scopes = append(key.Scopes, scope)
+		}
+	}
+	if len(key.Scopes) == 0 {
+		key.Scopes = []string{ScopeProcess}
+	}
+	if expires := strings.TrimSpace(fields[3]); expires != "" {
+		parsed, err := time.Parse("2006-01-02", expires)
+		if err != nil {
+			return nil, fmt.Errorf("data de expiração inválida %q: use AAAA-MM-DD", expires)
+		}
+		key.ExpiresAt = &parsed
+	}
+	if maxSize := strings.TrimSpace(fields[4]); maxSize != "" {
+		mb, err := strconv.Atoi(maxSize)
+		if err != nil || mb < 0 {
+			return nil, fmt.Errorf("tamanho máximo inválido %q: use megabytes inteiros", maxSize)
+		}
+		key.MaxFileSize = int64(mb) * 1024 * 1024
+	}
+	return key, nil
+}
+
+func validHash(hash string) error {
+	if len(hash) != sha256.Size*2 {
+		return fmt.Errorf("hash deve ser o SHA-256 da chave em hex (64 caracteres)")
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return fmt.Errorf("hash inválido: %v", err)
+	}
+	return nil
+}
+
+// StaticKeyStore chaves fixas, carregadas da configuração
+type StaticKeyStore struct {
+	keys map[string]*APIKey
+}
+
+// NewStaticKeyStore cria o armazenamento com as chaves informadas
+func NewStaticKeyStore(keys []*APIKey) *StaticKeyStore {
+	store := &StaticKeyStore{keys: make(map[string]*APIKey, len(keys))}
+	for _, key := range keys {
+		store.keys[key.Hash] = key
+	}
+	return store
+}
+
+// Find busca a chave pelo hash
+func (s *StaticKeyStore) Find(hash string) (*APIKey, error) {
+	return s.keys[hash], nil
+}
+
+// Len quantidade de chaves
+func (s *StaticKeyStore) Len() int {
+	return len(s.keys)
+}
+
+// fileKey formato de cada chave no arquivo JSON
+type fileKey struct {
+	ID            string     `json:"id"`
+	Hash          string     `json:"hash"`
+	Scopes        []string   `json:"scopes"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	MaxFileSizeMB int64      `json:"maxFileSizeMB,omitempty"`
+}
+
+// FileKeyStore chaves lidas de um arquivo JSON, recarregado quando o arquivo muda
+// (permite incluir e revogar chaves sem reiniciar o serviço)
+type FileKeyStore struct {
+	path string
+
+	mu        sync.Mutex
+	keys      *StaticKeyStore
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileKeyStore carrega o arquivo de chaves
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	store := &FileKeyStore{path: path}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Find busca a chave pelo hash, recarregando o arquivo se ele foi alterado
+func (s *FileKeyStore) Find(hash string) (*APIKey, error) {
+	s.mu.Lock()
+	if time.Since(s.checkedAt) >= storeCheckInterval {
+		s.checkedAt = time.Now()
+		if info, err := os.Stat(s.path); err == nil && !info.ModTime().Equal(s.modTime) {
+			if err := s.loadLocked(); err != nil {
+				slog.Warn("erro ao recarregar chaves de API, mantendo as anteriores", "error", err)
+			}
+		}
+	}
+	keys := s.keys
+	s.mu.Unlock()
+	return keys.Find(hash)
+}
+
+func (s *FileKeyStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkedAt = time.Now()
+	return s.loadLocked()
+}
+
+func (s *FileKeyStore) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("erro ao acessar arquivo de chaves: %v", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("erro ao ler arquivo de chaves: %v", err)
+	}
+	var entries []fileKey
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("arquivo de chaves inválido: %v", err)
+	}
+
+	var keys []*APIKey
+	for _, entry := range entries {
+		hash := strings.ToLower(strings.TrimPrefix(entry.Hash, "sha256="))
+		if entry.ID == "" {
+			return fmt.Errorf("arquivo de chaves inválido: chave sem id")
+		}
+		if err := validHash(hash); err != nil {
+			return fmt.Errorf("arquivo de chaves inválido (%s): %v", entry.ID, err)
+		}
+		scopes := entry.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{ScopeProcess}
+		}
+		keys = append(keys, &APIKey{
+			ID:          entry.ID,
+			Hash:        hash,
+			Scopes:      scopes,
+			ExpiresAt:   entry.ExpiresAt,
+			MaxFileSize: entry.MaxFileSizeMB * 1024 * 1024,
+		})
+	}
+
+	s.keys = NewStaticKeyStore(keys)
+	s.modTime = info.ModTime()
+	slog.Info("chaves de API carregadas", "keys", len(keys), "file", s.path)
+	return nil
+}
+
+// multiKeyStore consulta vários armazenamentos em ordem
+type multiKeyStore []KeyStore
+
+func (m multiKeyStore) Find(hash string) (*APIKey, error) {
+	for _, store := range m {
+		key, err := store.Find(hash)
+		if err != nil || key != nil {
+			return key, err
+		}
+	}
+	return nil, nil
+}
+
+// NewKeyStore monta o armazenamento a partir das entradas de API_KEYS e do arquivo API_KEY_STORE.
+// Devolve nil quando nenhuma chave está configurada.
+func NewKeyStore(entries []string, path string) (KeyStore, error) {
+	var stores multiKeyStore
+
+	var keys []*APIKey
+	for i, entry := range entries {
+		key, err := ParseKeyEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entrada %d de API_KEYS inválida: %v", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		stores = append(stores, NewStaticKeyStore(keys))
+	}
+
+	if path != "" {
+		fileStore, err := NewFileKeyStore(path)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, fileStore)
+	}
+
+	if len(stores) == 0 {
+		return nil, nil
+	}
+	return stores, nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Escopos de acesso
+const (
+	// ScopeProcess envio de arquivos para extração
+	ScopeProcess = "process"
+	// ScopeAdmin status detalhado e relatório de uso de todos os clientes
+	ScopeAdmin = "admin"
+)
+
+// APIKeyHeader header com a chave de API do cliente
+const APIKeyHeader = "X-API-Key"
+
+// Prefixos do ClientID: chaves de API, tenants e subjects de tokens JWT ficam em espaços de nomes
+// separados, para que um tenant não compartilhe limites, uso e artefatos com uma chave de mesmo id
+const (
+	clientPrefixAPIKey  = "key:"
+	clientPrefixTenant  = "jwt:"
+	clientPrefixSubject = "sub:"
+)
+
+// Principal identidade autenticada da requisição
+type Principal struct {
+	// ClientID identifica o cliente nos logs e na contabilização de uso: "key:<id da chave>",
+	// "jwt:<tenant do token>" ou, quando o token não tem tenant, "sub:<sub>"
+	ClientID string
+	// Subject e Tenant claims sub e tenant do token JWT
+	Subject string
+	Tenant  string
+	// Method esquema usado na autenticação (api-key ou jwt)
+	Method string
+	Scopes []string
+	// MaxFileSize limite de upload do cliente em bytes; 0 usa o limite global
+	MaxFileSize int64
+	ExpiresAt   *time.Time
+}
+
+// HasScope indica se o cliente tem o escopo (admin tem todos)
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Error falha de autenticação com o código devolvido ao cliente
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode código da falha (UNAUTHORIZED, INVALID_API_KEY, API_KEY_EXPIRED, INVALID_TOKEN, TOKEN_EXPIRED)
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+var (
+	errMissingCredentials = &Error{Code: "UNAUTHORIZED", Message: "Credenciais não informadas"}
+	errInvalidAPIKey      = &Error{Code: "INVALID_API_KEY", Message: "Chave de API inválida"}
+	errExpiredAPIKey      = &Error{Code: "API_KEY_EXPIRED", Message: "Chave de API expirada"}
+)
+
+// Authenticator valida as credenciais das requisições: chave de API (X-API-Key) ou token
+// JWT do provedor OIDC (Authorization: Bearer)
+type Authenticator struct {
+	keys   KeyStore
+	tokens *TokenValidator
+	now    func() time.Time
+}
+
+// NewAuthenticator cria o autenticador; keys nil desativa as chaves de API e tokens nil os tokens JWT
+func NewAuthenticator(keys KeyStore, tokens *TokenValidator) *Authenticator {
+	return &Authenticator{keys: keys, tokens: tokens, now: time.Now}
+}
+
+// Enabled indica se há algum esquema de autenticação configurado
+func (a *Authenticator) Enabled() bool {
+	return a.keys != nil || a.tokens != nil
+}
+
+// Challenge valor do header WWW-Authenticate com os esquemas aceitos
+func (a *Authenticator) Challenge() string {
+	var schemes []string
+	if a.tokens != nil {
+		schemes = append(schemes, `Bearer realm="api"`)
+	}
+	if a.keys != nil {
+		schemes = append(schemes, fmt.Sprintf(`ApiKey header="%s"`, APIKeyHeader))
+	}
+	return strings.Join(schemes, ", ")
+}
+
+// Authenticate identifica o cliente da requisição
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if authorization := r.Header.Get("Authorization"); a.tokens != nil && len(authorization) > len(BearerPrefix) &&
+		strings.EqualFold(authorization[:len(BearerPrefix)], BearerPrefix) {
+		return a.tokens.Validate(strings.TrimSpace(authorization[len(BearerPrefix):]))
+	}
+
+	key := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if key == "" || a.keys == nil {
+		return nil, errMissingCredentials
+	}
+
+	apiKey, err := a.keys.Find(HashKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.ExpiresAt != nil && !a.now().Before(*apiKey.ExpiresAt) {
+		return nil, errExpiredAPIKey
+	}
+
+	return &Principal{
+		ClientID:    clientPrefixAPIKey + apiKey.ID,
+		Method:      "api-key",
+		Scopes:      apiKey.Scopes,
+		MaxFileSize: apiKey.MaxFileSize,
+		ExpiresAt:   apiKey.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func authenticate(t *testing.T, a *Authenticator, key string) (*Principal, error) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/", nil)
+	if key != "" {
+		r.Header.Set(APIKeyHeader, key)
+	}
+	return a.Authenticate(r)
+}
+
+func errorCode(err error) string {
+	if authErr, ok := err.(*Error); ok {
+		return authErr.Code
+	}
+	return ""
+}
+
+func TestParseKeyEntry(t *testing.T) {
+	hash := HashKey("segredo")
+
+	key, err := ParseKeyEntry("parceiro-a:" + hash + ":process|admin:2030-01-31:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != "parceiro-a" || key.Hash != hash || len(key.Scopes) != 2 || key.MaxFileSize != 10*1024*1024 {
+		t.Fatalf("entrada interpretada errado: %+v", key)
+	}
+	if key.ExpiresAt == nil || !key.ExpiresAt.Equal(time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expiração inesperada: %v", key.ExpiresAt)
+	}
+
+	key, err = ParseKeyEntry("interno:sha256=" + hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Scopes) != 1 || key.Scopes[0] != ScopeProcess || key.ExpiresAt != nil {
+		t.Fatalf("padrões inesperados: %+v", key)
+	}
+
+	for _, entry := range []string{
+		":" + hash,
+		"cliente:segredo-em-texto-puro",
+		"cliente:" + hash + ":process:31/01/2030",
+		"cliente:" + hash + ":process::dez",
+		"cliente:" + hash + ":process:::extra",
+	} {
+		if _, err := ParseKeyEntry(entry); err == nil {
+			t.Errorf("entrada %q deveria ser rejeitada", entry)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	expired := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAuthenticator(NewStaticKeyStore([]*APIKey{
+		{ID: "ativo", Hash: HashKey("chave-ativa"), Scopes: []string{ScopeProcess}, MaxFileSize: 1024},
+		{ID: "vencido", Hash: HashKey("chave-vencida"), Scopes: []string{ScopeProcess}, ExpiresAt: &expired},
+	}), nil)
+
+	principal, err := authenticate(t, a, "chave-ativa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.ClientID != "key:ativo" || principal.MaxFileSize != 1024 || !principal.HasScope(ScopeProcess) || principal.HasScope(ScopeAdmin) {
+		t.Fatalf("cliente inesperado: %+v", principal)
+	}
+
+	for key, code := range map[string]string{
+		"":              "UNAUTHORIZED",
+		"chave-errada":  "INVALID_API_KEY",
+		"chave-vencida": "API_KEY_EXPIRED",
+	} {
+		if _, err := authenticate(t, a, key); errorCode(err) != code {
+			t.Errorf("chave %q: erro %v, esperado %s", key, err, code)
+		}
+	}
+}
+
+func TestClientIDNamespaces(t *testing.T) {
+	signer := newRSAKey(t, "rsa-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(signer), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := NewTokenValidator(TokenConfig{
+		Issuer:    "https://login.exemplo.com",
+		Audiences: []string{"fileprocessing"},
+		JWKSFile:  path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Chave de API com o mesmo id do tenant e do sub do token
+	a := NewAuthenticator(NewStaticKeyStore([]*APIKey{
+		{ID: "empresa-x", Hash: HashKey("chave-x"), Scopes: []string{ScopeProcess}},
+		{ID: "usuario-1", Hash: HashKey("chave-u"), Scopes: []string{ScopeProcess}},
+	}), tokens)
+
+	bearer := func(claims map[string]interface{}) *Principal {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+signer.sign(t, claims))
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return principal
+	}
+	apiKey := func(key string) *Principal {
+		t.Helper()
+		principal, err := authenticate(t, a, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return principal
+	}
+	withoutTenant := validClaims()
+	delete(withoutTenant, "tenant")
+	// Um tenant igual ao sub de outro token também não pode colidir
+	tenantLikeSubject := validClaims()
+	tenantLikeSubject["tenant"] = "usuario-1"
+
+	seen := map[string]string{}
+	for name, principal := range map[string]*Principal{
+		"chave empresa-x":  apiKey("chave-x"),
+		"chave usuario-1":  apiKey("chave-u"),
+		"tenant empresa-x": bearer(validClaims()),
+		"sub usuario-1":    bearer(withoutTenant),
+		"tenant usuario-1": bearer(tenantLikeSubject),
+	} {
+		if other, ok := seen[principal.ClientID]; ok {
+			t.Fatalf("%s e %s compartilham o cliente %q", name, other, principal.ClientID)
+		}
+		seen[principal.ClientID] = name
+	}
+	if _, ok := seen["key:empresa-x"]; !ok {
+		t.Fatalf("clientes inesperados: %v", seen)
+	}
+}
+
+func TestAdminHasEveryScope(t *testing.T) {
+	admin := &Principal{Scopes: []string{ScopeAdmin}}
+	if !admin.HasScope(ScopeProcess) || !admin.HasScope("qualquer") {
+		t.Fatal("admin deveria ter todos os escopos")
+	}
+}
+
+func TestFileKeyStoreReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"id": "primeiro", "hash": "`+HashKey("k1")+`", "scopes": ["process"]}]`, time.Now().Add(-time.Hour))
+	store, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := store.Find(HashKey("k1")); key == nil || key.ID != "primeiro" {
+		t.Fatalf("chave do arquivo não encontrada: %+v", key)
+	}
+
+	write(`[{"id": "segundo", "hash": "`+HashKey("k2")+`", "maxFileSizeMB": 5}]`, time.Now())
+	store.checkedAt = time.Time{}
+
+	if key, _ := store.Find(HashKey("k1")); key != nil {
+		t.Fatal("chave removida do arquivo continua válida")
+	}
+	key, _ := store.Find(HashKey("k2"))
+	if key == nil || key.MaxFileSize != 5*1024*1024 || key.Scopes[0] != ScopeProcess {
+		t.Fatalf("chave recarregada inesperada: %+v", key)
+	}
+}
+
+func TestNewKeyStoreWithoutKeys(t *testing.T) {
+	store, err := NewKeyStore(nil, "")
+	if err != nil || store != nil {
+		t.Fatalf("sem chaves o armazenamento deveria ser nil: %v %v", store, err)
+	}
+	if NewAuthenticator(store, nil).Enabled() {
+		t.Fatal("autenticação não deveria estar ativa sem chaves")
+	}
+}
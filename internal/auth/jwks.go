@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"backend-fileprocessing/internal/metrics"
+)
+
+// jwksMinRefresh intervalo mínimo entre buscas do JWKS, mesmo com o cache vencido ou o
+// provedor fora do ar
+const jwksMinRefresh = time.Minute
+
+// jwk chave pública no formato JWK (RFC 7517), apenas RSA e EC
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwkSet conjunto de chaves de assinatura do provedor OIDC, lido de uma URL (com cache e
+// atualização quando aparece um kid desconhecido) ou de um arquivo local (uso offline)
+type jwkSet struct {
+	url        string
+	file       string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// newJWKSet carrega as chaves; com URL, uma falha na primeira busca só é registrada e a busca
+// é repetida na primeira validação
+func newJWKSet(url, file string, ttl time.Duration) (*jwkSet, error) {
+	set := &jwkSet{
+		url:        url,
+		file:       file,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]crypto.PublicKey),
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler JWKS %s: %v", file, err)
+		}
+		keys, err := parseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS %s inválido: %v", file, err)
+		}
+		set.keys = keys
+		slog.Info("chaves de assinatura carregadas", "keys", len(keys), "file", file)
+		return set, nil
+	}
+	if err := set.refresh(); err != nil {
+		slog.Warn("não foi possível buscar o JWKS", "url", url, "error", err)
+	}
+	return set, nil
+}
+
+// key chave pública pelo kid, buscando o JWKS de novo quando o cache venceu ou o kid é desconhecido
+// (rotação de chaves no provedor); entre tentativas as chaves em cache continuam valendo
+func (s *jwkSet) key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	stale := s.url != "" && time.Since(s.fetchedAt) >= s.ttl
+	retry := s.url != "" && (stale || !ok) && time.Since(s.lastAttempt) >= jwksMinRefresh
+	if retry {
+		// Reserva a tentativa para que requisições simultâneas não busquem o JWKS juntas
+		s.lastAttempt = time.Now()
+	}
+	s.mu.Unlock()
+	metrics.CacheHit("jwks", ok && !stale)
+
+	if retry {
+		if err := s.refresh(); err != nil {
+			slog.Warn("erro ao atualizar JWKS, mantendo as chaves anteriores", "error", err)
+		}
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+		s.mu.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("chave de assinatura %q desconhecida", kid)
+	}
+	return key, nil
+}
+
+// refresh busca o JWKS na URL configurada
+func (s *jwkSet) refresh() error {
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	resp, err := s.httpClient.Get(s.url)
+	if err != nil {
+		return fmt.Errorf("erro ao buscar JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS respondeu status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("erro ao ler JWKS: %v", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	slog.Info("JWKS atualizado", "keys", len(keys))
+	return nil
+}
+
+// parseJWKS interpreta um documento {"keys": [...]}, ignorando chaves que não são de assinatura
+// ou de tipos não suportados
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("JWKS inválido: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("chave do JWKS ignorada", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS sem chaves de assinatura RSA ou EC")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n inválido: %v", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("e inválido")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curva %q não suportada", k.Crv)
+		}
+		x, errX := decodeBigInt(k.X)
+		y, errY := decodeBigInt(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("coordenadas inválidas")
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("ponto fora da curva %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("tipo de chave %q não suportado", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("valor vazio")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew tolerância de relógio na validação de exp/nbf/iat
+const clockSkew = 30 * time.Second
+
+// BearerPrefix prefixo do header Authorization com token JWT
+const BearerPrefix = "Bearer "
+
+var (
+	errInvalidToken = &Error{Code: "INVALID_TOKEN", Message: "Token inválido"}
+	errExpiredToken = &Error{Code: "TOKEN_EXPIRED", Message: "Token expirado"}
+)
+
+// TokenConfig validação de tokens do provedor OIDC
+type TokenConfig struct {
+	// Issuer valor exigido em iss; obrigatório com JWKS configurado
+	Issuer string
+	// Audiences valores aceitos em aud; obrigatório com JWKS configurado
+	Audiences []string
+	// JWKSURL endereço das chaves de assinatura; JWKSFile lê de um arquivo local (uso offline)
+	JWKSURL  string
+	JWKSFile string
+	JWKSTTL  time.Duration
+	// TenantClaim claim com o tenant do usuário; ScopesClaim claim com os escopos
+	// (texto separado por espaços ou lista)
+	TenantClaim string
+	ScopesClaim string
+}
+
+// TokenValidator valida tokens JWT RS256/ES256 assinados pelo provedor OIDC
+type TokenValidator struct {
+	config TokenConfig
+	keys   *jwkSet
+	parser *jwt.Parser
+}
+
+// NewTokenValidator cria o validador; devolve nil quando nenhum JWKS está configurado e erro
+// quando falta iss ou aud, que impedem aceitar tokens emitidos para outras aplicações do provedor
+func NewTokenValidator(cfg TokenConfig) (*TokenValidator, error) {
+	if cfg.JWKSURL == "" && cfg.JWKSFile == "" {
+		return nil, nil
+	}
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER é obrigatório com JWKS configurado")
+	}
+	if len(cfg.Audiences) == 0 {
+		return nil, fmt.Errorf("OIDC_AUDIENCE é obrigatório com JWKS configurado")
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant"
+	}
+	if cfg.ScopesClaim == "" {
+		cfg.ScopesClaim = "scope"
+	}
+	if cfg.JWKSTTL <= 0 {
+		cfg.JWKSTTL = time.Hour
+	}
+
+	keys, err := newJWKSet(cfg.JWKSURL, cfg.JWKSFile, cfg.JWKSTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithIssuer(cfg.Issuer),
+	}
+	return &TokenValidator{config: cfg, keys: keys, parser: jwt.NewParser(options...)}, nil
+}
+
+// Validate valida o token e converte as claims no cliente autenticado
+func (v *TokenValidator) Validate(raw string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.key(kid)
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errExpiredToken
+		}
+		return nil, &Error{Code: errInvalidToken.Code, Message: fmt.Sprintf("Token inválido: %v", err)}
+	}
+
+	if !v.audienceAllowed(claims) {
+		return nil, &Error{Code: errInvalidToken.Code, Message: "Token inválido: audiência não aceita"}
+	}
+
+	subject, _ := claims.GetSubject()
+	tenant, _ := claims[v.config.TenantClaim].(string)
+	principal := &Principal{
+		Subject: subject,
+		Tenant:  tenant,
+		Method:  "jwt",
+		Scopes:  claimScopes(claims[v.config.ScopesClaim]),
+	}
+	switch {
+	case tenant != "":
+		principal.ClientID = clientPrefixTenant + tenant
+	case subject != "":
+		principal.ClientID = clientPrefixSubject + subject
+	default:
+		return nil, &Error{Code: errInvalidToken.Code, Message: fmt.Sprintf("Token inválido: sem %s nem sub", v.config.TenantClaim)}
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		principal.ExpiresAt = &expiresAt.Time
+	}
+	return principal, nil
+}
+
+func (v *TokenValidator) audienceAllowed(claims jwt.MapClaims) bool {
+	audiences, err := claims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, aud := range audiences {
+		for _, allowed := range v.config.Audiences {
+			if aud == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimScopes aceita escopos como texto separado por espaços ("openid process") ou lista
+func claimScopes(value interface{}) []string {
+	switch scopes := value.(type) {
+	case string:
+		return strings.Fields(scopes)
+	case []interface{}:
+		var result []string
+		for _, scope := range scopes {
+			if s, ok := scope.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func newRSAKey(t *testing.T, kid string) signingKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey{kid: kid, method: jwt.SigningMethodRS256, private: key}
+}
+
+func newECKey(t *testing.T, kid string) signingKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey{kid: kid, method: jwt.SigningMethodES256, private: key}
+}
+
+func (k signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func encodeInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func jwksDocument(keys ...signingKey) []byte {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	for _, k := range keys {
+		switch public := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			doc.Keys = append(doc.Keys, jwk{Kid: k.kid, Kty: "RSA", Use: "sig", N: encodeInt(public.N), E: encodeInt(big.NewInt(int64(public.E)))})
+		case *ecdsa.PublicKey:
+			doc.Keys = append(doc.Keys, jwk{Kid: k.kid, Kty: "EC", Crv: "P-256", X: encodeInt(public.X), Y: encodeInt(public.Y)})
+		}
+	}
+	data, _ := json.Marshal(doc)
+	return data
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":    "https://login.exemplo.com",
+		"aud":    []string{"fileprocessing"},
+		"sub":    "usuario-1",
+		"tenant": "empresa-x",
+		"scope":  "openid process",
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestTokenValidatorWithJWKSFile(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t, "rsa-1"), newECKey(t, "ec-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(rsaKey, ecKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	validator, err := NewTokenValidator(TokenConfig{
+		Issuer:    "https://login.exemplo.com",
+		Audiences: []string{"fileprocessing"},
+		JWKSFile:  path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []signingKey{rsaKey, ecKey} {
+		principal, err := validator.Validate(key.sign(t, validClaims()))
+		if err != nil {
+			t.Fatalf("%s: %v", key.kid, err)
+		}
+		if principal.ClientID != "jwt:empresa-x" || principal.Subject != "usuario-1" || principal.Method != "jwt" ||
+			!principal.HasScope(ScopeProcess) || principal.HasScope(ScopeAdmin) {
+			t.Fatalf("%s: cliente inesperado %+v", key.kid, principal)
+		}
+	}
+
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	cases := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"expirado", rsaKey.sign(t, withClaim("exp", time.Now().Add(-time.Hour).Unix())), "TOKEN_EXPIRED"},
+		{"sem exp", rsaKey.sign(t, withClaim("exp", nil)), "INVALID_TOKEN"},
+		{"outro emissor", rsaKey.sign(t, withClaim("iss", "https://outro.exemplo.com")), "INVALID_TOKEN"},
+		{"outra audiência", rsaKey.sign(t, withClaim("aud", "outro-servico")), "INVALID_TOKEN"},
+		{"kid desconhecido", newRSAKey(t, "rsa-2").sign(t, validClaims()), "INVALID_TOKEN"},
+		{"chave errada com kid conhecido", signingKey{kid: "rsa-1", method: jwt.SigningMethodRS256, private: newRSAKey(t, "x").private}.sign(t, validClaims()), "INVALID_TOKEN"},
+		{"HS256", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+			token.Header["kid"] = "rsa-1"
+			signed, _ := token.SignedString([]byte("segredo"))
+			return signed
+		}(), "INVALID_TOKEN"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := validator.Validate(tc.token); errorCode(err) != tc.code {
+				t.Fatalf("erro %v, esperado %s", err, tc.code)
+			}
+		})
+	}
+}
+
+func TestTokenValidatorRefreshesJWKSOnKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t, "antiga"), newRSAKey(t, "nova")
+	var document atomic.Value
+	document.Store(jwksDocument(oldKey))
+	var fetches atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(document.Load().([]byte))
+	}))
+	defer jwksServer.Close()
+
+	validator, err := NewTokenValidator(TokenConfig{
+		Issuer:      "https://login.exemplo.com",
+		Audiences:   []string{"fileprocessing"},
+		JWKSURL:     jwksServer.URL,
+		ScopesClaim: "scp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := validClaims()
+	claims["scp"] = []string{"admin"}
+	delete(claims, "tenant")
+	principal, err := validator.Validate(oldKey.sign(t, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.ClientID != "sub:usuario-1" || !principal.HasScope(ScopeProcess) {
+		t.Fatalf("sem tenant o cliente deveria ser o sub, com escopos da lista: %+v", principal)
+	}
+
+	// O provedor passa a assinar com outra chave: o kid desconhecido dispara nova busca
+	document.Store(jwksDocument(newKey))
+	validator.keys.lastAttempt = time.Time{}
+	if _, err := validator.Validate(newKey.sign(t, claims)); err != nil {
+		t.Fatalf("chave nova deveria ser aceita após atualizar o JWKS: %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("esperava 2 buscas do JWKS, houve %d", fetches.Load())
+	}
+
+	// Kids desconhecidos não disparam buscas seguidas
+	if _, err := validator.Validate(newRSAKey(t, "falsa").sign(t, claims)); errorCode(err) != "INVALID_TOKEN" {
+		t.Fatalf("kid inexistente deveria ser rejeitado: %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("buscas do JWKS sem limite de frequência: %d", fetches.Load())
+	}
+}
+
+func TestTokenValidatorKeepsKeysWhileJWKSIsDown(t *testing.T) {
+	key := newRSAKey(t, "rsa-1")
+	var fetches atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Só a primeira busca funciona
+		if fetches.Add(1) > 1 {
+			http.Error(w, "indisponível", http.StatusBadGateway)
+			return
+		}
+		w.Write(jwksDocument(key))
+	}))
+	defer jwksServer.Close()
+
+	validator, err := NewTokenValidator(TokenConfig{
+		Issuer:    "https://login.exemplo.com",
+		Audiences: []string{"fileprocessing"},
+		JWKSURL:   jwksServer.URL,
+		JWKSTTL:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := key.sign(t, validClaims())
+
+	// Cache vencido e última tentativa antiga: uma única busca, que falha
+	time.Sleep(2 * time.Millisecond)
+	validator.keys.lastAttempt = time.Time{}
+	for i := 0; i < 5; i++ {
+		if _, err := validator.Validate(token); err != nil {
+			t.Fatalf("chave em cache deveria valer com o JWKS fora do ar: %v", err)
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("esperava 2 buscas do JWKS (inicial e uma nova tentativa), houve %d", fetches.Load())
+	}
+}
+
+func TestTokenValidatorRequiresIssuerAndAudience(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(newRSAKey(t, "rsa-1")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []TokenConfig{
+		{JWKSFile: path, Audiences: []string{"fileprocessing"}},
+		{JWKSFile: path, Issuer: "https://login.exemplo.com"},
+	} {
+		if _, err := NewTokenValidator(cfg); err == nil {
+			t.Fatalf("configuração sem iss/aud deveria ser recusada: %+v", cfg)
+		}
+	}
+}
+
+func TestAuthenticatorAcceptsBothSchemes(t *testing.T) {
+	key := newECKey(t, "ec-1")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, jwksDocument(key), 0o600)
+	validator, err := NewTokenValidator(TokenConfig{
+		Issuer:    "https://login.exemplo.com",
+		Audiences: []string{"fileprocessing"},
+		JWKSFile:  path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuthenticator(NewStaticKeyStore([]*APIKey{
+		{ID: "integracao", Hash: HashKey("chave"), Scopes: []string{ScopeProcess}},
+	}), validator)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+key.sign(t, validClaims()))
+	if principal, err := a.Authenticate(r); err != nil || principal.Method != "jwt" {
+		t.Fatalf("token: %+v %v", principal, err)
+	}
+
+	if principal, err := authenticate(t, a, "chave"); err != nil || principal.Method != "api-key" {
+		t.Fatalf("chave de API: %+v %v", principal, err)
+	}
+}
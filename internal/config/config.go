@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config estrutura de configuração
@@ -9,16 +13,219 @@ type Config struct {
 	Port        string
 	Environment string
 	LogLevel    string
-	MaxFileSize int64
+	// LogFormat saída dos logs (json ou text); vazio usa json em produção e text nos demais ambientes
+	LogFormat string
+	// ShutdownTimeout espera pelas requisições em andamento ao receber SIGINT/SIGTERM
+	ShutdownTimeout time.Duration
+	// MaxFileSize limite global de upload; MaxFileSizeByType sobrepõe por extensão (".pdf=50", em MB)
+	MaxFileSize       int64
+	MaxFileSizeByType []string
+	// MultipartMemory parte do upload mantida em memória; o restante vai para arquivo temporário
+	MultipartMemory int64
+
+	// Chaves da API Gemini: GEMINI_API_KEY e GEMINI_API_KEYS (lista), cada uma também lida de
+	// arquivo via *_FILE (secrets Docker/K8s). Usadas em rodízio conforme GeminiKeySelection.
+	GeminiAPIKeys      []string
+	GeminiKeySelection string
+
+	// Divisão de PDFs grandes em blocos de páginas processados em paralelo
+	PDFChunkPages       int
+	PDFChunkConcurrency int
+
+	// Arquivos acima deste tamanho (bytes) são enviados pela Files API do Gemini; 0 desativa
+	GeminiUploadThreshold int64
+
+	// Novas tentativas (backoff exponencial) em 429, 5xx e erros de rede
+	GeminiRetryMaxAttempts int
+	GeminiRetryBaseDelay   time.Duration
+	GeminiRetryMaxDelay    time.Duration
+	GeminiRetryBudget      time.Duration
+
+	// Circuit breaker por modelo/versão: abre após N falhas consecutivas e fica aberto pelo cooldown
+	GeminiBreakerThreshold int
+	GeminiBreakerCooldown  time.Duration
+
+	// Lista ordenada de modelos (GEMINI_MODELS); vazia usa a descoberta via API, em cache por GeminiModelsTTL
+	GeminiModels    []string
+	GeminiModelsTTL time.Duration
+
+	// Endpoint da API Gemini (pode apontar para um servidor local), modelo usado quando a
+	// descoberta falha e versões da API tentadas, em ordem
+	GeminiBaseURL      string
+	GeminiDefaultModel string
+	GeminiAPIVersions  []string
+
+	// generationConfig; nil/0 mantém o padrão do modelo
+	GeminiTemperature     *float64
+	GeminiTopP            *float64
+	GeminiTopK            int
+	GeminiMaxOutputTokens int
+	// safetySettings: "CATEGORIA=LIMITE" ou apenas "LIMITE" para todas as categorias
+	GeminiSafetySettings []string
+
+	// Preços por modelo para o relatório de uso: "modelo=entrada:saída" em USD por 1M de tokens
+	// (o modelo casa por prefixo)
+	GeminiPrices []string
+	// Dias de uso mantidos em memória para o relatório; dias mais antigos são descartados
+	UsageRetentionDays int
+
+	// Chaves de API dos clientes, guardadas apenas como hash: entradas "id:hash:escopos:expira:max_mb"
+	// em API_KEYS (ou API_KEYS_FILE) e/ou arquivo JSON em API_KEY_STORE. Sem chaves a API fica aberta.
+	APIKeys     []string
+	APIKeyStore string
+
+	// Tokens JWT (RS256/ES256) do provedor OIDC, validados pelo JWKS da URL ou de um arquivo
+	// local; com JWKS configurado, iss e aud são obrigatórios
+	OIDCIssuer      string
+	OIDCAudiences   []string
+	OIDCJWKSURL     string
+	OIDCJWKSFile    string
+	OIDCJWKSTTL     time.Duration
+	OIDCTenantClaim string
+	OIDCScopesClaim string
+
+	// Limites por cliente (credencial autenticada ou IP); 0 desativa cada limite
+	RateLimitRPM      int
+	RateLimitBurst    int
+	MaxConcurrentJobs int
+	DailyUploadBytes  int64
+
+	// CORS das rotas da API (process, status, usage); origens aceitam curinga de subdomínio
+	// ("https://*.exemplo.com"). As rotas públicas (health, supported-types) usam CORSPublicOrigins,
+	// sem credenciais.
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+	CORSPublicOrigins    []string
+
+	// Verificação de malware antes do processamento: endereço do clamd ("tcp://host:3310" ou
+	// "unix:///run/clamav/clamd.ctl"); vazio desativa. ScanFailOpen processa o arquivo mesmo se o
+	// scanner estiver indisponível (o padrão é recusar).
+	ClamdAddress string
+	ClamdTimeout time.Duration
+	ScanFailOpen bool
+
+	// PIIMode tratamento padrão de dados pessoais no texto extraído (off, detect, mask, tokenize),
+	// sobreposto pelo campo "pii" da requisição; PIITokenKey assina os tokens do modo tokenize
+	PIIMode     string
+	PIITokenKey string
+
+	// Cópia tarjada (PDF rasterizado ou PNG): resolução da rasterização, ferramentas do Poppler e
+	// por quanto tempo o arquivo gerado fica disponível para download em ArtifactDir
+	RedactionDPI     int
+	RedactionTimeout time.Duration
+	PdftoppmPath     string
+	PdftotextPath    string
+	ArtifactDir      string
+	ArtifactTTL      time.Duration
+
+	// MetricsEnabled expõe /metrics no formato do Prometheus
+	MetricsEnabled bool
+
+	// Tracing OpenTelemetry: exportador (none, otlp ou stdout), coletor OTLP/HTTP, nome do serviço
+	// e fração dos traces gravados. O traceparent recebido dos chamadores é sempre respeitado.
+	TracingExporter    string
+	TracingEndpoint    string
+	TracingServiceName string
+	TracingSampleRatio float64
 }
 
 // Load carrega configurações do ambiente
 func Load() *Config {
 	return &Config{
-		Port:        getEnv("PORT", "9091"),
-		Environment: getEnv("GIN_MODE", "debug"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		MaxFileSize: 25 * 1024 * 1024, // 25MB (aumentado)
+		Port:              getEnv("PORT", "9091"),
+		Environment:       getEnv("GIN_MODE", "debug"),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		LogFormat:         getEnv("LOG_FORMAT", ""),
+		ShutdownTimeout:   getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		MaxFileSize:       int64(getEnvInt("MAX_FILE_SIZE_MB", 25)) * 1024 * 1024,
+		MaxFileSizeByType: getEnvList("MAX_FILE_SIZE_BY_TYPE"),
+		MultipartMemory:   int64(getEnvInt("MULTIPART_MEMORY_MB", 8)) * 1024 * 1024,
+
+		GeminiAPIKeys:      getSecretList("GEMINI_API_KEY", "GEMINI_API_KEYS"),
+		GeminiKeySelection: getEnv("GEMINI_KEY_SELECTION", "round-robin"),
+
+		PDFChunkPages:       getEnvInt("PDF_CHUNK_PAGES", 10),
+		PDFChunkConcurrency: getEnvInt("PDF_CHUNK_CONCURRENCY", 3),
+
+		GeminiUploadThreshold: int64(getEnvInt("GEMINI_UPLOAD_THRESHOLD_MB", 15)) * 1024 * 1024,
+
+		GeminiRetryMaxAttempts: getEnvInt("GEMINI_RETRY_MAX_ATTEMPTS", 3),
+		GeminiRetryBaseDelay:   getEnvDuration("GEMINI_RETRY_BASE_DELAY", time.Second),
+		GeminiRetryMaxDelay:    getEnvDuration("GEMINI_RETRY_MAX_DELAY", 30*time.Second),
+		GeminiRetryBudget:      getEnvDuration("GEMINI_RETRY_BUDGET", 5*time.Minute),
+
+		GeminiBreakerThreshold: getEnvInt("GEMINI_BREAKER_THRESHOLD", 3),
+		GeminiBreakerCooldown:  getEnvDuration("GEMINI_BREAKER_COOLDOWN", time.Minute),
+
+		GeminiModels:    getEnvList("GEMINI_MODELS"),
+		GeminiModelsTTL: getEnvDuration("GEMINI_MODELS_TTL", time.Hour),
+
+		GeminiBaseURL:      getEnv("GEMINI_BASE_URL", "https://generativelanguage.googleapis.com"),
+		GeminiDefaultModel: getEnv("GEMINI_DEFAULT_MODEL", "gemini-2.0-flash"),
+		GeminiAPIVersions:  getEnvList("GEMINI_API_VERSIONS"),
+
+		GeminiTemperature:     getEnvFloat("GEMINI_TEMPERATURE"),
+		GeminiTopP:            getEnvFloat("GEMINI_TOP_P"),
+		GeminiTopK:            getEnvInt("GEMINI_TOP_K", 0),
+		GeminiMaxOutputTokens: getEnvInt("GEMINI_MAX_OUTPUT_TOKENS", 0),
+		GeminiSafetySettings:  getEnvList("GEMINI_SAFETY_SETTINGS"),
+
+		GeminiPrices:       getEnvList("GEMINI_PRICES"),
+		UsageRetentionDays: getEnvInt("USAGE_RETENTION_DAYS", 90),
+
+		APIKeys:     getSecretList("API_KEYS"),
+		APIKeyStore: getEnv("API_KEY_STORE", ""),
+
+		OIDCIssuer:      getEnv("OIDC_ISSUER", ""),
+		OIDCAudiences:   getEnvList("OIDC_AUDIENCE"),
+		OIDCJWKSURL:     getEnv("OIDC_JWKS_URL", ""),
+		OIDCJWKSFile:    getEnv("OIDC_JWKS_FILE", ""),
+		OIDCJWKSTTL:     getEnvDuration("OIDC_JWKS_TTL", time.Hour),
+		OIDCTenantClaim: getEnv("OIDC_TENANT_CLAIM", "tenant"),
+		OIDCScopesClaim: getEnv("OIDC_SCOPES_CLAIM", "scope"),
+
+		RateLimitRPM:      getEnvInt("RATE_LIMIT_RPM", 60),
+		RateLimitBurst:    getEnvInt("RATE_LIMIT_BURST", 20),
+		MaxConcurrentJobs: getEnvInt("MAX_CONCURRENT_JOBS", 3),
+		DailyUploadBytes:  int64(getEnvInt("DAILY_UPLOAD_LIMIT_MB", 0)) * 1024 * 1024,
+
+		CORSAllowedOrigins: getEnvListDefault("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods: getEnvListDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "OPTIONS"}),
+		CORSAllowedHeaders: getEnvListDefault("CORS_ALLOWED_HEADERS", []string{
+			"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-API-Key", "X-Client-ID", "X-Request-ID",
+			"traceparent", "tracestate",
+		}),
+		CORSExposedHeaders: getEnvListDefault("CORS_EXPOSED_HEADERS", []string{
+			"Content-Length", "X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "Retry-After",
+		}),
+		CORSAllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 12*time.Hour),
+		CORSPublicOrigins:    getEnvListDefault("CORS_PUBLIC_ALLOWED_ORIGINS", []string{"*"}),
+
+		ClamdAddress: getEnv("CLAMD_ADDRESS", ""),
+		ClamdTimeout: getEnvDuration("CLAMD_TIMEOUT", 30*time.Second),
+		ScanFailOpen: getEnvBool("SCAN_FAIL_OPEN", false),
+
+		PIIMode:     getEnv("PII_MODE", "off"),
+		PIITokenKey: getSecret("PII_TOKEN_KEY"),
+
+		RedactionDPI:     getEnvInt("REDACTION_DPI", 150),
+		RedactionTimeout: getEnvDuration("REDACTION_TIMEOUT", 2*time.Minute),
+		PdftoppmPath:     getEnv("PDFTOPPM_PATH", "pdftoppm"),
+		PdftotextPath:    getEnv("PDFTOTEXT_PATH", "pdftotext"),
+		ArtifactDir:      getEnv("ARTIFACT_DIR", ""),
+		ArtifactTTL:      getEnvDuration("ARTIFACT_TTL", time.Hour),
+
+		MetricsEnabled: getEnvBool("METRICS_ENABLED", true),
+
+		TracingExporter:    getEnv("OTEL_TRACES_EXPORTER", "none"),
+		TracingEndpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "backend-fileprocessing"),
+		TracingSampleRatio: getEnvFloatDefault("OTEL_TRACES_SAMPLER_ARG", 1),
 	}
 }
 
@@ -29,3 +236,98 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getSecret obtém um segredo do arquivo indicado em KEY_FILE (secrets Docker/K8s) ou, na falta dele, da variável KEY
+func getSecret(key string) string {
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return strings.TrimSpace(string(data))
+		}
+		slog.Warn("não foi possível ler o arquivo do segredo", "variable", key+"_FILE", "path", path, "error", err)
+	}
+	return strings.TrimSpace(os.Getenv(key))
+}
+
+// getSecretList junta os segredos das variáveis informadas (valores separados por vírgula ou
+// quebra de linha), sem repetições e na ordem em que aparecem
+func getSecretList(keys ...string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, value := range strings.FieldsFunc(getSecret(key), func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+			if value = strings.TrimSpace(value); value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// getEnvInt obtém variável de ambiente inteira com valor padrão
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool obtém variável de ambiente booleana (true/false, 1/0) com valor padrão
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvFloat obtém variável de ambiente decimal; nil quando ausente ou inválida
+func getEnvFloat(key string) *float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
+// getEnvFloatDefault obtém variável de ambiente decimal com valor padrão
+func getEnvFloatDefault(key string, defaultValue float64) float64 {
+	if value := getEnvFloat(key); value != nil {
+		return *value
+	}
+	return defaultValue
+}
+
+// getEnvDuration obtém variável de ambiente de duração (ex.: "500ms", "30s") com valor padrão
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList obtém variável de ambiente com valores separados por vírgula (itens vazios são ignorados)
+func getEnvList(key string) []string {
+	var values []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvListDefault obtém lista separada por vírgula; ausente ou vazia usa o valor padrão
+func getEnvListDefault(key string, defaultValue []string) []string {
+	if values := getEnvList(key); len(values) > 0 {
+		return values
+	}
+	return defaultValue
+}
@@ -0,0 +1,63 @@
+package geminitest
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"backend-fileprocessing/internal/config"
+)
+
+// TestAPIKey chave usada por Config
+const TestAPIKey = "test-key-0123456789"
+
+// Config configuração apontando para o servidor falso, com esperas curtas e sem divisão de PDFs
+func (s *Server) Config() *config.Config {
+	return &config.Config{
+		Port:        "0",
+		Environment: "test",
+		MaxFileSize: 25 * 1024 * 1024,
+
+		GeminiAPIKeys:      []string{TestAPIKey},
+		GeminiKeySelection: "round-robin",
+
+		GeminiRetryMaxAttempts: 3,
+		GeminiRetryBaseDelay:   time.Millisecond,
+		GeminiRetryMaxDelay:    10 * time.Millisecond,
+		GeminiRetryBudget:      10 * time.Second,
+
+		GeminiBreakerThreshold: 3,
+		GeminiBreakerCooldown:  time.Minute,
+
+		GeminiModels:       []string{"gemini-2.0-flash"},
+		GeminiModelsTTL:    time.Hour,
+		GeminiBaseURL:      s.URL,
+		GeminiDefaultModel: "gemini-2.0-flash",
+		GeminiAPIVersions:  []string{"v1beta"},
+
+		PDFChunkConcurrency: 1,
+		MetricsEnabled:      true,
+	}
+}
+
+// PDF gera um PDF válido com o número de páginas informado (cada página escreve "Página N")
+func PDF(pages int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	// 1 = catálogo, 2 = árvore de páginas, 3 = fonte; depois pares página/conteúdo
+	kids := ""
+	for i := 0; i < pages; i++ {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+	fmt.Fprintf(&buf, "1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&buf, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 595 842] >>\nendobj\n", kids, pages)
+	fmt.Fprintf(&buf, "3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	for i := 0; i < pages; i++ {
+		content := fmt.Sprintf("BT /F1 24 Tf 72 720 Td (Pagina %d) Tj ET", i+1)
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>\nendobj\n", 4+2*i, 5+2*i)
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", 5+2*i, len(content), content)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\n%%%%EOF\n", 4+2*pages)
+	return buf.Bytes()
+}
@@ -0,0 +1,200 @@
+package geminitest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Response resposta roteirizada para uma chamada de geração
+type Response struct {
+	Status int
+	Body   interface{}
+	Header http.Header
+	// Delay espera antes de responder (respostas lentas, timeouts)
+	Delay time.Duration
+}
+
+// After devolve a mesma resposta atrasada em d
+func (r Response) After(d time.Duration) Response {
+	r.Delay = d
+	return r
+}
+
+// Estruturas mínimas da resposta do generateContent
+type generateResponse struct {
+	Candidates     []candidate     `json:"candidates,omitempty"`
+	PromptFeedback *promptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *usageMetadata  `json:"usageMetadata,omitempty"`
+}
+
+type candidate struct {
+	Content       content        `json:"content"`
+	FinishReason  string         `json:"finishReason,omitempty"`
+	SafetyRatings []safetyRating `json:"safetyRatings,omitempty"`
+}
+
+type content struct {
+	Parts []part `json:"parts,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+type part struct {
+	Text string `json:"text"`
+}
+
+type promptFeedback struct {
+	BlockReason   string         `json:"blockReason,omitempty"`
+	SafetyRatings []safetyRating `json:"safetyRatings,omitempty"`
+}
+
+type safetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type usageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// PromptTokens tokens de entrada informados em toda resposta de sucesso
+const PromptTokens = 100
+
+// Text resposta de sucesso com o texto dividido nas partes informadas (uma parte se só houver uma)
+func Text(parts ...string) Response {
+	result := candidate{Content: content{Role: "model"}, FinishReason: "STOP"}
+	outputTokens := 0
+	for _, text := range parts {
+		result.Content.Parts = append(result.Content.Parts, part{Text: text})
+		outputTokens += (utf8.RuneCountInString(text) + 3) / 4
+	}
+	return Response{
+		Status: http.StatusOK,
+		Body: generateResponse{
+			Candidates: []candidate{result},
+			UsageMetadata: &usageMetadata{
+				PromptTokenCount:     PromptTokens,
+				CandidatesTokenCount: outputTokens,
+				TotalTokenCount:      PromptTokens + outputTokens,
+			},
+		},
+	}
+}
+
+// Pages resposta de sucesso com o texto de cada página precedido do delimitador pedido no prompt
+func Pages(pages ...string) Response {
+	var builder strings.Builder
+	for i, page := range pages {
+		fmt.Fprintf(&builder, "=== PÁGINA %d ===\n%s\n\n", i+1, page)
+	}
+	return Text(builder.String())
+}
+
+// NotFound modelo inexistente na versão da API
+func NotFound() Response {
+	return Response{Status: http.StatusNotFound}
+}
+
+// RateLimited cota excedida com RetryInfo.retryDelay (0 omite o retryDelay)
+func RateLimited(retryDelay time.Duration) Response {
+	body := errorBody(http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "You exceeded your current quota, please check your plan and billing details.")
+	if retryDelay > 0 {
+		body.Error.Details = append(body.Error.Details, errorDetail{
+			Type:       "type.googleapis.com/google.rpc.RetryInfo",
+			RetryDelay: fmt.Sprintf("%gs", retryDelay.Seconds()),
+		})
+	}
+	return Response{Status: http.StatusTooManyRequests, Body: body}
+}
+
+// ServerError erro interno do Gemini
+func ServerError() Response {
+	return Response{
+		Status: http.StatusInternalServerError,
+		Body:   errorBody(http.StatusInternalServerError, "INTERNAL", "An internal error has occurred."),
+	}
+}
+
+// Unavailable modelo sobrecarregado (503)
+func Unavailable() Response {
+	return Response{
+		Status: http.StatusServiceUnavailable,
+		Body:   errorBody(http.StatusServiceUnavailable, "UNAVAILABLE", "The model is overloaded. Please try again later."),
+	}
+}
+
+// BadRequest requisição rejeitada (não deve ser repetida)
+func BadRequest(message string) Response {
+	return Response{
+		Status: http.StatusBadRequest,
+		Body:   errorBody(http.StatusBadRequest, "INVALID_ARGUMENT", message),
+	}
+}
+
+// promptOnly consumo de respostas bloqueadas: a entrada é cobrada mesmo sem texto gerado
+var promptOnly = &usageMetadata{PromptTokenCount: PromptTokens, TotalTokenCount: PromptTokens}
+
+// SafetyBlock resposta interrompida pelos filtros de segurança (finishReason SAFETY)
+func SafetyBlock() Response {
+	return Response{
+		Status: http.StatusOK,
+		Body: generateResponse{
+			Candidates: []candidate{{
+				Content:      content{Role: "model"},
+				FinishReason: "SAFETY",
+				SafetyRatings: []safetyRating{
+					{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "HIGH", Blocked: true},
+				},
+			}},
+			UsageMetadata: promptOnly,
+		},
+	}
+}
+
+// PromptBlocked entrada recusada (promptFeedback.blockReason)
+func PromptBlocked(reason string) Response {
+	return Response{
+		Status: http.StatusOK,
+		Body: generateResponse{
+			PromptFeedback: &promptFeedback{BlockReason: reason},
+			UsageMetadata:  promptOnly,
+		},
+	}
+}
+
+// Finish resposta com texto e o finishReason informado (ex.: MAX_TOKENS, RECITATION)
+func Finish(reason, text string) Response {
+	response := Text(text)
+	body := response.Body.(generateResponse)
+	body.Candidates[0].FinishReason = reason
+	response.Body = body
+	return response
+}
+
+// Estrutura de erro da API Google
+type apiError struct {
+	Error struct {
+		Code    int           `json:"code"`
+		Message string        `json:"message"`
+		Status  string        `json:"status"`
+		Details []errorDetail `json:"details,omitempty"`
+	} `json:"error"`
+}
+
+type errorDetail struct {
+	Type       string `json:"@type"`
+	RetryDelay string `json:"retryDelay,omitempty"`
+}
+
+func errorBody(status int, code, message string) *apiError {
+	body := &apiError{}
+	body.Error.Code = status
+	body.Error.Status = code
+	body.Error.Message = message
+	return body
+}
@@ -0,0 +1,423 @@
+// Package geminitest servidor falso da API Gemini (httptest) para testes sem chave nem rede.
+// Cobre generateContent, streamGenerateContent, listagem de modelos e a Files API, com
+// respostas roteirizadas por modelo (sucesso, 404, 429 com retryDelay, 500, bloqueios, lentidão).
+package geminitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server API Gemini falsa
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// models modelos devolvidos pela listagem (GET /{versão}/models)
+	models []string
+	// scripts respostas enfileiradas por modelo ("" vale para qualquer modelo)
+	scripts map[string][]Response
+	// responder gera a resposta quando não há nada enfileirado (antes do fallback)
+	responder func(Call) Response
+	// fallback resposta quando não há nada enfileirado
+	fallback Response
+	calls    []Call
+	uploads  map[string]*upload
+	files    map[string]*File
+	nextID   int
+}
+
+// Call requisição recebida pelo servidor falso
+type Call struct {
+	Method     string
+	Path       string
+	APIVersion string
+	// Model e Action preenchidos nas chamadas de geração (generateContent, streamGenerateContent)
+	Model  string
+	Action string
+	// APIKey valor do header x-goog-api-key
+	APIKey string
+	// QueryKey valor de ?key= (deve ficar vazio: a chave vai no header)
+	QueryKey string
+	// Header headers recebidos (traceparent, identificação da requisição)
+	Header http.Header
+	Body   []byte
+}
+
+// File arquivo mantido pela Files API falsa
+type File struct {
+	Name     string `json:"name"`
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	State    string `json:"state"`
+	Size     int    `json:"sizeBytes,string"`
+	// APIKey chave que enviou o arquivo
+	APIKey string `json:"-"`
+}
+
+type upload struct {
+	mimeType    string
+	displayName string
+	size        int
+	data        []byte
+	apiKey      string
+}
+
+// New inicia o servidor; ele é encerrado automaticamente no fim do teste
+func New(t testing.TB) *Server {
+	s := &Server{
+		models:   []string{"gemini-2.0-flash"},
+		scripts:  make(map[string][]Response),
+		fallback: Text("Texto extraído pelo servidor falso do Gemini."),
+		uploads:  make(map[string]*upload),
+		files:    make(map[string]*File),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetModels define os modelos devolvidos pela listagem
+func (s *Server) SetModels(models ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = models
+}
+
+// SetDefault define a resposta usada quando não há respostas enfileiradas
+func (s *Server) SetDefault(response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = response
+}
+
+// SetResponder define uma função que monta a resposta a partir da requisição (ex.: conforme o
+// nome do arquivo no prompt). Respostas enfileiradas têm prioridade.
+func (s *Server) SetResponder(responder func(Call) Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = responder
+}
+
+// Enqueue enfileira respostas para as próximas chamadas de geração ao modelo (ou a qualquer modelo, com "")
+func (s *Server) Enqueue(model string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[model] = append(s.scripts[model], responses...)
+}
+
+// Calls todas as requisições recebidas, em ordem
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// GenerateCalls requisições de geração (generateContent e streamGenerateContent), em ordem
+func (s *Server) GenerateCalls() []Call {
+	var calls []Call
+	for _, call := range s.Calls() {
+		if call.Action != "" {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Files arquivos ainda presentes na Files API (enviados e não removidos)
+func (s *Server) Files() []File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make([]File, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, *file)
+	}
+	return files
+}
+
+// next próxima resposta roteirizada para a chamada
+func (s *Server) next(call Call) Response {
+	s.mu.Lock()
+	for _, key := range []string{call.Model, ""} {
+		if queue := s.scripts[key]; len(queue) > 0 {
+			s.scripts[key] = queue[1:]
+			s.mu.Unlock()
+			return queue[0]
+		}
+	}
+	responder, fallback := s.responder, s.fallback
+	s.mu.Unlock()
+
+	if responder != nil {
+		return responder(call)
+	}
+	return fallback
+}
+
+func (s *Server) record(r *http.Request, body []byte) Call {
+	call := Call{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		APIKey:   r.Header.Get("x-goog-api-key"),
+		QueryKey: r.URL.Query().Get("key"),
+		Header:   r.Header.Clone(),
+		Body:     body,
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 0 {
+		call.APIVersion = parts[0]
+	}
+	if len(parts) == 3 && parts[1] == "models" {
+		if model, action, found := strings.Cut(parts[2], ":"); found {
+			call.Model, call.Action = model, action
+		}
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	return call
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	call := s.record(r, body)
+
+	// Sessões de upload são autenticadas pela própria URL, como na API real
+	if strings.HasPrefix(r.URL.Path, "/upload-session/") {
+		s.serveUploadSession(w, r, body)
+		return
+	}
+	if call.APIKey == "" {
+		writeError(w, http.StatusForbidden, "PERMISSION_DENIED", "Method doesn't allow unregistered callers. Please use API Key.")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/") && strings.HasSuffix(r.URL.Path, "/files"):
+		s.startUpload(w, r, body, call.APIKey)
+	case strings.HasPrefix(r.URL.Path, "/"+call.APIVersion+"/files/"):
+		s.serveFile(w, r, call.APIKey)
+	case r.Method == http.MethodGet && r.URL.Path == "/"+call.APIVersion+"/models":
+		s.listModels(w)
+	case r.Method == http.MethodPost && call.Action == "generateContent":
+		s.serveGenerate(w, r, call, false)
+	case r.Method == http.MethodPost && call.Action == "streamGenerateContent":
+		s.serveGenerate(w, r, call, true)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("rota não suportada pelo servidor falso: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) listModels(w http.ResponseWriter) {
+	s.mu.Lock()
+	models := make([]map[string]string, 0, len(s.models))
+	for _, model := range s.models {
+		models = append(models, map[string]string{"name": "models/" + model})
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"models": models})
+}
+
+func (s *Server) serveGenerate(w http.ResponseWriter, r *http.Request, call Call, stream bool) {
+	var request struct {
+		Contents []json.RawMessage `json:"contents"`
+	}
+	if err := json.Unmarshal(call.Body, &request); err != nil || len(request.Contents) == 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "corpo da requisição inválido")
+		return
+	}
+
+	response := s.next(call)
+	if response.Delay > 0 {
+		select {
+		case <-time.After(response.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status == http.StatusNotFound && response.Body == nil {
+		writeError(w, status, "NOT_FOUND", fmt.Sprintf("models/%s is not found for API version %s", call.Model, call.APIVersion))
+		return
+	}
+	if !stream || status != http.StatusOK {
+		writeJSON(w, status, response.Body)
+		return
+	}
+
+	// streamGenerateContent: a resposta é dividida em pedaços, um candidato por parte
+	chunks := streamChunks(response.Body)
+	if r.URL.Query().Get("alt") == "sse" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, chunk := range chunks {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\r\n\r\n", data)
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, chunks)
+}
+
+// streamChunks divide uma resposta de sucesso em uma resposta por parte de texto
+func streamChunks(body interface{}) []interface{} {
+	data, _ := json.Marshal(body)
+	var full generateResponse
+	if err := json.Unmarshal(data, &full); err != nil || len(full.Candidates) == 0 || len(full.Candidates[0].Content.Parts) <= 1 {
+		return []interface{}{body}
+	}
+
+	first := full.Candidates[0]
+	chunks := make([]interface{}, 0, len(first.Content.Parts))
+	for i, text := range first.Content.Parts {
+		chunk := generateResponse{Candidates: []candidate{{Content: content{Parts: []part{text}, Role: "model"}}}}
+		if i == len(first.Content.Parts)-1 {
+			chunk.Candidates[0].FinishReason = first.FinishReason
+			chunk.UsageMetadata = full.UsageMetadata
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func (s *Server) startUpload(w http.ResponseWriter, r *http.Request, body []byte, apiKey string) {
+	if r.Header.Get("X-Goog-Upload-Protocol") != "resumable" || r.Header.Get("X-Goog-Upload-Command") != "start" {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "apenas upload resumível é suportado")
+		return
+	}
+	size, err := strconv.Atoi(r.Header.Get("X-Goog-Upload-Header-Content-Length"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "X-Goog-Upload-Header-Content-Length ausente")
+		return
+	}
+	var metadata struct {
+		File struct {
+			DisplayName string `json:"display_name"`
+		} `json:"file"`
+	}
+	_ = json.Unmarshal(body, &metadata)
+
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("session-%d", s.nextID)
+	s.uploads[id] = &upload{
+		mimeType:    r.Header.Get("X-Goog-Upload-Header-Content-Type"),
+		displayName: metadata.File.DisplayName,
+		size:        size,
+		apiKey:      apiKey,
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("X-Goog-Upload-URL", s.URL+"/upload-session/"+id)
+	w.Header().Set("X-Goog-Upload-Status", "active")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveUploadSession(w http.ResponseWriter, r *http.Request, body []byte) {
+	id := strings.TrimPrefix(r.URL.Path, "/upload-session/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "sessão de upload não encontrada")
+		return
+	}
+
+	command := r.Header.Get("X-Goog-Upload-Command")
+	if command == "query" {
+		w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(len(session.data)))
+		w.Header().Set("X-Goog-Upload-Status", "active")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	offset, err := strconv.Atoi(r.Header.Get("X-Goog-Upload-Offset"))
+	if err != nil || offset != len(session.data) {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("offset %q não confere com %d bytes recebidos", r.Header.Get("X-Goog-Upload-Offset"), len(session.data)))
+		return
+	}
+	session.data = append(session.data, body...)
+
+	if !strings.Contains(command, "finalize") {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(session.data) != session.size {
+		writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("recebidos %d de %d bytes", len(session.data), session.size))
+		return
+	}
+
+	delete(s.uploads, id)
+	s.nextID++
+	name := fmt.Sprintf("files/file-%d", s.nextID)
+	file := &File{
+		Name:     name,
+		URI:      s.URL + "/v1beta/" + name,
+		MimeType: session.mimeType,
+		State:    "ACTIVE",
+		Size:     len(session.data),
+		APIKey:   session.apiKey,
+	}
+	s.files[name] = file
+	writeJSON(w, http.StatusOK, map[string]interface{}{"file": file})
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, apiKey string) {
+	name := r.URL.Path[strings.Index(r.URL.Path, "files/"):]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[name]
+	// Arquivos só são visíveis para a chave (projeto) que os enviou
+	if !ok || file.APIKey != apiKey {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "arquivo não encontrado: "+name)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, file)
+	case http.MethodDelete:
+		delete(s.files, name)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "método não suportado")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorBody(status, code, message))
+}
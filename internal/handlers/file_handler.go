@@ -1,16 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"path/filepath"
+	"strconv"
 
+	"backend-fileprocessing/internal/auth"
+	"backend-fileprocessing/internal/middleware"
 	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pii"
 	"backend-fileprocessing/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// multipartOverhead folga para boundaries e cabeçalhos do multipart além do arquivo
+const multipartOverhead = 64 * 1024
+
+// errorStatus status HTTP dos códigos de erro que não são falhas do arquivo enviado (400)
+var errorStatus = map[string]int{
+	"MALWARE_DETECTED": http.StatusUnprocessableEntity,
+	"SCAN_FAILED":      http.StatusServiceUnavailable,
+	"REDACTION_FAILED": http.StatusInternalServerError,
+}
+
+// unprocessedCodes recusas do serviço antes de qualquer processamento: o upload é devolvido ao
+// volume diário do cliente
+var unprocessedCodes = map[string]bool{
+	"UNSUPPORTED_FILE_TYPE": true,
+	"REDACTION_UNSUPPORTED": true,
+}
+
 // FileHandler handler para processamento de arquivos
 type FileHandler struct {
 	fileService *services.FileService
@@ -29,56 +52,172 @@ func NewFileHandler(fileService *services.FileService) *FileHandler {
 // @Tags files
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "Arquivo para processar (PDF, imagem, TXT, DOCX)"
+// @Param file formData file true "Arquivo para processar (PDF, imagem, TXT, DOCX, XML de NF-e/CT-e/NFS-e)"
+// @Param pii formData string false "Dados pessoais no texto: off, detect, mask ou tokenize (padrão: PII_MODE)"
+// @Param redact formData boolean false "Gerar cópia do PDF ou da imagem com os dados pessoais tarjados"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 413 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 429 {object} models.Response
 // @Failure 500 {object} models.Response
+// @Failure 503 {object} models.Response
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /api/v1/files/process [post]
 func (h *FileHandler) ProcessFile(c *gin.Context) {
-	// Verificar se há arquivo
-	file, header, err := c.Request.FormFile("file")
+	// Limitar o corpo enquanto é lido: o maior limite entre os tipos (o tipo só é conhecido depois
+	// do parse), com folga para os cabeçalhos do multipart
+	uploadLimit := h.clientLimit(c, h.fileService.MaxUploadSize())
+	if c.Request.ContentLength > uploadLimit+multipartOverhead {
+		fileTooLarge(c, uploadLimit)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, uploadLimit+multipartOverhead)
+
+	// Verificar se há arquivo (partes acima de MaxMultipartMemory vão para arquivo temporário)
+	header, err := c.FormFile("file")
+	if c.Request.MultipartForm != nil {
+		defer c.Request.MultipartForm.RemoveAll()
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fileTooLarge(c, uploadLimit)
+			return
+		}
+		middleware.RefundUpload(c)
+		middleware.AbortWithErrorResponse(c, http.StatusBadRequest, models.NewErrorResponse(
 			"NO_FILE",
 			"Nenhum arquivo foi enviado",
 			"Envie um arquivo usando o campo 'file'",
 		))
 		return
 	}
-	defer file.Close()
 
-	// Validar tamanho do arquivo (25MB máximo - aumentado para arquivos maiores)
-	maxSize := int64(25 * 1024 * 1024) // 25MB
+	// Validar tamanho do arquivo pelo limite do tipo
+	maxSize := h.clientLimit(c, h.fileService.MaxFileSize(filepath.Ext(header.Filename)))
 	if header.Size > maxSize {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"FILE_TOO_LARGE",
-			"Arquivo muito grande",
-			"Tamanho máximo permitido: 25MB",
+		fileTooLarge(c, maxSize)
+		return
+	}
+
+	// Opções do processamento (campo do formulário ou parâmetro de query; ausente usa a configuração)
+	var opts services.ProcessOptions
+	if value := c.DefaultPostForm("pii", c.Query("pii")); value != "" {
+		mode, err := pii.ParseMode(value)
+		if err != nil {
+			middleware.RefundUpload(c)
+			middleware.AbortWithErrorResponse(c, http.StatusBadRequest, models.NewErrorResponse(
+				"INVALID_OPTION",
+				err.Error(),
+				"Valores aceitos para 'pii': off, detect, mask, tokenize",
+			))
+			return
+		}
+		opts.PIIMode = mode
+	}
+	if value := c.DefaultPostForm("redact", c.Query("redact")); value != "" {
+		redact, err := strconv.ParseBool(value)
+		if err != nil {
+			middleware.RefundUpload(c)
+			middleware.AbortWithErrorResponse(c, http.StatusBadRequest, models.NewErrorResponse(
+				"INVALID_OPTION",
+				fmt.Sprintf("Valor inválido para 'redact': %q", value),
+				"Use redact=true para receber a cópia tarjada",
+			))
+			return
+		}
+		opts.Redact = redact
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		middleware.AbortWithErrorResponse(c, http.StatusInternalServerError, models.NewErrorResponse(
+			"PROCESSING_ERROR",
+			fmt.Sprintf("Erro ao ler arquivo enviado: %v", err),
+			"Tente enviar o arquivo novamente",
 		))
 		return
 	}
+	defer file.Close()
 
 	// Processar arquivo
-	log.Printf("🔄 Iniciando processamento do arquivo: %s (%.2f MB)", header.Filename, float64(header.Size)/1024/1024)
-	response, err := h.fileService.ProcessFile(file, header.Filename, header.Size)
+	client := clientID(c)
+	response, err := h.fileService.ProcessFile(c.Request.Context(), file, header.Filename, header.Size, client, opts)
 	if err != nil {
-		log.Printf("❌ Erro ao processar arquivo: %v", err)
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+		slog.ErrorContext(c.Request.Context(), "erro ao processar arquivo", "error", err)
+		middleware.AbortWithErrorResponse(c, http.StatusInternalServerError, models.NewErrorResponse(
 			"PROCESSING_ERROR",
 			fmt.Sprintf("Erro ao processar arquivo: %v", err),
 			"Verifique se o arquivo está válido e se o serviço Gemini está configurado corretamente",
 		))
 		return
 	}
-	
-	log.Printf("📊 Resposta do processamento: success=%v", response.Success)
 
 	// Retornar resposta
 	if response.Success {
 		c.JSON(http.StatusOK, response)
+	} else if status, ok := errorStatus[response.Error.Code]; ok {
+		middleware.AbortWithErrorResponse(c, status, response)
 	} else {
-		c.JSON(http.StatusBadRequest, response)
+		if unprocessedCodes[response.Error.Code] {
+			middleware.RefundUpload(c)
+		}
+		middleware.AbortWithErrorResponse(c, http.StatusBadRequest, response)
+	}
+}
+
+// clientLimit aplica o limite próprio da credencial, quando menor que o do serviço
+func (h *FileHandler) clientLimit(c *gin.Context, limit int64) int64 {
+	if principal := middleware.Principal(c); principal != nil && principal.MaxFileSize > 0 && principal.MaxFileSize < limit {
+		return principal.MaxFileSize
+	}
+	return limit
+}
+
+// fileTooLarge responde 413 com o limite aplicado; o upload não conta no volume diário
+func fileTooLarge(c *gin.Context, maxSize int64) {
+	middleware.RefundUpload(c)
+	middleware.AbortWithErrorResponse(c, http.StatusRequestEntityTooLarge, models.NewErrorResponse(
+		"FILE_TOO_LARGE",
+		"Arquivo muito grande",
+		fmt.Sprintf("Tamanho máximo permitido: %s", services.FormatSize(maxSize)),
+	))
+}
+
+// DownloadArtifact baixa um arquivo gerado pelo processamento (ex.: cópia tarjada)
+// @Summary Baixar arquivo gerado
+// @Description Baixa a cópia tarjada indicada em data.redacted.url; disponível até expiresAt e só para o cliente que a gerou
+// @Tags files
+// @Produce application/pdf
+// @Produce image/png
+// @Param id path string true "Id do arquivo gerado"
+// @Success 200 {file} file
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /api/v1/files/artifacts/{id} [get]
+func (h *FileHandler) DownloadArtifact(c *gin.Context) {
+	principal := middleware.Principal(c)
+	admin := principal != nil && principal.HasScope(auth.ScopeAdmin)
+
+	artifact := h.fileService.Artifact(c.Param("id"), clientID(c), admin)
+	if artifact == nil {
+		middleware.AbortWithErrorResponse(c, http.StatusNotFound, models.NewErrorResponse(
+			"ARTIFACT_NOT_FOUND",
+			"Arquivo não encontrado",
+			"O arquivo expirou ou foi gerado por outro cliente; processe o documento novamente",
+		))
+		return
 	}
+
+	c.Header("Content-Type", artifact.ContentType)
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(artifact.Path, artifact.FileName)
 }
 
 // GetSupportedTypes retorna tipos de arquivo suportados
@@ -90,7 +229,7 @@ func (h *FileHandler) ProcessFile(c *gin.Context) {
 // @Router /api/v1/files/supported-types [get]
 func (h *FileHandler) GetSupportedTypes(c *gin.Context) {
 	supportedTypes := h.fileService.GetSupportedTypes()
-	
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    supportedTypes,
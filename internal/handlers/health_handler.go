@@ -5,19 +5,22 @@ import (
     "time"
 
     "backend-fileprocessing/internal/models"
+    "backend-fileprocessing/internal/services"
 
     "github.com/gin-gonic/gin"
 )
 
 // HealthHandler handler para health checks
 type HealthHandler struct {
-	startTime time.Time
+	startTime   time.Time
+	fileService *services.FileService
 }
 
 // NewHealthHandler cria novo handler de health
-func NewHealthHandler() *HealthHandler {
+func NewHealthHandler(fileService *services.FileService) *HealthHandler {
 	return &HealthHandler{
-		startTime: time.Now(),
+		startTime:   time.Now(),
+		fileService: fileService,
 	}
 }
 
@@ -47,10 +50,15 @@ func (h *HealthHandler) HealthCheck(c *gin.Context) {
 
 // Status retorna status detalhado do serviço
 // @Summary Status detalhado
-// @Description Retorna status detalhado do serviço
+// @Description Retorna status detalhado do serviço, incluindo a saúde (circuit breaker) dos modelos Gemini e o uso de cada chave de API
 // @Tags health
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 429 {object} models.Response
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /api/v1/status [get]
 func (h *HealthHandler) Status(c *gin.Context) {
 	response := models.StatusResponse{
@@ -66,6 +74,8 @@ func (h *HealthHandler) Status(c *gin.Context) {
 			"DOCX Support",
 			"REST API",
 		},
+		Gemini:     h.fileService.ModelHealth(),
+		GeminiKeys: h.fileService.KeyUsage(),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
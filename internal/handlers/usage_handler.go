@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend-fileprocessing/internal/auth"
+	"backend-fileprocessing/internal/middleware"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler handler do relatório de consumo de tokens
+type UsageHandler struct {
+	usageService *services.UsageService
+}
+
+// NewUsageHandler cria novo handler de uso
+func NewUsageHandler(usageService *services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// Report retorna o consumo de tokens e o custo por dia, cliente e modelo
+// @Summary Relatório de uso
+// @Description Retorna tokens consumidos e custo estimado agregados por dia, cliente e modelo
+// @Tags usage
+// @Produce json
+// @Param from query string false "Primeiro dia (AAAA-MM-DD, padrão: to)"
+// @Param to query string false "Último dia (AAAA-MM-DD, padrão: hoje em UTC)"
+// @Param client query string false "Filtrar por cliente (sem escopo admin, apenas o próprio cliente)"
+// @Success 200 {object} models.UsageReport
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 429 {object} models.Response
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /api/v1/usage [get]
+func (h *UsageHandler) Report(c *gin.Context) {
+	// Sem escopo admin, cada cliente só enxerga o próprio consumo
+	client := c.Query("client")
+	if principal := middleware.Principal(c); principal != nil && !principal.HasScope(auth.ScopeAdmin) {
+		client = principal.ClientID
+	}
+
+	report, err := h.usageService.Report(c.Query("from"), c.Query("to"), client)
+	if err != nil {
+		middleware.AbortWithErrorResponse(c, http.StatusBadRequest, models.NewErrorResponse(
+			"INVALID_PERIOD",
+			err.Error(),
+			"Use from e to no formato AAAA-MM-DD",
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// clientID identifica o cliente da requisição: credencial autenticada, header X-Client-ID
+// (autenticação desativada) ou, na falta deles, o IP
+func clientID(c *gin.Context) string {
+	if id := c.GetString(middleware.ClientIDKey); id != "" {
+		return id
+	}
+	return c.ClientIP()
+}
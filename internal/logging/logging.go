@@ -0,0 +1,156 @@
+// Package logging configura o log estruturado (log/slog) do serviço: JSON em produção, texto em
+// desenvolvimento, nível vindo da configuração e atributos da requisição (request_id, client,
+// file_hash, processor, trace_id) carregados no context.Context e incluídos em cada linha.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Atributos da requisição presentes em todas as linhas registradas com o contexto
+const (
+	KeyRequestID = "request_id"
+	KeyClient    = "client"
+	KeyFileHash  = "file_hash"
+	KeyProcessor = "processor"
+)
+
+// Formatos de saída
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// attrsKey chave dos atributos no context.Context
+type attrsKey struct{}
+
+// ParseLevel interpreta LOG_LEVEL (debug, info, warn/warning, error); vazio é info
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("nível de log inválido %q: use debug, info, warn ou error", level)
+	}
+}
+
+// New cria o logger com o formato (json ou text) e o nível informados
+func New(out io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(format, FormatJSON) {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Setup instala o logger como padrão do slog e do pacote log (bibliotecas que usam log.Printf
+// passam a sair no mesmo formato, em nível info)
+func Setup(out io.Writer, format, level string) error {
+	parsed, err := ParseLevel(level)
+	slog.SetDefault(New(out, format, parsed))
+	return err
+}
+
+// With devolve um contexto com os atributos acrescentados (pares chave/valor); uma chave já
+// presente tem o valor substituído
+func With(ctx context.Context, args ...any) context.Context {
+	current, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	attrs := make([]slog.Attr, len(current), len(current)+len(args)/2)
+	copy(attrs, current)
+
+	for _, attr := range argsToAttrs(args) {
+		replaced := false
+		for i := range attrs {
+			if attrs[i].Key == attr.Key {
+				attrs[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			attrs = append(attrs, attr)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// argsToAttrs converte pares chave/valor (ou slog.Attr) como em slog.Logger.With
+func argsToAttrs(args []any) []slog.Attr {
+	var attrs []slog.Attr
+	for len(args) > 0 {
+		switch key := args[0].(type) {
+		case slog.Attr:
+			attrs = append(attrs, key)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, slog.String("!BADKEY", key))
+				args = nil
+				continue
+			}
+			attrs = append(attrs, slog.Any(key, args[1]))
+			args = args[2:]
+		default:
+			attrs = append(attrs, slog.Any("!BADKEY", key))
+			args = args[1:]
+		}
+	}
+	return attrs
+}
+
+// FileHash identifica o arquivo nos logs sem expor o nome (que pode conter dados pessoais):
+// primeiros 12 dígitos hexadecimais do SHA-256 do nome
+func FileHash(filename string) string {
+	sum := sha256.Sum256([]byte(filename))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Fatal registra o erro e encerra o processo
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler acrescenta a cada registro os atributos guardados no contexto e o trace/span
+// atual, para correlacionar a linha com o trace
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+			record.AddAttrs(attrs...)
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
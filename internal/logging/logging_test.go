@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestContextAttrsInEveryLine(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, FormatJSON, slog.LevelInfo)
+
+	ctx := With(context.Background(), KeyRequestID, "req-1", KeyClient, "acme")
+	ctx = With(ctx, KeyClient, "globex", KeyProcessor, "pdf")
+	logger.InfoContext(ctx, "processando arquivo", "chars", 10)
+	logger.DebugContext(ctx, "abaixo do nível")
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("esperava uma única linha JSON, obtido %q: %v", out.String(), err)
+	}
+	want := map[string]any{"msg": "processando arquivo", "request_id": "req-1", "client": "globex", "processor": "pdf", "chars": float64(10)}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, esperado %v", key, line[key], value)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warning": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(input); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; esperado %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("esperava erro para nível inválido")
+	}
+}
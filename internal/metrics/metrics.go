@@ -0,0 +1,104 @@
+// Package metrics métricas Prometheus do serviço, expostas em /metrics no formato texto.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixo de todas as métricas do serviço
+const namespace = "fileprocessing"
+
+// processingBuckets processamentos levam de milissegundos (TXT) a minutos (PDFs grandes no Gemini)
+var processingBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var (
+	// HTTPRequests requisições por rota (padrão registrado, ex.: /api/v1/files/process), método e status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requisições HTTP por rota, método e status.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration latência das requisições por rota, método e status
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latência das requisições HTTP por rota, método e status.",
+		Buckets:   processingBuckets,
+	}, []string{"route", "method", "status"})
+
+	// ProcessingDuration tempo de processamento por tipo de arquivo, processador e resultado (success, error)
+	ProcessingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "processing_duration_seconds",
+		Help:      "Tempo de processamento de arquivos por tipo, processador e resultado.",
+		Buckets:   processingBuckets,
+	}, []string{"file_type", "processor", "outcome"})
+
+	// GeminiAttempts chamadas generateContent por modelo, versão da API e status HTTP ("network" em falhas de rede,
+	// "canceled" quando o cliente desconecta)
+	GeminiAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gemini_attempts_total",
+		Help:      "Tentativas de chamada ao Gemini por modelo, versão da API e status HTTP.",
+	}, []string{"model", "api_version", "status_code"})
+
+	// CacheRequests consultas aos caches (gemini_models, jwks) com resultado hit ou miss;
+	// a taxa de acerto é hit / (hit + miss)
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Consultas aos caches internos por cache e resultado (hit, miss).",
+	}, []string{"cache", "result"})
+
+	// JobsInFlight processamentos em andamento
+	JobsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "jobs_in_flight",
+		Help:      "Processamentos de arquivo em andamento.",
+	})
+
+	// BytesProcessed bytes de arquivos processados por tipo
+	BytesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_processed_total",
+		Help:      "Bytes de arquivos processados por tipo.",
+	}, []string{"file_type"})
+
+	// QuotaExceeded requisições recusadas por limite (RATE_LIMIT_EXCEEDED, TOO_MANY_CONCURRENT_JOBS, DAILY_QUOTA_EXCEEDED)
+	QuotaExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_exceeded_total",
+		Help:      "Requisições recusadas por limite de taxa, concorrência ou volume diário.",
+	}, []string{"limit"})
+)
+
+// registry registro próprio (sem as métricas globais de outras bibliotecas), com métricas do runtime Go e do processo
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, ProcessingDuration, GeminiAttempts,
+		CacheRequests, JobsInFlight, BytesProcessed, QuotaExceeded,
+	)
+}
+
+// Handler responde /metrics no formato texto do Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// CacheHit registra uma consulta ao cache
+func CacheHit(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"backend-fileprocessing/internal/auth"
+	"backend-fileprocessing/internal/logging"
+	"backend-fileprocessing/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey chave do cliente autenticado no contexto do gin
+const principalKey = "principal"
+
+// ClientIDKey chave do identificador do cliente no contexto do gin (usada pelo Logger)
+const ClientIDKey = "clientID"
+
+// ClientIDHeader header que identifica o cliente quando a autenticação está desativada
+const ClientIDHeader = "X-Client-ID"
+
+// Auth autentica a requisição e guarda o cliente no contexto. Sem autenticação configurada,
+// todas as requisições passam (modo aberto, para desenvolvimento).
+func Auth(authenticator *auth.Authenticator) gin.HandlerFunc {
+	if !authenticator.Enabled() {
+		slog.Warn("autenticação desativada: configure API_KEYS, API_KEY_STORE ou OIDC_JWKS_URL para proteger a API")
+		return func(c *gin.Context) {
+			if id := c.GetHeader(ClientIDHeader); id != "" {
+				setClient(c, id)
+			}
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		principal, err := authenticator.Authenticate(c.Request)
+		if err != nil {
+			code := "UNAUTHORIZED"
+			var authErr *auth.Error
+			if errors.As(err, &authErr) {
+				code = authErr.Code
+			} else {
+				slog.ErrorContext(c.Request.Context(), "erro ao validar credenciais", "error", err)
+			}
+			c.Header("WWW-Authenticate", authenticator.Challenge())
+			AbortWithErrorResponse(c, http.StatusUnauthorized, models.NewErrorResponse(
+				code,
+				err.Error(),
+				fmt.Sprintf("Envie a chave de API no header %s ou um token no header Authorization: Bearer", auth.APIKeyHeader),
+			))
+			return
+		}
+
+		c.Set(principalKey, principal)
+		setClient(c, principal.ClientID)
+		c.Next()
+	}
+}
+
+// RequireScope exige que o cliente autenticado tenha o escopo. Sem autenticação configurada
+// (nenhum cliente no contexto) a requisição passa.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := Principal(c)
+		if principal != nil && !principal.HasScope(scope) {
+			AbortWithErrorResponse(c, http.StatusForbidden, models.NewErrorResponse(
+				"INSUFFICIENT_SCOPE",
+				fmt.Sprintf("A credencial não tem o escopo %q", scope),
+				"Solicite uma chave com o escopo necessário",
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Principal cliente autenticado da requisição (nil quando a autenticação está desativada)
+func Principal(c *gin.Context) *auth.Principal {
+	if value, ok := c.Get(principalKey); ok {
+		if principal, ok := value.(*auth.Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// setClient guarda o cliente no contexto do gin e nos atributos de log da requisição
+func setClient(c *gin.Context, clientID string) {
+	c.Set(ClientIDKey, clientID)
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), logging.KeyClient, clientID))
+}
@@ -1,26 +1,83 @@
 package middleware
 
 import (
+	"log/slog"
+	"strings"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORS configuração de CORS
-func CORS() gin.HandlerFunc {
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
-	config.AllowHeaders = []string{
-		"Origin",
-		"Content-Type",
-		"Accept",
-		"Authorization",
-		"X-Requested-With",
-		"X-API-Key",
+// CORSPolicy política de CORS de um grupo de rotas
+type CORSPolicy struct {
+	// AllowedOrigins origens exatas ("https://app.exemplo.com"), subdomínios com curinga
+	// ("https://*.exemplo.com") ou "*" para qualquer origem
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS aplica a política ao grupo de rotas. Requisições preflight (OPTIONS) são respondidas aqui,
+// então o middleware deve vir antes da autenticação e o grupo precisa registrar as rotas OPTIONS.
+func CORS(policy CORSPolicy) gin.HandlerFunc {
+	config := cors.Config{
+		AllowMethods:     policy.AllowedMethods,
+		AllowHeaders:     policy.AllowedHeaders,
+		ExposeHeaders:    policy.ExposedHeaders,
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           policy.MaxAge,
+	}
+
+	var patterns []string
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			config.AllowAllOrigins = true
+		}
+		patterns = append(patterns, strings.ToLower(strings.TrimRight(origin, "/")))
+	}
+
+	if config.AllowAllOrigins {
+		// Navegadores recusam credenciais com "Access-Control-Allow-Origin: *"
+		if config.AllowCredentials {
+			slog.Warn("CORS: credenciais desativadas porque todas as origens são permitidas; liste as origens para usar credenciais")
+			config.AllowCredentials = false
+		}
+	} else {
+		config.AllowOriginFunc = func(origin string) bool {
+			origin = strings.ToLower(origin)
+			for _, pattern := range patterns {
+				if matchOrigin(pattern, origin) {
+					return true
+				}
+			}
+			return false
+		}
 	}
-	config.ExposeHeaders = []string{"Content-Length"}
-	config.AllowCredentials = true
-	config.MaxAge = 12 * 3600 // 12 hours
 
 	return cors.New(config)
 }
+
+// matchOrigin compara a origem com um padrão exato ou com curinga de subdomínio
+// ("https://*.exemplo.com" aceita "https://app.exemplo.com" e "https://a.b.exemplo.com", mas não
+// "https://exemplo.com" nem "https://app.exemplo.com.br")
+func matchOrigin(pattern, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == origin
+	}
+	if !strings.HasPrefix(suffix, ".") || len(origin) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	for _, r := range subdomain {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return !strings.HasPrefix(subdomain, ".") && !strings.HasSuffix(subdomain, ".")
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import "testing"
+
+func TestMatchOrigin(t *testing.T) {
+	cases := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://app.exemplo.com", "https://app.exemplo.com", true},
+		{"https://app.exemplo.com", "http://app.exemplo.com", false},
+		{"https://*.exemplo.com", "https://painel.exemplo.com", true},
+		{"https://*.exemplo.com", "https://a.b.exemplo.com", true},
+		{"https://*.exemplo.com", "https://exemplo.com", false},
+		{"https://*.exemplo.com", "https://.exemplo.com", false},
+		{"https://*.exemplo.com", "https://exemplo.com.evil.com", false},
+		{"https://*.exemplo.com", "https://app.exemplo.com.br", false},
+		{"https://*.exemplo.com", "https://evil.com/.exemplo.com", false},
+		{"https://*.exemplo.com", "http://app.exemplo.com", false},
+		{"https://*.exemplo.com:8443", "https://app.exemplo.com:8443", true},
+		{"https://*.exemplo.com:8443", "https://app.exemplo.com", false},
+		{"https://*exemplo.com", "https://evilexemplo.com", false},
+	}
+	for _, tc := range cases {
+		if got := matchOrigin(tc.pattern, tc.origin); got != tc.want {
+			t.Errorf("matchOrigin(%q, %q) = %v, esperado %v", tc.pattern, tc.origin, got, tc.want)
+		}
+	}
+}
@@ -1,21 +1,44 @@
 package middleware
 
 import (
-    "fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-// Logger middleware de logging
+// Logger registra cada requisição em uma linha estruturada, com nível pelo status (error para 5xx,
+// warn para 4xx). Os atributos da requisição (request_id, client) vêm do contexto.
 func Logger() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-        return fmt.Sprintf("[%s] %s %s %d %s %s %s\n",
-            param.TimeStamp.Format("2006/01/02 - 15:04:05"),
-            param.Method,
-            param.Path,
-            param.StatusCode,
-            param.Latency,
-            param.ClientIP,
-            param.ErrorMessage,
-        )
-	})
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", route,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			attrs = append(attrs, "errors", errs)
+		}
+		slog.Log(c.Request.Context(), level, "requisição", attrs...)
+	}
 }
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend-fileprocessing/internal/metrics"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limita a taxa de requisições por cliente (token bucket). Deve vir depois de Auth:
+// clientes autenticados são limitados pela credencial, os demais pelo IP.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	limit := limiter.Config().RequestsPerMinute
+	return func(c *gin.Context) {
+		allowed, remaining, retryAfter := limiter.Allow(rateLimitKey(c))
+		if limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		if !allowed {
+			tooManyRequests(c, retryAfter, "RATE_LIMIT_EXCEEDED",
+				"Limite de requisições excedido",
+				fmt.Sprintf("Limite: %d requisições por minuto", limit))
+			return
+		}
+		c.Next()
+	}
+}
+
+// uploadRefundKey marca, no contexto do gin, um upload recusado antes do processamento
+const uploadRefundKey = "uploadRefund"
+
+// RefundUpload devolve ao volume diário do cliente o upload recusado sem processamento (arquivo
+// ausente ou grande demais, opção inválida, tipo não suportado)
+func RefundUpload(c *gin.Context) {
+	c.Set(uploadRefundKey, true)
+}
+
+// ProcessingQuota limita os processamentos simultâneos e o volume diário enviado por cliente
+func ProcessingQuota(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	config := limiter.Config()
+	return func(c *gin.Context) {
+		key := rateLimitKey(c)
+
+		release, ok := limiter.Acquire(key)
+		if !ok {
+			tooManyRequests(c, time.Second, "TOO_MANY_CONCURRENT_JOBS",
+				"Processamentos simultâneos demais para o cliente",
+				fmt.Sprintf("Aguarde a conclusão dos processamentos em andamento (limite: %d)", config.MaxConcurrent))
+			return
+		}
+		defer release()
+
+		if config.DailyBytes > 0 {
+			if size := c.Request.ContentLength; size >= 0 {
+				// Tamanho conhecido: reservar antes de ler o corpo
+				if used, ok := limiter.ReserveBytes(key, size); !ok {
+					dailyQuotaExceeded(c, used, config.DailyBytes)
+					return
+				}
+				defer func() {
+					if c.GetBool(uploadRefundKey) {
+						limiter.RefundBytes(key, size)
+					}
+				}()
+			} else {
+				// Corpo sem Content-Length: contabilizar o que for lido
+				if used := limiter.UsedBytes(key); used >= config.DailyBytes {
+					dailyQuotaExceeded(c, used, config.DailyBytes)
+					return
+				}
+				body := &countingReader{ReadCloser: c.Request.Body}
+				c.Request.Body = body
+				defer func() {
+					if !c.GetBool(uploadRefundKey) {
+						limiter.RecordBytes(key, body.n)
+					}
+				}()
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey chave dos limites: credencial autenticada ou IP
+func rateLimitKey(c *gin.Context) string {
+	if principal := Principal(c); principal != nil {
+		return "client:" + principal.ClientID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func dailyQuotaExceeded(c *gin.Context, used, limit int64) {
+	tooManyRequests(c, ratelimit.UntilNextDay(time.Now()), "DAILY_QUOTA_EXCEEDED",
+		"Volume diário de upload excedido",
+		fmt.Sprintf("Usado hoje: %.2f MB de %.2f MB; a cota é renovada à meia-noite UTC", float64(used)/1024/1024, float64(limit)/1024/1024))
+}
+
+// tooManyRequests responde 429 com Retry-After em segundos (arredondado para cima)
+func tooManyRequests(c *gin.Context, retryAfter time.Duration, code, message, details string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	metrics.QuotaExceeded.WithLabelValues(code).Inc()
+	AbortWithErrorResponse(c, http.StatusTooManyRequests, models.NewErrorResponse(code, message, details))
+}
+
+// countingReader conta os bytes lidos do corpo da requisição
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -2,7 +2,7 @@ package middleware
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 
@@ -14,10 +14,6 @@ import (
 // Recovery middleware de recovery
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		// Log detalhado do panic
-		log.Printf("❌ PANIC RECOVERED: %v", recovered)
-		log.Printf("❌ Stack trace:\n%s", debug.Stack())
-		
 		var errMsg string
 		if err, ok := recovered.(string); ok {
 			errMsg = err
@@ -27,14 +23,18 @@ func Recovery() gin.HandlerFunc {
 			errMsg = fmt.Sprintf("%v", recovered)
 		}
 
-		log.Printf("❌ Erro: %s", errMsg)
-		log.Printf("❌ Path: %s %s", c.Request.Method, c.Request.URL.Path)
+		// Log detalhado do panic
+		slog.ErrorContext(c.Request.Context(), "panic recuperado",
+			"error", errMsg,
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"stack", string(debug.Stack()),
+		)
 
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+		AbortWithErrorResponse(c, http.StatusInternalServerError, models.NewErrorResponse(
 			"INTERNAL_ERROR",
 			fmt.Sprintf("Erro interno do servidor: %s", errMsg),
-			"Verifique os logs do servidor para mais detalhes",
+			"Informe o requestId ao suporte para localizar o erro nos logs",
 		))
-		c.Abort()
 	})
 }
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"backend-fileprocessing/internal/logging"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID aceita o X-Request-ID do chamador (ou gera um), guarda no contexto da requisição e
+// nos atributos de log e devolve no header da resposta
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		ctx := requestid.WithContext(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(logging.With(ctx, logging.KeyRequestID, id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
+
+// AbortWithErrorResponse encerra a requisição com a resposta de erro, incluindo o request_id no
+// corpo para o cliente informar ao suporte
+func AbortWithErrorResponse(c *gin.Context, status int, response models.Response) {
+	if response.Error != nil {
+		withID := *response.Error
+		withID.RequestID = requestid.FromContext(c.Request.Context())
+		response.Error = &withID
+	}
+	c.AbortWithStatusJSON(status, response)
+}
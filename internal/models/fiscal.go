@@ -0,0 +1,87 @@
+package models
+
+// FiscalDocument documento fiscal eletrônico (NF-e, CT-e, NFS-e) extraído do XML autorizado
+type FiscalDocument struct {
+	Type             string               `json:"type"`
+	Namespace        string               `json:"namespace"`
+	AccessKey        string               `json:"accessKey,omitempty"`
+	AccessKeyValid   *bool                `json:"accessKeyValid,omitempty"`
+	VerificationCode string               `json:"verificationCode,omitempty"`
+	Issues           []string             `json:"issues,omitempty"`
+	Model            string               `json:"model,omitempty"`
+	Series           string               `json:"series,omitempty"`
+	Number           string               `json:"number"`
+	IssuedAt         string               `json:"issuedAt,omitempty"`
+	Operation        string               `json:"operation,omitempty"`
+	Issuer           FiscalParty          `json:"issuer"`
+	Recipient        *FiscalParty         `json:"recipient,omitempty"`
+	Items            []FiscalItem         `json:"items"`
+	Taxes            []FiscalTax          `json:"taxes"`
+	Totals           FiscalTotals         `json:"totals"`
+	Authorization    *FiscalAuthorization `json:"authorization,omitempty"`
+}
+
+// FiscalParty emitente, destinatário ou tomador do documento fiscal
+type FiscalParty struct {
+	Document              string         `json:"document,omitempty"`
+	DocumentType          string         `json:"documentType,omitempty"`
+	Name                  string         `json:"name,omitempty"`
+	TradeName             string         `json:"tradeName,omitempty"`
+	StateRegistration     string         `json:"stateRegistration,omitempty"`
+	MunicipalRegistration string         `json:"municipalRegistration,omitempty"`
+	Address               *FiscalAddress `json:"address,omitempty"`
+}
+
+// FiscalAddress endereço de uma parte do documento fiscal
+type FiscalAddress struct {
+	Street   string `json:"street,omitempty"`
+	Number   string `json:"number,omitempty"`
+	District string `json:"district,omitempty"`
+	City     string `json:"city,omitempty"`
+	CityCode string `json:"cityCode,omitempty"`
+	State    string `json:"state,omitempty"`
+	ZipCode  string `json:"zipCode,omitempty"`
+}
+
+// FiscalItem item (produto, serviço ou componente de frete) do documento fiscal
+type FiscalItem struct {
+	Number      int         `json:"number"`
+	Code        string      `json:"code,omitempty"`
+	Description string      `json:"description"`
+	NCM         string      `json:"ncm,omitempty"`
+	CFOP        string      `json:"cfop,omitempty"`
+	Unit        string      `json:"unit,omitempty"`
+	Quantity    float64     `json:"quantity,omitempty"`
+	UnitPrice   float64     `json:"unitPrice,omitempty"`
+	Total       float64     `json:"total"`
+	Taxes       []FiscalTax `json:"taxes,omitempty"`
+}
+
+// FiscalTax tributo destacado no documento ou no item
+type FiscalTax struct {
+	Type   string  `json:"type"`
+	Base   float64 `json:"base,omitempty"`
+	Rate   float64 `json:"rate,omitempty"`
+	Amount float64 `json:"amount"`
+}
+
+// FiscalTotals totais do documento fiscal
+type FiscalTotals struct {
+	Products  float64 `json:"products,omitempty"`
+	Services  float64 `json:"services,omitempty"`
+	Freight   float64 `json:"freight,omitempty"`
+	Insurance float64 `json:"insurance,omitempty"`
+	Discount  float64 `json:"discount,omitempty"`
+	Other     float64 `json:"other,omitempty"`
+	Taxes     float64 `json:"taxes,omitempty"`
+	Total     float64 `json:"total"`
+}
+
+// FiscalAuthorization protocolo de autorização da SEFAZ/ambiente nacional
+type FiscalAuthorization struct {
+	Protocol     string `json:"protocol,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Message      string `json:"message,omitempty"`
+	AuthorizedAt string `json:"authorizedAt,omitempty"`
+	AccessKey    string `json:"accessKey,omitempty"`
+}
@@ -11,8 +11,65 @@ type Response struct {
 
 // Data dados da resposta
 type Data struct {
-	Text string `json:"text"`
-	Info Info   `json:"info"`
+	Text     string          `json:"text"`
+	Info     Info            `json:"info"`
+	Pages    []Page          `json:"pages,omitempty"`
+	Document *FiscalDocument `json:"document,omitempty"`
+	// FailedChunks blocos de páginas que falharam (o restante do documento foi processado)
+	FailedChunks []ChunkFailure `json:"failedChunks,omitempty"`
+	// PII dados pessoais encontrados no texto (com o pedido de detecção, máscara ou tokenização)
+	PII *PIIReport `json:"pii,omitempty"`
+	// Redacted cópia do arquivo com os dados pessoais tarjados (quando pedida)
+	Redacted *Artifact `json:"redacted,omitempty"`
+}
+
+// Artifact arquivo gerado pelo processamento, baixado em URL até ExpiresAt
+type Artifact struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	ExpiresAt   string `json:"expiresAt"`
+	Pages       int    `json:"pages,omitempty"`
+	// Regions quantidade de regiões tarjadas
+	Regions int `json:"regions"`
+}
+
+// PIIReport resultado da detecção de dados pessoais
+type PIIReport struct {
+	// Mode "detect", "mask" ou "tokenize"
+	Mode     string       `json:"mode"`
+	Findings []PIIFinding `json:"findings"`
+}
+
+// PIIFinding dado pessoal encontrado; offsets em caracteres (runas) dentro de Data.Text, fim exclusivo
+type PIIFinding struct {
+	// Type "cpf", "cnpj", "email", "phone", "card" ou "address"
+	Type  string `json:"type"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	// Page página do achado, quando o documento tem páginas
+	Page int `json:"page,omitempty"`
+	// Replacement texto colocado no lugar do valor (máscara ou token)
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Page texto de uma página; offsets em caracteres (runas) dentro de Data.Text, fim exclusivo
+type Page struct {
+	Number      int    `json:"number"`
+	Text        string `json:"text"`
+	StartOffset int    `json:"startOffset"`
+	EndOffset   int    `json:"endOffset"`
+}
+
+// ChunkFailure falha no processamento de um intervalo de páginas
+type ChunkFailure struct {
+	FirstPage int    `json:"firstPage"`
+	LastPage  int    `json:"lastPage"`
+	Error     string `json:"error"`
+	// Code código do erro quando conhecido (ex.: GEMINI_SAFETY_BLOCKED)
+	Code string `json:"code,omitempty"`
 }
 
 // Error estrutura de erro
@@ -20,23 +77,81 @@ type Error struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	// RequestID identificador da requisição (header X-Request-ID), para correlacionar com os logs
+	RequestID string `json:"requestId,omitempty"`
 }
 
 // Info informações do arquivo processado
 type Info struct {
-	FileName    string `json:"fileName"`
-	FileType    string `json:"fileType"`
-	FileSize    int64  `json:"fileSize"`
-	ProcessedAt string `json:"processedAt"`
+	FileName       string `json:"fileName"`
+	FileType       string `json:"fileType"`
+	FileSize       int64  `json:"fileSize"`
+	ProcessedAt    string `json:"processedAt"`
 	ProcessingTime string `json:"processingTime,omitempty"`
+	// Usage tokens consumidos no Gemini (usageMetadata), um item por modelo utilizado
+	Usage []TokenUsage `json:"usage,omitempty"`
+	// Scan veredito da verificação de malware feita antes do processamento
+	Scan *ScanVerdict `json:"scan,omitempty"`
+}
+
+// ScanVerdict resultado da verificação de malware do arquivo
+type ScanVerdict struct {
+	// Scanner "clamd" ou "none" (verificação desativada)
+	Scanner string `json:"scanner"`
+	// Status "clean", "infected", "not_scanned" ou "error" (scanner indisponível)
+	Status    string `json:"status"`
+	Signature string `json:"signature,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+}
+
+// TokenUsage tokens consumidos em um modelo
+type TokenUsage struct {
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	TotalTokens  int     `json:"totalTokens"`
+	CostUSD      float64 `json:"costUSD"`
+	// Estimated contagem estimada localmente (extrações nativas, sem Gemini)
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// UsageReport consumo agregado por dia, cliente e modelo
+type UsageReport struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Client   string      `json:"client,omitempty"`
+	Currency string      `json:"currency"`
+	Totals   UsageTotals `json:"totals"`
+	Items    []UsageItem `json:"items"`
+}
+
+// UsageTotals totais do relatório de uso
+type UsageTotals struct {
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUSD"`
+}
+
+// UsageItem consumo de um cliente em um modelo num dia
+type UsageItem struct {
+	Date         string  `json:"date"`
+	Client       string  `json:"client"`
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUSD"`
 }
 
 // SupportedTypes tipos de arquivo suportados
 type SupportedTypes struct {
-	Documents []string `json:"documents"`
-	Images    []string `json:"images"`
-	MaxSize   string   `json:"maxSize"`
-	MaxSizeBytes int64  `json:"maxSizeBytes"`
+	Documents    []string `json:"documents"`
+	Images       []string `json:"images"`
+	MaxSize      string   `json:"maxSize"`
+	MaxSizeBytes int64    `json:"maxSizeBytes"`
+	// MaxSizeByType limites próprios por extensão, em bytes
+	MaxSizeByType map[string]int64 `json:"maxSizeByType,omitempty"`
 }
 
 // HealthResponse resposta do health check
@@ -50,12 +165,41 @@ type HealthResponse struct {
 
 // StatusResponse resposta de status
 type StatusResponse struct {
-	Service   string    `json:"service"`
-	Version   string    `json:"version"`
-	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Environment string  `json:"environment"`
-	Features  []string  `json:"features"`
+	Service     string    `json:"service"`
+	Version     string    `json:"version"`
+	Status      string    `json:"status"`
+	Timestamp   time.Time `json:"timestamp"`
+	Environment string    `json:"environment"`
+	Features    []string  `json:"features"`
+	// Gemini saúde e estado do circuit breaker de cada modelo/versão da API já utilizado
+	Gemini []ModelHealth `json:"gemini,omitempty"`
+	// GeminiKeys uso de cada chave de API do pool (identificada por índice e hash, nunca pela chave)
+	GeminiKeys []APIKeyUsage `json:"geminiKeys,omitempty"`
+}
+
+// APIKeyUsage contadores de uso de uma chave de API do Gemini
+type APIKeyUsage struct {
+	ID            string     `json:"id"`
+	Requests      int64      `json:"requests"`
+	Successes     int64      `json:"successes"`
+	RateLimited   int64      `json:"rateLimited"`
+	Failures      int64      `json:"failures"`
+	LastUsedAt    *time.Time `json:"lastUsedAt,omitempty"`
+	CooldownUntil *time.Time `json:"cooldownUntil,omitempty"`
+}
+
+// ModelHealth saúde de um modelo Gemini em uma versão da API
+type ModelHealth struct {
+	Model               string     `json:"model"`
+	APIVersion          string     `json:"apiVersion"`
+	State               string     `json:"state"` // closed, open ou half-open
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Successes           int64      `json:"successes"`
+	Failures            int64      `json:"failures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	LastFailureAt       *time.Time `json:"lastFailureAt,omitempty"`
+	OpenUntil           *time.Time `json:"openUntil,omitempty"`
 }
 
 // NewSuccessResponse cria nova resposta de sucesso
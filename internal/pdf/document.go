@@ -0,0 +1,263 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// ErrEncrypted PDFs criptografados não podem ser divididos
+var ErrEncrypted = errors.New("PDF criptografado não pode ser dividido")
+
+// inheritableKeys atributos de página herdados da árvore de páginas
+var inheritableKeys = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// objectHeader início de um objeto indireto ("12 0 obj")
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// Document PDF carregado em memória com a lista de páginas resolvida
+type Document struct {
+	objects map[int]Object
+	pages   []page
+}
+
+// page página folha com os atributos herdados já resolvidos
+type page struct {
+	ref  Ref
+	dict Dict
+}
+
+// Open carrega um PDF a partir dos bytes. Os objetos são localizados por varredura sequencial
+// (tolerante a tabelas xref quebradas) e objetos dentro de object streams são expandidos.
+func Open(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("arquivo não é um PDF")
+	}
+
+	doc := &Document{objects: make(map[int]Object)}
+	var root Object
+	var encrypted bool
+
+	pos := 0
+	for pos < len(data) {
+		loc := objectHeader.FindIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		if start > 0 && !isWhitespace(data[start-1]) && !isDelimiter(data[start-1]) {
+			pos = start + 1
+			continue
+		}
+
+		p := &parser{data: data, pos: start}
+		num, obj, err := p.parseIndirect()
+		if err != nil {
+			pos = start + 1
+			continue
+		}
+		// Definições posteriores (atualizações incrementais) substituem as anteriores
+		doc.objects[num] = obj
+		pos = p.pos
+
+		if stream, ok := obj.(*Stream); ok && stream.Dict["Type"] == Name("XRef") {
+			if r, ok := stream.Dict["Root"]; ok {
+				root = r
+			}
+			if _, ok := stream.Dict["Encrypt"]; ok {
+				encrypted = true
+			}
+		}
+	}
+
+	// Trailers clássicos (o último prevalece)
+	for _, idx := range allIndexes(data, []byte("trailer")) {
+		p := &parser{data: data, pos: idx + len("trailer")}
+		obj, err := p.parseObject()
+		if err != nil {
+			continue
+		}
+		if trailer, ok := obj.(Dict); ok {
+			if r, ok := trailer["Root"]; ok {
+				root = r
+			}
+			if _, ok := trailer["Encrypt"]; ok {
+				encrypted = true
+			}
+		}
+	}
+
+	if encrypted {
+		return nil, ErrEncrypted
+	}
+
+	doc.expandObjectStreams()
+
+	if root == nil {
+		// Sem trailer: procurar o catálogo diretamente
+		for num, obj := range doc.objects {
+			if dict, ok := obj.(Dict); ok && dict["Type"] == Name("Catalog") {
+				root = Ref{Num: num}
+				break
+			}
+		}
+	}
+	catalog, ok := doc.resolve(root).(Dict)
+	if !ok {
+		return nil, fmt.Errorf("catálogo do PDF não encontrado")
+	}
+
+	visited := make(map[int]bool)
+	if err := doc.collectPages(catalog["Pages"], Dict{}, visited); err != nil {
+		return nil, err
+	}
+	if len(doc.pages) == 0 {
+		return nil, fmt.Errorf("PDF sem páginas")
+	}
+	return doc, nil
+}
+
+// PageCount número de páginas do documento
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// resolve segue referências indiretas
+func (d *Document) resolve(obj Object) Object {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.Num]
+	}
+	return nil
+}
+
+// expandObjectStreams extrai objetos comprimidos em /Type /ObjStm (PDF 1.5+)
+func (d *Document) expandObjectStreams() {
+	for _, obj := range d.objects {
+		stream, ok := obj.(*Stream)
+		if !ok || stream.Dict["Type"] != Name("ObjStm") {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := d.resolve(stream.Dict["N"]).(int64)
+		first, _ := d.resolve(stream.Dict["First"]).(int64)
+		if first < 0 || first > int64(len(data)) {
+			continue
+		}
+
+		header := &parser{data: data[:first]}
+		for i := int64(0); i < count; i++ {
+			header.skipSpace()
+			num, err1 := strconv.Atoi(header.keyword())
+			header.skipSpace()
+			offset, err2 := strconv.Atoi(header.keyword())
+			if err1 != nil || err2 != nil {
+				break
+			}
+			// Objetos definidos fora de object streams têm prioridade (atualizações incrementais)
+			if _, exists := d.objects[num]; exists {
+				continue
+			}
+			if offset < 0 || offset >= len(data)-int(first) {
+				continue
+			}
+			p := &parser{data: data, pos: int(first) + offset}
+			value, err := p.parseObject()
+			if err != nil {
+				continue
+			}
+			d.objects[num] = value
+		}
+	}
+}
+
+// decodeStream decodifica streams sem filtro ou com FlateDecode (sem preditores)
+func (d *Document) decodeStream(stream *Stream) ([]byte, error) {
+	filter := d.resolve(stream.Dict["Filter"])
+	if arr, ok := filter.(Array); ok {
+		if len(arr) != 1 {
+			return nil, fmt.Errorf("cadeia de filtros não suportada")
+		}
+		filter = arr[0]
+	}
+	switch filter {
+	case nil:
+		return stream.Data, nil
+	case Name("FlateDecode"):
+		reader, err := zlib.NewReader(bytes.NewReader(stream.Data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("filtro não suportado: %v", filter)
+}
+
+// collectPages percorre a árvore de páginas em ordem, propagando atributos herdáveis
+func (d *Document) collectPages(node Object, inherited Dict, visited map[int]bool) error {
+	ref, isRef := node.(Ref)
+	if isRef {
+		if visited[ref.Num] {
+			return fmt.Errorf("ciclo na árvore de páginas")
+		}
+		visited[ref.Num] = true
+	}
+	dict, ok := d.resolve(node).(Dict)
+	if !ok {
+		return nil
+	}
+
+	attrs := make(Dict, len(inheritableKeys))
+	for key, value := range inherited {
+		attrs[key] = value
+	}
+	for _, key := range inheritableKeys {
+		if value, ok := dict[key]; ok {
+			attrs[key] = value
+		}
+	}
+
+	kids, hasKids := d.resolve(dict["Kids"]).(Array)
+	if dict["Type"] == Name("Pages") || (hasKids && dict["Type"] != Name("Page")) {
+		for _, kid := range kids {
+			if err := d.collectPages(kid, attrs, visited); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	pageDict := make(Dict, len(dict)+len(attrs))
+	for key, value := range attrs {
+		pageDict[key] = value
+	}
+	for key, value := range dict {
+		pageDict[key] = value
+	}
+	d.pages = append(d.pages, page{ref: ref, dict: pageDict})
+	return nil
+}
+
+func allIndexes(data, sep []byte) []int {
+	var indexes []int
+	offset := 0
+	for {
+		idx := bytes.Index(data[offset:], sep)
+		if idx < 0 {
+			return indexes
+		}
+		indexes = append(indexes, offset+idx)
+		offset += idx + len(sep)
+	}
+}
@@ -0,0 +1,292 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// build monta um PDF com os objetos numerados a partir de 1 (vazio pula o número) e, se informado,
+// o trailer clássico
+func build(trailer string, objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	if trailer != "" {
+		fmt.Fprintf(&buf, "trailer\n%s\n%%%%EOF\n", trailer)
+	}
+	return buf.Bytes()
+}
+
+// stream objeto stream com os dados informados
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// content stream de conteúdo de página com o texto informado
+func content(text string) string {
+	return stream("", "BT /F1 12 Tf ("+text+") Tj ET")
+}
+
+// objStm object stream comprimido com os objetos informados (número e corpo, nessa ordem)
+func objStm(objects ...interface{}) string {
+	var header, body strings.Builder
+	for i := 0; i < len(objects); i += 2 {
+		fmt.Fprintf(&header, "%d %d ", objects[i], body.Len())
+		body.WriteString(objects[i+1].(string) + "\n")
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(header.String() + body.String()))
+	zw.Close()
+	dict := fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(objects)/2, header.Len())
+	return stream(dict, compressed.String())
+}
+
+// treeObjects árvore de páginas com dois níveis: Resources e MediaBox herdados da raiz, a terceira
+// página sobrepõe a MediaBox. Ordem das páginas: 5, 6, 4.
+var treeObjects = []string{
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 /Resources << /Font << /F1 7 0 R >> >> /MediaBox [0 0 612 792] >>",
+	"<< /Type /Pages /Parent 2 0 R /Kids [5 0 R 6 0 R] /Count 2 >>",
+	"<< /Type /Page /Parent 2 0 R /Contents 10 0 R /MediaBox [0 0 100 100] >>",
+	"<< /Type /Page /Parent 3 0 R /Contents 8 0 R >>",
+	"<< /Type /Page /Parent 3 0 R /Contents 9 0 R >>",
+	"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	content("Pagina 1"),
+	content("Pagina 2"),
+	content("Pagina 3"),
+}
+
+func classicPDF() []byte {
+	return build("<< /Root 1 0 R /Size 11 >>", treeObjects...)
+}
+
+func TestOpen(t *testing.T) {
+	xrefStream := stream("/Type /XRef /Root 1 0 R /Size 13 /W [1 2 1]", "\x01\x00\x0f\x00")
+
+	cases := []struct {
+		name  string
+		data  []byte
+		pages int
+	}{
+		{"trailer clássico", classicPDF(), 3},
+		{"sem trailer, catálogo por varredura", build("", treeObjects...), 3},
+		{
+			// O catálogo indicado pelo xref stream prevalece sobre o catálogo solto (objeto 11)
+			"xref stream",
+			build("", append(append([]string{}, treeObjects...),
+				"<< /Type /Catalog /Pages 12 0 R >>",
+				"<< /Type /Pages /Kids [5 0 R] /Count 1 >>",
+				xrefStream)...),
+			3,
+		},
+		{
+			// Catálogo, árvore e páginas dentro de um object stream; o objeto 6 redefinido fora
+			// do stream (atualização incremental) prevalece
+			"object stream",
+			build("",
+				"", "", "", "", "",
+				"<< /Type /Page /Parent 3 0 R /Contents 9 0 R >>",
+				treeObjects[6], treeObjects[7], treeObjects[8], treeObjects[9],
+				objStm(
+					1, treeObjects[0], 2, treeObjects[1], 3, treeObjects[2],
+					4, treeObjects[3], 5, treeObjects[4], 6, "<< /Type /Page /Contents 8 0 R /Rotate 90 >>",
+				),
+				stream("/Type /XRef /Root 1 0 R /Size 13", "xref"),
+			),
+			3,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Open(tc.data)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if doc.PageCount() != tc.pages {
+				t.Fatalf("páginas = %d, esperado %d", doc.PageCount(), tc.pages)
+			}
+			// Ordem da árvore: as páginas apontam para os conteúdos 8, 9 e 10
+			for i, pg := range doc.pages {
+				if _, ok := pg.dict["Resources"]; !ok {
+					t.Errorf("página %d sem Resources herdados", i+1)
+				}
+				if ref, _ := pg.dict["Contents"].(Ref); ref.Num != 8+i {
+					t.Errorf("página %d com conteúdo %v, esperado %d", i+1, pg.dict["Contents"], 8+i)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenInheritsPageAttributes(t *testing.T) {
+	doc, err := Open(classicPDF())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if box := fmt.Sprint(doc.pages[0].dict["MediaBox"]); box != "[0 0 612 792]" {
+		t.Errorf("MediaBox herdada = %s", box)
+	}
+	if box := fmt.Sprint(doc.pages[2].dict["MediaBox"]); box != "[0 0 100 100]" {
+		t.Errorf("MediaBox própria da página deveria prevalecer: %s", box)
+	}
+}
+
+func TestOpenRejectsInvalidDocuments(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"não é PDF", []byte("GIF89a"), "não é um PDF"},
+		{"sem catálogo", build("", "<< /Type /Font >>"), "catálogo"},
+		{"sem páginas", build("", "<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>"), "sem páginas"},
+		{"ciclo na árvore", build("", "<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [2 0 R] /Count 1 >>"), "ciclo"},
+		{"criptografado", build("<< /Root 1 0 R /Encrypt 3 0 R >>", treeObjects...), "criptografado"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Open(tc.data)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("erro = %v, esperado %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestOpenHostileInputs entradas malformadas devem resultar em erro, nunca em panic
+func TestOpenHostileInputs(t *testing.T) {
+	// Object stream (objeto 1) com um catálogo sem páginas como objeto 2; cabeçalho e /First manipulados
+	hostileObjStm := func(header string, first int) []byte {
+		body := header + "<< /Type /Catalog /Pages 9 0 R >>"
+		return build("", stream(fmt.Sprintf("/Type /ObjStm /N 1 /First %d", first), body))
+	}
+
+	cases := map[string][]byte{
+		"/First negativo":             hostileObjStm("2 0 ", -5),
+		"/First além dos dados":       hostileObjStm("2 0 ", 9999),
+		"offset negativo":             hostileObjStm("2 -9 ", 4),
+		"offset além dos dados":       hostileObjStm("2 9999 ", 5),
+		"offset no fim dos dados":     hostileObjStm("2 33 ", 5),
+		"offset gigante":              hostileObjStm("2 9223372036854775807 ", 21),
+		"/Length gigante":             build("", "<< /Length 9223372036854775807 >>\nstream\nabc\nendstream"),
+		"stream sem endstream":        build("", "<< /Length 3 >>\nstream\nabc"),
+		"string sem fechamento":       []byte("%PDF-1.7\n1 0 obj\n(abc"),
+		"dicionário sem fechamento":   []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages"),
+		"trailer truncado":            append(build("", treeObjects[1:]...), "trailer\n<< /Root"...),
+		"referência negativa":         build("", "<< /Type /Catalog /Pages -2 0 R >>"),
+		"flate inválido":              build("", stream("/Type /ObjStm /N 1 /First 4 /Filter /FlateDecode", "lixo")),
+		"filtros em cadeia":           build("", stream("/Type /ObjStm /N 1 /First 4 /Filter [/A85 /FlateDecode]", "1 0 x")),
+		"apenas o cabeçalho":          []byte("%PDF-"),
+		"número de objeto gigante":    []byte("%PDF-1.7\n99999999999999999999 0 obj\n<< >>\nendobj\n"),
+		"hexadecimal sem fechamento":  []byte("%PDF-1.7\n1 0 obj\n<414243"),
+		"escape no fim do arquivo":    []byte("%PDF-1.7\n1 0 obj\n(abc\\"),
+		"array sem fechamento":        []byte("%PDF-1.7\n1 0 obj\n[1 2 3"),
+		"xref stream sem dicionário":  []byte("%PDF-1.7\n1 0 obj\nstream\nendstream\nendobj\n"),
+		"palavra-chave desconhecida":  []byte("%PDF-1.7\n1 0 obj\nfoo\nendobj\n"),
+		"catálogo aponta para número": build("", "<< /Type /Catalog /Pages 5 >>"),
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Open(data); err == nil {
+				t.Fatal("esperava erro")
+			}
+		})
+	}
+
+	// Arquivo válido cortado em vários pontos
+	full := classicPDF()
+	for cut := 5; cut < len(full); cut += 37 {
+		if doc, err := Open(full[:cut]); err == nil && doc.PageCount() == 0 {
+			t.Fatalf("corte em %d: documento sem páginas aceito", cut)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	doc, err := Open(classicPDF())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	cases := []struct {
+		perChunk int
+		ranges   [][2]int
+	}{
+		{1, [][2]int{{1, 1}, {2, 2}, {3, 3}}},
+		{2, [][2]int{{1, 2}, {3, 3}}},
+		{5, [][2]int{{1, 3}}},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%d por bloco", tc.perChunk), func(t *testing.T) {
+			chunks, err := doc.Split(tc.perChunk)
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			if len(chunks) != len(tc.ranges) {
+				t.Fatalf("blocos = %d, esperado %d", len(chunks), len(tc.ranges))
+			}
+			for i, chunk := range chunks {
+				if chunk.FirstPage != tc.ranges[i][0] || chunk.LastPage != tc.ranges[i][1] {
+					t.Errorf("bloco %d = %d-%d, esperado %v", i, chunk.FirstPage, chunk.LastPage, tc.ranges[i])
+				}
+				sub, err := Open(chunk.Data)
+				if err != nil {
+					t.Fatalf("bloco %d não abre: %v", i, err)
+				}
+				if sub.PageCount() != chunk.LastPage-chunk.FirstPage+1 {
+					t.Errorf("bloco %d com %d páginas", i, sub.PageCount())
+				}
+			}
+		})
+	}
+
+	if _, err := doc.Split(0); err == nil {
+		t.Error("Split(0) deveria falhar")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	doc, err := Open(classicPDF())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	data, err := doc.Extract(3, 3)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	for _, want := range []string{"(Pagina 3)", "/Helvetica", "[0 0 100 100]"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("página extraída sem %s", want)
+		}
+	}
+	// Páginas fora do intervalo (e a árvore original) não são arrastadas junto
+	for _, unwanted := range []string{"(Pagina 1)", "(Pagina 2)", "/Count 3"} {
+		if bytes.Contains(data, []byte(unwanted)) {
+			t.Errorf("página extraída contém %s", unwanted)
+		}
+	}
+
+	sub, err := Open(data)
+	if err != nil {
+		t.Fatalf("PDF extraído não abre: %v", err)
+	}
+	if _, ok := sub.pages[0].dict["Resources"]; !ok {
+		t.Error("Resources herdados deveriam ser copiados para a página")
+	}
+
+	for _, r := range [][2]int{{0, 1}, {2, 1}, {1, 4}} {
+		if _, err := doc.Extract(r[0], r[1]); err == nil {
+			t.Errorf("Extract(%d, %d) deveria falhar", r[0], r[1])
+		}
+	}
+}
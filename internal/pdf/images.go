@@ -0,0 +1,77 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// ImagePage página composta apenas por uma imagem; Width e Height em pontos (1/72 pol.)
+type ImagePage struct {
+	Image  image.Image
+	Width  float64
+	Height float64
+}
+
+// WriteImages gera um PDF em que cada página é uma imagem JPEG ocupando a página inteira
+// (sem camada de texto: usado para devolver documentos rasterizados e tarjados)
+func WriteImages(pages []ImagePage, quality int) ([]byte, error) {
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("documento sem páginas")
+	}
+
+	// 1 = catálogo, 2 = raiz da árvore de páginas; depois página, conteúdo e imagem de cada página
+	w := &writer{next: 3}
+	kids := make(Array, 0, len(pages))
+	for i, pg := range pages {
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, pg.Image, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("erro ao codificar página %d: %v", i+1, err)
+		}
+
+		pageNum, contentNum, imageNum := w.next, w.next+1, w.next+2
+		w.next += 3
+		bounds := pg.Image.Bounds()
+		// O codificador JPEG grava imagens *image.Gray com um único componente
+		colorSpace := Name("DeviceRGB")
+		if _, gray := pg.Image.(*image.Gray); gray {
+			colorSpace = Name("DeviceGray")
+		}
+		content := fmt.Sprintf("q %s 0 0 %s 0 0 cm /Im0 Do Q", formatNumber(pg.Width), formatNumber(pg.Height))
+
+		w.objects = append(w.objects,
+			outObject{num: pageNum, value: Dict{
+				"Type":      Name("Page"),
+				"Parent":    Ref{Num: 2},
+				"MediaBox":  Array{int64(0), int64(0), pg.Width, pg.Height},
+				"Resources": Dict{"XObject": Dict{"Im0": Ref{Num: imageNum}}},
+				"Contents":  Ref{Num: contentNum},
+			}},
+			outObject{num: contentNum, value: &Stream{Dict: Dict{}, Data: []byte(content)}},
+			outObject{num: imageNum, value: &Stream{Dict: Dict{
+				"Type":             Name("XObject"),
+				"Subtype":          Name("Image"),
+				"Width":            int64(bounds.Dx()),
+				"Height":           int64(bounds.Dy()),
+				"ColorSpace":       colorSpace,
+				"BitsPerComponent": int64(8),
+				"Filter":           Name("DCTDecode"),
+			}, Data: encoded.Bytes()}},
+		)
+		kids = append(kids, Ref{Num: pageNum})
+	}
+
+	w.objects = append(w.objects,
+		outObject{num: 1, value: Dict{"Type": Name("Catalog"), "Pages": Ref{Num: 2}}},
+		outObject{num: 2, value: Dict{"Type": Name("Pages"), "Kids": kids, "Count": int64(len(kids))}},
+	)
+	return w.serialize(), nil
+}
+
+// formatNumber número PDF sem notação científica
+func formatNumber(v float64) string {
+	var buf bytes.Buffer
+	writeObject(&buf, v)
+	return buf.String()
+}
@@ -0,0 +1,117 @@
+// Package pdf implementa um leitor/escritor mínimo de PDF em Go puro, suficiente para
+// dividir documentos em intervalos de páginas sem depender de bibliotecas externas.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Object valor PDF: nil (null), bool, int64, float64, Name, String, Ref, Array, Dict ou *Stream
+type Object interface{}
+
+// Name nome PDF (sem a barra inicial)
+type Name string
+
+// String string PDF (bytes crus, já sem escapes)
+type String []byte
+
+// Ref referência indireta "N G R"
+type Ref struct {
+	Num int
+	Gen int
+}
+
+// Array array PDF
+type Array []Object
+
+// Dict dicionário PDF
+type Dict map[Name]Object
+
+// Stream objeto stream (dicionário + dados ainda codificados)
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// writeObject serializa um objeto PDF
+func writeObject(buf *bytes.Buffer, obj Object) {
+	switch v := obj.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case Name:
+		writeName(buf, v)
+	case String:
+		buf.WriteByte('<')
+		buf.WriteString(fmt.Sprintf("%X", []byte(v)))
+		buf.WriteByte('>')
+	case Ref:
+		fmt.Fprintf(buf, "%d %d R", v.Num, v.Gen)
+	case Array:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			writeObject(buf, item)
+		}
+		buf.WriteByte(']')
+	case Dict:
+		writeDict(buf, v)
+	case *Stream:
+		dict := make(Dict, len(v.Dict)+1)
+		for key, value := range v.Dict {
+			dict[key] = value
+		}
+		dict["Length"] = int64(len(v.Data))
+		writeDict(buf, dict)
+		buf.WriteString("\nstream\n")
+		buf.Write(v.Data)
+		buf.WriteString("\nendstream")
+	default:
+		buf.WriteString("null")
+	}
+}
+
+// writeDict serializa um dicionário com as chaves em ordem (saída determinística)
+func writeDict(buf *bytes.Buffer, dict Dict) {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	buf.WriteString("<<")
+	for _, key := range keys {
+		writeName(buf, Name(key))
+		buf.WriteByte(' ')
+		writeObject(buf, dict[Name(key)])
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(">>")
+}
+
+// writeName serializa um nome, escapando caracteres especiais como #xx
+func writeName(buf *bytes.Buffer, name Name) {
+	buf.WriteByte('/')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x21 || c > 0x7e || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(buf, "#%02X", c)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+}
@@ -0,0 +1,357 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// parser analisador léxico/sintático de objetos PDF sobre um buffer em memória
+type parser struct {
+	data []byte
+	pos  int
+}
+
+func isWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace ignora espaços em branco e comentários
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isWhitespace(c) {
+			p.pos++
+			continue
+		}
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// keyword lê uma palavra regular (números, true, obj, R...) sem consumir delimitadores
+func (p *parser) keyword() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isWhitespace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// peekKeyword lê a próxima palavra sem avançar
+func (p *parser) peekKeyword() string {
+	saved := p.pos
+	p.skipSpace()
+	word := p.keyword()
+	p.pos = saved
+	return word
+}
+
+// parseObject lê o próximo objeto direto (referências "N G R" incluídas)
+func (p *parser) parseObject() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("fim inesperado do arquivo")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		p.pos++
+		return p.parseName(), nil
+	case c == '(':
+		p.pos++
+		return p.parseLiteralString()
+	case c == '<':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '<' {
+			p.pos += 2
+			return p.parseDict()
+		}
+		p.pos++
+		return p.parseHexString()
+	case c == '[':
+		p.pos++
+		return p.parseArray()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumberOrRef()
+	}
+
+	word := p.keyword()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("caractere inesperado %q na posição %d", p.data[p.pos], p.pos)
+	}
+	return nil, fmt.Errorf("palavra-chave inesperada %q na posição %d", word, p.pos)
+}
+
+// parseName lê um nome (após a barra), decodificando #xx
+func (p *parser) parseName() Name {
+	raw := p.keyword()
+	if !bytes.ContainsRune([]byte(raw), '#') {
+		return Name(raw)
+	}
+	var out []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, raw[i])
+	}
+	return Name(out)
+}
+
+// parseLiteralString lê uma string entre parênteses (com aninhamento e escapes)
+func (p *parser) parseLiteralString() (Object, error) {
+	var out []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(out), nil
+			}
+			out = append(out, c)
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, fmt.Errorf("string literal incompleta")
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// continuação de linha
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+			case '\n':
+				// continuação de linha
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						value = value*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return nil, fmt.Errorf("string literal sem fechamento")
+}
+
+// parseHexString lê uma string hexadecimal <...>
+func (p *parser) parseHexString() (Object, error) {
+	var digits []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			out := make([]byte, len(digits)/2)
+			for i := range out {
+				v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("string hexadecimal inválida")
+				}
+				out[i] = byte(v)
+			}
+			return String(out), nil
+		}
+		if !isWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return nil, fmt.Errorf("string hexadecimal sem fechamento")
+}
+
+// parseArray lê um array [...]
+func (p *parser) parseArray() (Object, error) {
+	arr := Array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, fmt.Errorf("array sem fechamento")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		item, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, item)
+	}
+}
+
+// parseDict lê um dicionário <<...>>
+func (p *parser) parseDict() (Object, error) {
+	dict := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 >= len(p.data) {
+			return nil, fmt.Errorf("dicionário sem fechamento")
+		}
+		if p.data[p.pos] == '>' && p.data[p.pos+1] == '>' {
+			p.pos += 2
+			return dict, nil
+		}
+		if p.data[p.pos] != '/' {
+			return nil, fmt.Errorf("chave de dicionário inválida na posição %d", p.pos)
+		}
+		p.pos++
+		key := p.parseName()
+		value, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+// parseNumberOrRef lê um número; se seguido de "G R", devolve uma referência
+func (p *parser) parseNumberOrRef() (Object, error) {
+	word := p.keyword()
+	num, err := strconv.ParseInt(word, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(word, 64)
+		if ferr != nil {
+			return nil, fmt.Errorf("número inválido %q", word)
+		}
+		return f, nil
+	}
+
+	// Tentar "N G R"
+	saved := p.pos
+	p.skipSpace()
+	genWord := p.keyword()
+	if gen, err := strconv.Atoi(genWord); err == nil && genWord != "" {
+		p.skipSpace()
+		if p.keyword() == "R" {
+			return Ref{Num: int(num), Gen: gen}, nil
+		}
+	}
+	p.pos = saved
+	return num, nil
+}
+
+// parseIndirect lê "N G obj ... endobj" a partir da posição atual
+func (p *parser) parseIndirect() (int, Object, error) {
+	p.skipSpace()
+	num, err := strconv.Atoi(p.keyword())
+	if err != nil {
+		return 0, nil, fmt.Errorf("número de objeto inválido")
+	}
+	p.skipSpace()
+	if _, err := strconv.Atoi(p.keyword()); err != nil {
+		return 0, nil, fmt.Errorf("geração de objeto inválida")
+	}
+	p.skipSpace()
+	if p.keyword() != "obj" {
+		return 0, nil, fmt.Errorf("palavra-chave obj ausente")
+	}
+
+	obj, err := p.parseObject()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if dict, ok := obj.(Dict); ok && p.peekKeyword() == "stream" {
+		p.skipSpace()
+		p.keyword()
+		data, err := p.readStreamData(dict)
+		if err != nil {
+			return 0, nil, err
+		}
+		obj = &Stream{Dict: dict, Data: data}
+	}
+
+	if p.peekKeyword() == "endobj" {
+		p.skipSpace()
+		p.keyword()
+	}
+	return num, obj, nil
+}
+
+// readStreamData lê os dados do stream usando /Length direto quando confiável, ou buscando "endstream"
+func (p *parser) readStreamData(dict Dict) ([]byte, error) {
+	// Após "stream" vem CRLF ou LF
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	if length, ok := dict["Length"].(int64); ok && length >= 0 && length <= int64(len(p.data)-start) {
+		end := start + int(length)
+		check := &parser{data: p.data, pos: end}
+		if check.peekKeyword() == "endstream" {
+			check.skipSpace()
+			check.keyword()
+			p.pos = check.pos
+			return p.data[start:end], nil
+		}
+	}
+
+	idx := bytes.Index(p.data[start:], []byte("endstream"))
+	if idx < 0 {
+		return nil, fmt.Errorf("stream sem endstream")
+	}
+	end := start + idx
+	p.pos = end + len("endstream")
+	// Remover EOL que antecede endstream
+	if end > start && p.data[end-1] == '\n' {
+		end--
+	}
+	if end > start && p.data[end-1] == '\r' {
+		end--
+	}
+	return p.data[start:end], nil
+}
@@ -0,0 +1,182 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Chunk sub-documento com um intervalo de páginas (numeração a partir de 1, inclusiva)
+type Chunk struct {
+	FirstPage int
+	LastPage  int
+	Data      []byte
+}
+
+// Split divide o documento em sub-documentos de até pagesPerChunk páginas
+func (d *Document) Split(pagesPerChunk int) ([]Chunk, error) {
+	if pagesPerChunk <= 0 {
+		return nil, fmt.Errorf("páginas por bloco deve ser maior que zero")
+	}
+
+	var chunks []Chunk
+	for first := 1; first <= len(d.pages); first += pagesPerChunk {
+		last := first + pagesPerChunk - 1
+		if last > len(d.pages) {
+			last = len(d.pages)
+		}
+		data, err := d.Extract(first, last)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao extrair páginas %d-%d: %v", first, last, err)
+		}
+		chunks = append(chunks, Chunk{FirstPage: first, LastPage: last, Data: data})
+	}
+	return chunks, nil
+}
+
+// Extract gera um novo PDF contendo apenas as páginas first..last
+func (d *Document) Extract(first, last int) ([]byte, error) {
+	if first < 1 || last > len(d.pages) || first > last {
+		return nil, fmt.Errorf("intervalo de páginas inválido: %d-%d (documento com %d páginas)", first, last, len(d.pages))
+	}
+
+	w := &writer{
+		doc:       d,
+		mapping:   make(map[int]int),
+		pageNodes: make(map[int]bool),
+		// 1 = catálogo, 2 = raiz da árvore de páginas
+		next: 3,
+	}
+	for num, obj := range d.objects {
+		if dict, ok := obj.(Dict); ok && (dict["Type"] == Name("Page") || dict["Type"] == Name("Pages")) {
+			w.pageNodes[num] = true
+		}
+	}
+
+	selected := d.pages[first-1 : last]
+	kids := make(Array, 0, len(selected))
+	pageNums := make([]int, 0, len(selected))
+	for _, pg := range selected {
+		num := w.next
+		w.next++
+		if pg.ref.Num != 0 {
+			w.mapping[pg.ref.Num] = num
+		}
+		kids = append(kids, Ref{Num: num})
+		pageNums = append(pageNums, num)
+	}
+
+	for i, pg := range selected {
+		dict := make(Dict, len(pg.dict))
+		for key, value := range pg.dict {
+			if key == "Parent" {
+				continue
+			}
+			dict[key] = value
+		}
+		copied := w.copy(dict).(Dict)
+		copied["Parent"] = Ref{Num: 2}
+		w.objects = append(w.objects, outObject{num: pageNums[i], value: copied})
+	}
+
+	// Objetos referenciados pelas páginas (copiados em largura, renumerando)
+	for len(w.queue) > 0 {
+		oldNum := w.queue[0]
+		w.queue = w.queue[1:]
+		w.objects = append(w.objects, outObject{num: w.mapping[oldNum], value: w.copy(d.objects[oldNum])})
+	}
+
+	w.objects = append(w.objects,
+		outObject{num: 1, value: Dict{"Type": Name("Catalog"), "Pages": Ref{Num: 2}}},
+		outObject{num: 2, value: Dict{"Type": Name("Pages"), "Kids": kids, "Count": int64(len(kids))}},
+	)
+	return w.serialize(), nil
+}
+
+// writer estado da cópia de objetos para o novo documento
+type writer struct {
+	doc       *Document
+	mapping   map[int]int
+	pageNodes map[int]bool
+	next      int
+	queue     []int
+	objects   []outObject
+}
+
+type outObject struct {
+	num   int
+	value Object
+}
+
+// copy copia um objeto trocando referências antigas pelas novas; referências a páginas
+// fora do intervalo (ou à árvore de páginas original) viram null para não arrastar o documento inteiro
+func (w *writer) copy(obj Object) Object {
+	switch v := obj.(type) {
+	case Ref:
+		if newNum, ok := w.mapping[v.Num]; ok {
+			return Ref{Num: newNum}
+		}
+		if w.pageNodes[v.Num] {
+			return nil
+		}
+		if _, exists := w.doc.objects[v.Num]; !exists {
+			return nil
+		}
+		newNum := w.next
+		w.next++
+		w.mapping[v.Num] = newNum
+		w.queue = append(w.queue, v.Num)
+		return Ref{Num: newNum}
+	case Array:
+		out := make(Array, len(v))
+		for i, item := range v {
+			out[i] = w.copy(item)
+		}
+		return out
+	case Dict:
+		out := make(Dict, len(v))
+		for key, value := range v {
+			out[key] = w.copy(value)
+		}
+		return out
+	case *Stream:
+		dict := make(Dict, len(v.Dict))
+		for key, value := range v.Dict {
+			if key == "Length" {
+				continue
+			}
+			dict[key] = w.copy(value)
+		}
+		return &Stream{Dict: dict, Data: v.Data}
+	}
+	return obj
+}
+
+// serialize escreve o PDF final com tabela xref clássica
+func (w *writer) serialize() []byte {
+	size := w.next
+	offsets := make([]int, size)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for _, obj := range w.objects {
+		offsets[obj.num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", obj.num)
+		writeObject(&buf, obj.value)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n", size)
+	buf.WriteString("0000000000 65535 f \n")
+	for num := 1; num < size; num++ {
+		if offsets[num] == 0 {
+			buf.WriteString("0000000000 65535 f \n")
+			continue
+		}
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[num])
+	}
+	buf.WriteString("trailer\n")
+	writeDict(&buf, Dict{"Size": int64(size), "Root": Ref{Num: 1}})
+	fmt.Fprintf(&buf, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes()
+}
@@ -0,0 +1,247 @@
+// Package pii detecta dados pessoais (CPF, CNPJ, e-mail, telefone, cartão, endereço) no texto
+// extraído e os mascara ou substitui por tokens antes da resposta.
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// Mode tratamento dos dados pessoais encontrados
+type Mode string
+
+const (
+	// ModeOff sem detecção
+	ModeOff Mode = "off"
+	// ModeDetect só lista os achados, sem alterar o texto
+	ModeDetect Mode = "detect"
+	// ModeMask troca letras e dígitos por "*", mantendo o tamanho (offsets iguais aos do original)
+	ModeMask Mode = "mask"
+	// ModeTokenize troca o valor por um token estável ("[CPF_3f9a0c1b7e]"): o mesmo valor gera o mesmo token
+	ModeTokenize Mode = "tokenize"
+)
+
+// ParseMode valida o modo informado na requisição ou na configuração
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case ModeOff, ModeDetect, ModeMask, ModeTokenize:
+		return mode, nil
+	case "":
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("modo de PII inválido %q: use off, detect, mask ou tokenize", value)
+	}
+}
+
+// Tipos de dado pessoal
+const (
+	TypeCPF     = "cpf"
+	TypeCNPJ    = "cnpj"
+	TypeEmail   = "email"
+	TypePhone   = "phone"
+	TypeCard    = "card"
+	TypeAddress = "address"
+	TypeName    = "name"
+)
+
+// detector expressão de um tipo com validação opcional do trecho encontrado
+type detector struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// detectors em ordem de prioridade: um trecho já atribuído a um tipo não é reavaliado pelos seguintes
+// (um CNPJ válido não vira cartão, um CPF não vira telefone)
+var detectors = []detector{
+	{TypeCNPJ, regexp.MustCompile(`\b\d{2}\.?\d{3}\.?\d{3}/?\d{4}-?\d{2}\b`), ValidCNPJ},
+	{TypeCPF, regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`), ValidCPF},
+	{TypeCard, regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), ValidCard},
+	{TypeEmail, regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`), nil},
+	{TypePhone, regexp.MustCompile(`(?:\+55[ -]?)?(?:\(\d{2}\) ?|\b\d{2}[ -])?\b9?\d{4}[ -]?\d{4}\b`), validPhone},
+	{TypeAddress, regexp.MustCompile(`(?i)\b(?:rua|r\.|avenida|av\.|alameda|al\.|travessa|tv\.|praça|pça\.|rodovia|estrada|largo)[ \t]+[^\n,;]{2,60}?,?[ \t]*(?:n[º°o.]?[ \t]*)?\d{1,5}\b`), nil},
+	{TypeAddress, regexp.MustCompile(`\b\d{5}-\d{3}\b`), nil},
+}
+
+// Redactor aplica os modos de PII; key assina os tokens do modo tokenize
+type Redactor struct {
+	key []byte
+}
+
+// NewRedactor cria o redator; a mesma chave gera os mesmos tokens entre processamentos e instâncias
+func NewRedactor(key []byte) *Redactor {
+	return &Redactor{key: key}
+}
+
+// match trecho com dado pessoal (offsets em bytes do texto original)
+type match struct {
+	kind       string
+	start, end int
+}
+
+// detect encontra os dados pessoais do texto, sem sobreposição, em ordem de posição
+func detect(text string) []match {
+	var found []match
+	overlaps := func(start, end int) bool {
+		for _, m := range found {
+			if start < m.end && m.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range detectors {
+		for _, loc := range d.re.FindAllStringIndex(text, -1) {
+			value := text[loc[0]:loc[1]]
+			if (d.valid == nil || d.valid(value)) && !overlaps(loc[0], loc[1]) {
+				found = append(found, match{kind: d.kind, start: loc[0], end: loc[1]})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	return found
+}
+
+// Span dado pessoal encontrado; offsets em caracteres (runas) do texto analisado, fim exclusivo
+type Span struct {
+	Type       string
+	Start, End int
+}
+
+// Find lista os dados pessoais do texto sem alterá-lo
+func Find(text string) []Span {
+	matches := detect(text)
+	spans := make([]Span, 0, len(matches))
+	last, offset := 0, 0
+	for _, m := range matches {
+		offset += utf8.RuneCountInString(text[last:m.start])
+		length := utf8.RuneCountInString(text[m.start:m.end])
+		spans = append(spans, Span{Type: m.kind, Start: offset, End: offset + length})
+		offset += length
+		last = m.end
+	}
+	return spans
+}
+
+// Apply detecta os dados pessoais e aplica o modo. Devolve o texto resultante, os achados com
+// offsets em caracteres (runas) nesse texto e a conversão de offsets do texto original para ele.
+func (r *Redactor) Apply(text string, mode Mode) (string, []models.PIIFinding, func(int) int) {
+	identity := func(offset int) int { return offset }
+	if mode == ModeOff {
+		return text, nil, identity
+	}
+	matches := detect(text)
+	if len(matches) == 0 {
+		return text, nil, identity
+	}
+
+	// shift deslocamento acumulado a partir de cada achado (só muda no modo tokenize)
+	type shift struct {
+		origStart, origEnd, delta int
+	}
+	var shifts []shift
+
+	var out strings.Builder
+	findings := make([]models.PIIFinding, 0, len(matches))
+	last, origRunes, outRunes := 0, 0, 0
+	for _, m := range matches {
+		before := text[last:m.start]
+		out.WriteString(before)
+		origRunes += utf8.RuneCountInString(before)
+		outRunes += utf8.RuneCountInString(before)
+
+		value := text[m.start:m.end]
+		replacement := value
+		switch mode {
+		case ModeMask:
+			replacement = maskValue(value)
+		case ModeTokenize:
+			replacement = r.token(m.kind, value)
+		}
+		out.WriteString(replacement)
+
+		valueRunes := utf8.RuneCountInString(value)
+		replacementRunes := utf8.RuneCountInString(replacement)
+		finding := models.PIIFinding{Type: m.kind, Start: outRunes, End: outRunes + replacementRunes}
+		if mode != ModeDetect {
+			finding.Replacement = replacement
+		}
+		findings = append(findings, finding)
+		shifts = append(shifts, shift{origRunes, origRunes + valueRunes, outRunes + replacementRunes - origRunes - valueRunes})
+
+		origRunes += valueRunes
+		outRunes += replacementRunes
+		last = m.end
+	}
+	out.WriteString(text[last:])
+
+	convert := func(offset int) int {
+		delta := 0
+		for _, s := range shifts {
+			if offset <= s.origStart {
+				break
+			}
+			if offset < s.origEnd {
+				// Dentro de um achado substituído: o limite vai para o fim da substituição
+				return s.origEnd + s.delta
+			}
+			delta = s.delta
+		}
+		return offset + delta
+	}
+	return out.String(), findings, convert
+}
+
+// Replace aplica o modo a um campo que já é um dado pessoal inteiro (documento, nome ou endereço
+// de uma parte do XML fiscal); off e detect devolvem o valor sem alteração
+func (r *Redactor) Replace(kind, value string, mode Mode) string {
+	if value == "" {
+		return value
+	}
+	switch mode {
+	case ModeMask:
+		return maskValue(value)
+	case ModeTokenize:
+		return r.token(kind, value)
+	}
+	return value
+}
+
+// maskValue troca letras e dígitos por "*", mantendo pontuação e tamanho
+func maskValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return '*'
+		}
+		return r
+	}, value)
+}
+
+// token HMAC-SHA256 do valor normalizado (só dígitos nos documentos, minúsculas nos demais)
+func (r *Redactor) token(kind, value string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	switch kind {
+	case TypeCPF, TypeCNPJ, TypeCard, TypePhone:
+		normalized = strings.Map(func(c rune) rune {
+			if c >= '0' && c <= '9' {
+				return c
+			}
+			return -1
+		}, value)
+		if kind == TypePhone && len(normalized) >= 12 && strings.HasPrefix(normalized, "55") {
+			normalized = normalized[2:]
+		}
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind + ":" + normalized))
+	return fmt.Sprintf("[%s_%s]", strings.ToUpper(kind), hex.EncodeToString(mac.Sum(nil))[:10])
+}
@@ -0,0 +1,119 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidators(t *testing.T) {
+	cases := []struct {
+		name  string
+		valid func(string) bool
+		value string
+		want  bool
+	}{
+		{"cpf", ValidCPF, "529.982.247-25", true},
+		{"cpf sem pontuação", ValidCPF, "52998224725", true},
+		{"cpf dígito errado", ValidCPF, "529.982.247-26", false},
+		{"cpf repetido", ValidCPF, "111.111.111-11", false},
+		{"cnpj", ValidCNPJ, "11.222.333/0001-81", true},
+		{"cnpj dígito errado", ValidCNPJ, "11.222.333/0001-82", false},
+		{"cartão", ValidCard, "4111 1111 1111 1111", true},
+		{"cartão inválido", ValidCard, "4111 1111 1111 1112", false},
+		{"telefone com DDD", validPhone, "(11) 98765-4321", true},
+		{"telefone com país", validPhone, "+55 21 3456-7890", true},
+		{"número sem hífen nem DDD", validPhone, "12345678", false},
+	}
+	for _, tc := range cases {
+		if got := tc.valid(tc.value); got != tc.want {
+			t.Errorf("%s: %q = %v, esperado %v", tc.name, tc.value, got, tc.want)
+		}
+	}
+}
+
+const sample = "Cliente José, CPF 529.982.247-25, e-mail jose@exemplo.com.br, " +
+	"tel. (11) 98765-4321, cartão 4111 1111 1111 1111, empresa 11.222.333/0001-81, " +
+	"Rua das Flores, 123 - CEP 01310-100. Pedido 123.456.789-00."
+
+func TestDetect(t *testing.T) {
+	_, findings, _ := NewRedactor([]byte("k")).Apply(sample, ModeDetect)
+
+	var kinds []string
+	runes := []rune(sample)
+	for _, f := range findings {
+		kinds = append(kinds, f.Type+"="+string(runes[f.Start:f.End]))
+		if f.Replacement != "" {
+			t.Errorf("detect não substitui o valor: %+v", f)
+		}
+	}
+	want := []string{
+		"cpf=529.982.247-25",
+		"email=jose@exemplo.com.br",
+		"phone=(11) 98765-4321",
+		"card=4111 1111 1111 1111",
+		"cnpj=11.222.333/0001-81",
+		"address=Rua das Flores, 123",
+		"address=01310-100",
+	}
+	if strings.Join(kinds, "|") != strings.Join(want, "|") {
+		t.Fatalf("achados:\n%s\nesperado:\n%s", strings.Join(kinds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMaskKeepsOffsets(t *testing.T) {
+	text, findings, convert := NewRedactor([]byte("k")).Apply(sample, ModeMask)
+
+	if utf8.RuneCountInString(text) != utf8.RuneCountInString(sample) || convert(40) != 40 {
+		t.Fatal("a máscara deve manter o tamanho do texto")
+	}
+	if !strings.Contains(text, "CPF ***.***.***-**") || strings.Contains(text, "jose@") {
+		t.Fatalf("texto mascarado: %s", text)
+	}
+	if !strings.Contains(text, "Pedido 123.456.789-00") {
+		t.Fatal("número com dígito verificador inválido não é CPF")
+	}
+	if findings[0].Replacement != "***.***.***-**" {
+		t.Fatalf("replacement = %q", findings[0].Replacement)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	redactor := NewRedactor([]byte("chave"))
+	text, findings, convert := redactor.Apply("A: 529.982.247-25; B: 52998224725; fim", ModeTokenize)
+
+	if findings[0].Replacement != findings[1].Replacement || !strings.HasPrefix(findings[0].Replacement, "[CPF_") {
+		t.Fatalf("o mesmo CPF deve gerar o mesmo token: %+v", findings)
+	}
+	runes := []rune(text)
+	for _, f := range findings {
+		if string(runes[f.Start:f.End]) != f.Replacement {
+			t.Fatalf("offsets devem apontar para o token no texto devolvido: %+v em %q", f, text)
+		}
+	}
+	// "fim" começa em 35 no original
+	if got := string(runes[convert(35):]); got != "fim" {
+		t.Fatalf("conversão de offset: %q", got)
+	}
+
+	if token := redactor.Replace(TypeCPF, "52998224725", ModeTokenize); token != findings[0].Replacement {
+		t.Fatalf("Replace deveria gerar o mesmo token do texto: %q", token)
+	}
+	if value := redactor.Replace(TypeName, "Ana Souza", ModeDetect); value != "Ana Souza" {
+		t.Fatalf("detect não altera o valor: %q", value)
+	}
+
+	other, _, _ := NewRedactor([]byte("outra")).Apply("529.982.247-25", ModeTokenize)
+	if other == findings[0].Replacement {
+		t.Fatal("chaves diferentes devem gerar tokens diferentes")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(" Mask "); err != nil || mode != ModeMask {
+		t.Fatalf("ParseMode = %v, %v", mode, err)
+	}
+	if _, err := ParseMode("hash"); err == nil {
+		t.Fatal("modo desconhecido deveria ser recusado")
+	}
+}
@@ -0,0 +1,107 @@
+package pii
+
+// digits dígitos do texto, ignorando pontuação e espaços
+func digits(s string) []int {
+	var result []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			result = append(result, int(r-'0'))
+		}
+	}
+	return result
+}
+
+// repeated todos os dígitos iguais (000.000.000-00 passa no cálculo, mas não é documento)
+func repeated(d []int) bool {
+	for _, v := range d[1:] {
+		if v != d[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCPF confere os dois dígitos verificadores do CPF
+func ValidCPF(s string) bool {
+	d := digits(s)
+	if len(d) != 11 || repeated(d) {
+		return false
+	}
+	for check := 9; check <= 10; check++ {
+		sum := 0
+		for i := 0; i < check; i++ {
+			sum += d[i] * (check + 1 - i)
+		}
+		digit := sum * 10 % 11
+		if digit == 10 {
+			digit = 0
+		}
+		if digit != d[check] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCNPJ confere os dois dígitos verificadores do CNPJ
+func ValidCNPJ(s string) bool {
+	d := digits(s)
+	if len(d) != 14 || repeated(d) {
+		return false
+	}
+	weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	for check := 12; check <= 13; check++ {
+		sum := 0
+		for i := 0; i < check; i++ {
+			sum += d[i] * weights[i+13-check]
+		}
+		digit := 11 - sum%11
+		if digit >= 10 {
+			digit = 0
+		}
+		if digit != d[check] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCard confere o número de cartão (13 a 19 dígitos) pelo algoritmo de Luhn
+func ValidCard(s string) bool {
+	d := digits(s)
+	if len(d) < 13 || len(d) > 19 || repeated(d) {
+		return false
+	}
+	sum := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		v := d[i]
+		if (len(d)-1-i)%2 == 1 {
+			v *= 2
+			if v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+	}
+	return sum%10 == 0
+}
+
+// validPhone telefone brasileiro: com DDD (10 ou 11 dígitos, opcionalmente com +55) ou local com hífen
+func validPhone(s string) bool {
+	d := digits(s)
+	if len(d) >= 12 && d[0] == 5 && d[1] == 5 {
+		d = d[2:]
+	}
+	switch len(d) {
+	case 10, 11:
+		// DDD de 11 a 99 e celular começando com 9
+		return d[0] != 0 && d[1] != 0 && (len(d) == 10 || d[2] == 9)
+	case 8, 9:
+		for _, r := range s {
+			if r == '-' {
+				return true
+			}
+		}
+	}
+	return false
+}
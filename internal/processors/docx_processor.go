@@ -1,11 +1,16 @@
 package processors
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
+
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DocxProcessor processador de arquivos DOCX usando Google Gemini
@@ -21,47 +26,58 @@ func NewDocxProcessor(geminiExtractor GeminiExtractor) *DocxProcessor {
 }
 
 // Process processa arquivo DOCX usando Google Gemini
-func (p *DocxProcessor) Process(file io.Reader, filename string) (string, error) {
-	log.Printf("📄 Processando DOCX: %s", filename)
+func (p *DocxProcessor) Process(ctx context.Context, file io.Reader, filename string) (string, error) {
+	result, err := p.ProcessStructured(ctx, file, filename)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
 
+// ProcessStructured processa arquivo DOCX usando Google Gemini, mantendo o texto separado por página
+func (p *DocxProcessor) ProcessStructured(ctx context.Context, file io.Reader, filename string) (*Result, error) {
 	// Verificar se Gemini está disponível
 	if p.geminiExtractor == nil || !p.geminiExtractor.IsAvailable() {
-		return "", fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
+		return nil, fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
 	}
 
 	// Criar arquivo temporário para poder reler
+	_, tempSpan := tracing.Start(ctx, "tempfile.write")
 	tempFile, err := os.CreateTemp("", "temp_*.docx")
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar arquivo temporário: %v", err)
+		tracing.End(tempSpan, err)
+		return nil, fmt.Errorf("erro ao criar arquivo temporário: %v", err)
 	}
 	defer os.Remove(tempFile.Name())
 	defer tempFile.Close()
 
 	// Copiar conteúdo do arquivo
-	_, err = io.Copy(tempFile, file)
+	written, err := io.Copy(tempFile, file)
+	tempSpan.SetAttributes(attribute.Int64("file.size", written))
+	tracing.End(tempSpan, err)
 	if err != nil {
-		return "", fmt.Errorf("erro ao copiar arquivo: %v", err)
+		return nil, fmt.Errorf("erro ao copiar arquivo: %v", err)
 	}
 
 	// Processar com Gemini
-	log.Printf("🤖 Processando DOCX com Google Gemini...")
+	slog.DebugContext(ctx, "processando DOCX com o Gemini")
 
 	// Ler arquivo novamente para passar para Gemini
 	fileReader, err := os.Open(tempFile.Name())
 	if err != nil {
-		return "", fmt.Errorf("erro ao reabrir arquivo para Gemini: %v", err)
+		return nil, fmt.Errorf("erro ao reabrir arquivo para Gemini: %v", err)
 	}
 	defer fileReader.Close()
 
-	text, err := p.geminiExtractor.ExtractTextFromFile(fileReader, filename)
+	result, err := p.geminiExtractor.ExtractTextFromFile(ctx, fileReader, filename)
 	if err != nil {
-		return "", fmt.Errorf("erro ao processar DOCX com Gemini: %v", err)
+		return nil, fmt.Errorf("erro ao processar DOCX com Gemini: %w", err)
 	}
 
-	if len(strings.TrimSpace(text)) < 10 {
-		return "", fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)")
+	if len(strings.TrimSpace(result.Text)) < 10 {
+		return nil, WithUsage(fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)"), result.Usage)
 	}
 
-	log.Printf("✅ Gemini extraiu texto do DOCX: %d caracteres", len(text))
-	return strings.TrimSpace(text), nil
+	slog.InfoContext(ctx, "texto extraído do DOCX", "chars", len(result.Text), "pages", len(result.Pages))
+	return result, nil
 }
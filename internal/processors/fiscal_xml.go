@@ -0,0 +1,602 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// Namespaces dos documentos fiscais eletrônicos reconhecidos
+const (
+	nsNFe          = "http://www.portalfiscal.inf.br/nfe"
+	nsCTe          = "http://www.portalfiscal.inf.br/cte"
+	nsNFSeNacional = "http://www.sped.fazenda.gov.br/nfse"
+	nsNFSeABRASF   = "http://www.abrasf.org.br/nfse.xsd"
+)
+
+// errNotFiscal indica um XML válido que não é NF-e, CT-e nem NFS-e
+var errNotFiscal = errors.New("XML não reconhecido como NF-e, CT-e ou NFS-e")
+
+// parseFiscalDocument identifica o tipo de documento pelo namespace e extrai os dados estruturados.
+// XMLs dos mesmos namespaces sem o grupo da nota (eventos de cancelamento e CC-e, lotes de RPS,
+// DPS) não são documentos fiscais e seguem como texto genérico.
+func parseFiscalDocument(root *xmlNode) (*models.FiscalDocument, error) {
+	switch {
+	case root.findSpace(func(s string) bool { return s == nsNFe }) != nil:
+		if inf := root.find("infNFe"); inf != nil {
+			return parseNFe(root, inf), nil
+		}
+	case root.findSpace(func(s string) bool { return s == nsCTe }) != nil:
+		if inf := root.find("infCte"); inf != nil {
+			return parseCTe(root, inf), nil
+		}
+	case root.findSpace(func(s string) bool { return s == nsNFSeNacional }) != nil:
+		if inf := root.find("infNFSe"); inf != nil {
+			return parseNFSeNacional(inf), nil
+		}
+	case root.findSpace(func(s string) bool { return strings.Contains(s, "abrasf.org.br") }) != nil,
+		root.Name == "InfNfse" || root.find("InfNfse") != nil:
+		inf := root
+		if inf.Name != "InfNfse" {
+			inf = root.find("InfNfse")
+		}
+		if inf != nil {
+			return parseNFSeABRASF(inf), nil
+		}
+	}
+	return nil, errNotFiscal
+}
+
+// parseNFe extrai NF-e (modelo 55) e NFC-e (modelo 65)
+func parseNFe(root, inf *xmlNode) *models.FiscalDocument {
+	ide := inf.child("ide")
+	doc := &models.FiscalDocument{
+		Type:      "NF-e",
+		Namespace: nsNFe,
+		Model:     ide.text("mod"),
+		Series:    ide.text("serie"),
+		Number:    ide.text("nNF"),
+		IssuedAt:  firstNonEmpty(ide.text("dhEmi"), ide.text("dEmi")),
+		Operation: ide.text("natOp"),
+		Issuer:    parseParty(inf.child("emit"), "enderEmit"),
+		Items:     []models.FiscalItem{},
+	}
+	if doc.Model == "65" {
+		doc.Type = "NFC-e"
+	}
+	if dest := inf.child("dest"); dest != nil {
+		recipient := parseParty(dest, "enderDest")
+		doc.Recipient = &recipient
+	}
+
+	for i, det := range inf.all("det") {
+		prod := det.child("prod")
+		number, err := strconv.Atoi(det.attr("nItem"))
+		if err != nil {
+			number = i + 1
+		}
+		doc.Items = append(doc.Items, models.FiscalItem{
+			Number:      number,
+			Code:        prod.text("cProd"),
+			Description: prod.text("xProd"),
+			NCM:         prod.text("NCM"),
+			CFOP:        prod.text("CFOP"),
+			Unit:        prod.text("uCom"),
+			Quantity:    parseAmount(prod.text("qCom")),
+			UnitPrice:   parseAmount(prod.text("vUnCom")),
+			Total:       parseAmount(prod.text("vProd")),
+			Taxes:       parseItemTaxes(det.child("imposto")),
+		})
+	}
+
+	tot := inf.path("total", "ICMSTot")
+	doc.Taxes = withoutZero(collectTaxes(
+		taxFrom(tot, "ICMS", "vBC", "", "vICMS"),
+		taxFrom(tot, "ICMS-ST", "vBCST", "", "vST"),
+		taxFrom(tot, "FCP", "", "", "vFCP"),
+		taxFrom(tot, "IPI", "", "", "vIPI"),
+		taxFrom(tot, "II", "", "", "vII"),
+		taxFrom(tot, "PIS", "", "", "vPIS"),
+		taxFrom(tot, "COFINS", "", "", "vCOFINS"),
+	))
+	doc.Totals = models.FiscalTotals{
+		Products:  parseAmount(tot.text("vProd")),
+		Freight:   parseAmount(tot.text("vFrete")),
+		Insurance: parseAmount(tot.text("vSeg")),
+		Discount:  parseAmount(tot.text("vDesc")),
+		Other:     parseAmount(tot.text("vOutro")),
+		Taxes:     parseAmount(tot.text("vTotTrib")),
+		Total:     parseAmount(tot.text("vNF")),
+	}
+
+	prot := root.find("protNFe").child("infProt")
+	doc.Authorization = parseAuthorization(prot, "chNFe")
+
+	verifyAccessKey(doc, strings.TrimPrefix(inf.attr("Id"), "NFe"), ide, "nNF", "cNF")
+	return doc
+}
+
+// parseCTe extrai CT-e (modelo 57); os componentes do valor da prestação viram itens
+func parseCTe(root, inf *xmlNode) *models.FiscalDocument {
+	ide := inf.child("ide")
+	doc := &models.FiscalDocument{
+		Type:      "CT-e",
+		Namespace: nsCTe,
+		Model:     ide.text("mod"),
+		Series:    ide.text("serie"),
+		Number:    ide.text("nCT"),
+		IssuedAt:  ide.text("dhEmi"),
+		Operation: ide.text("natOp"),
+		Issuer:    parseParty(inf.child("emit"), "enderEmit"),
+		Items:     []models.FiscalItem{},
+	}
+	if dest := inf.child("dest"); dest != nil {
+		recipient := parseParty(dest, "enderDest")
+		doc.Recipient = &recipient
+	}
+
+	vPrest := inf.child("vPrest")
+	for i, comp := range vPrest.all("Comp") {
+		doc.Items = append(doc.Items, models.FiscalItem{
+			Number:      i + 1,
+			Description: comp.text("xNome"),
+			CFOP:        ide.text("CFOP"),
+			Total:       parseAmount(comp.text("vComp")),
+		})
+	}
+
+	imp := inf.child("imp")
+	icms := imp.child("ICMS").first()
+	doc.Taxes = collectTaxes(
+		taxFrom(icms, "ICMS", "vBC", "pICMS", "vICMS"),
+		taxFrom(icms, "ICMS-ST", "vBCSTRet", "pICMSSTRet", "vICMSSTRet"),
+	)
+	doc.Totals = models.FiscalTotals{
+		Services: parseAmount(vPrest.text("vTPrest")),
+		Taxes:    parseAmount(imp.text("vTotTrib")),
+		Total:    parseAmount(vPrest.text("vTPrest")),
+	}
+
+	prot := root.find("protCTe").child("infProt")
+	doc.Authorization = parseAuthorization(prot, "chCTe")
+
+	verifyAccessKey(doc, strings.TrimPrefix(inf.attr("Id"), "CTe"), ide, "nCT", "cCT")
+	return doc
+}
+
+// parseNFSeNacional extrai NFS-e do padrão nacional (ambiente de dados nacional)
+func parseNFSeNacional(inf *xmlNode) *models.FiscalDocument {
+	dps := inf.path("DPS", "infDPS")
+	serv := dps.child("serv")
+	valores := inf.child("valores")
+
+	doc := &models.FiscalDocument{
+		Type:      "NFS-e",
+		Namespace: nsNFSeNacional,
+		Series:    dps.text("serie"),
+		Number:    inf.text("nNFSe"),
+		IssuedAt:  firstNonEmpty(dps.text("dhEmi"), inf.text("dhProc")),
+		Operation: serv.text("cServ", "xDescServ"),
+		Issuer:    parseParty(inf.child("emit"), "enderNac"),
+		Items: []models.FiscalItem{{
+			Number:      1,
+			Code:        serv.text("cServ", "cTribNac"),
+			Description: serv.text("cServ", "xDescServ"),
+			Total:       parseAmount(dps.text("valores", "vServPrest", "vServ")),
+		}},
+	}
+	if toma := dps.child("toma"); toma != nil {
+		recipient := parseParty(toma, "end")
+		doc.Recipient = &recipient
+	}
+
+	doc.Taxes = collectTaxes(taxFrom(valores, "ISSQN", "vBC", "pAliqAplic", "vISSQN"))
+	doc.Totals = models.FiscalTotals{
+		Services: parseAmount(dps.text("valores", "vServPrest", "vServ")),
+		Total:    parseAmount(valores.text("vLiq")),
+	}
+
+	key := strings.TrimPrefix(inf.attr("Id"), "NFS")
+	doc.AccessKey = key
+	var issues []string
+	if len(key) != 50 || !isDigits(key) {
+		issues = append(issues, fmt.Sprintf("chave de acesso da NFS-e deve ter 50 dígitos (recebida: %q)", key))
+	} else {
+		issues = append(issues, checkKeyDigit(key)...)
+		issues = append(issues, compareKeyPart("código do município emissor", key[0:7], dps.text("cLocEmi"), 7)...)
+		issues = append(issues, compareKeyPart("CNPJ/CPF do emitente", key[9:23], doc.Issuer.Document, 14)...)
+		issues = append(issues, compareKeyPart("número da NFS-e", key[23:36], doc.Number, 13)...)
+	}
+	setKeyVerdict(doc, issues)
+	return doc
+}
+
+// parseNFSeABRASF extrai NFS-e municipal no padrão ABRASF (v1 e v2), que não possui chave de acesso
+func parseNFSeABRASF(inf *xmlNode) *models.FiscalDocument {
+	servico := inf.find("Servico")
+	valores := servico.child("Valores")
+	valoresNfse := inf.child("ValoresNfse")
+
+	doc := &models.FiscalDocument{
+		Type:             "NFS-e",
+		Namespace:        nsNFSeABRASF,
+		Number:           inf.text("Numero"),
+		IssuedAt:         inf.text("DataEmissao"),
+		VerificationCode: inf.text("CodigoVerificacao"),
+		Operation:        inf.text("NaturezaOperacao"),
+		Issuer:           parseABRASFParty(inf.find("PrestadorServico"), "IdentificacaoPrestador"),
+		Items: []models.FiscalItem{{
+			Number:      1,
+			Code:        servico.text("ItemListaServico"),
+			Description: servico.text("Discriminacao"),
+			Total:       parseAmount(valores.text("ValorServicos")),
+		}},
+	}
+	if doc.Issuer.Name == "" {
+		doc.Issuer = parseABRASFParty(inf.find("Prestador"), "")
+	}
+	tomador := inf.find("TomadorServico")
+	if tomador == nil {
+		tomador = inf.find("Tomador")
+	}
+	if tomador != nil {
+		recipient := parseABRASFParty(tomador, "IdentificacaoTomador")
+		doc.Recipient = &recipient
+	}
+
+	issValues := valores
+	if valoresNfse != nil {
+		issValues = valoresNfse
+	}
+	doc.Taxes = withoutZero(collectTaxes(
+		taxFrom(issValues, "ISSQN", "BaseCalculo", "Aliquota", "ValorIss"),
+		taxFrom(valores, "PIS", "", "", "ValorPis"),
+		taxFrom(valores, "COFINS", "", "", "ValorCofins"),
+		taxFrom(valores, "INSS", "", "", "ValorInss"),
+		taxFrom(valores, "IR", "", "", "ValorIr"),
+		taxFrom(valores, "CSLL", "", "", "ValorCsll"),
+	))
+	doc.Totals = models.FiscalTotals{
+		Services: parseAmount(valores.text("ValorServicos")),
+		Discount: parseAmount(valores.text("DescontoIncondicionado")),
+		Total:    parseAmount(firstNonEmpty(issValues.text("ValorLiquidoNfse"), valores.text("ValorLiquidoNfse"), valores.text("ValorServicos"))),
+	}
+	return doc
+}
+
+// parseParty extrai emitente/destinatário nos layouts SEFAZ (NF-e, CT-e, NFS-e nacional)
+func parseParty(node *xmlNode, addressTag string) models.FiscalParty {
+	party := models.FiscalParty{
+		Name:                  node.text("xNome"),
+		TradeName:             node.text("xFant"),
+		StateRegistration:     node.text("IE"),
+		MunicipalRegistration: firstNonEmpty(node.text("IM"), node.text("im")),
+	}
+	switch {
+	case node.text("CNPJ") != "":
+		party.Document, party.DocumentType = node.text("CNPJ"), "CNPJ"
+	case node.text("CPF") != "":
+		party.Document, party.DocumentType = node.text("CPF"), "CPF"
+	case node.text("idEstrangeiro") != "":
+		party.Document, party.DocumentType = node.text("idEstrangeiro"), "ESTRANGEIRO"
+	}
+
+	addr := node.child(addressTag)
+	if addr != nil {
+		// NFS-e nacional aninha o endereço nacional dentro de "end"
+		if nested := addr.child("endNac"); nested != nil {
+			party.Address = &models.FiscalAddress{
+				Street:   addr.text("xLgr"),
+				Number:   addr.text("nro"),
+				District: addr.text("xBairro"),
+				CityCode: nested.text("cMun"),
+				ZipCode:  nested.text("CEP"),
+			}
+		} else {
+			party.Address = &models.FiscalAddress{
+				Street:   addr.text("xLgr"),
+				Number:   addr.text("nro"),
+				District: addr.text("xBairro"),
+				City:     addr.text("xMun"),
+				CityCode: addr.text("cMun"),
+				State:    addr.text("UF"),
+				ZipCode:  addr.text("CEP"),
+			}
+		}
+	}
+	return party
+}
+
+// parseABRASFParty extrai prestador/tomador no layout ABRASF
+func parseABRASFParty(node *xmlNode, identTag string) models.FiscalParty {
+	ident := node
+	if identTag != "" && node.child(identTag) != nil {
+		ident = node.child(identTag)
+	}
+	party := models.FiscalParty{
+		Name:                  node.text("RazaoSocial"),
+		TradeName:             node.text("NomeFantasia"),
+		MunicipalRegistration: ident.text("InscricaoMunicipal"),
+	}
+	if cnpj := ident.find("Cnpj"); cnpj != nil {
+		party.Document, party.DocumentType = strings.TrimSpace(cnpj.Text), "CNPJ"
+	} else if cpf := ident.find("Cpf"); cpf != nil {
+		party.Document, party.DocumentType = strings.TrimSpace(cpf.Text), "CPF"
+	}
+	if addr := node.child("Endereco"); addr != nil {
+		party.Address = &models.FiscalAddress{
+			Street:   addr.text("Endereco"),
+			Number:   addr.text("Numero"),
+			District: addr.text("Bairro"),
+			CityCode: firstNonEmpty(addr.text("CodigoMunicipio"), addr.text("Cidade")),
+			State:    firstNonEmpty(addr.text("Uf"), addr.text("Estado")),
+			ZipCode:  addr.text("Cep"),
+		}
+	}
+	return party
+}
+
+// parseItemTaxes extrai os tributos de um item de NF-e (grupo imposto)
+func parseItemTaxes(imposto *xmlNode) []models.FiscalTax {
+	icms := imposto.child("ICMS").first()
+	pis := imposto.child("PIS").first()
+	cofins := imposto.child("COFINS").first()
+	return collectTaxes(
+		taxFrom(icms, "ICMS", "vBC", "pICMS", "vICMS"),
+		taxFrom(icms, "ICMS-ST", "vBCST", "pICMSST", "vICMSST"),
+		taxFrom(imposto.path("IPI", "IPITrib"), "IPI", "vBC", "pIPI", "vIPI"),
+		taxFrom(imposto.child("II"), "II", "vBC", "", "vII"),
+		taxFrom(pis, "PIS", "vBC", "pPIS", "vPIS"),
+		taxFrom(cofins, "COFINS", "vBC", "pCOFINS", "vCOFINS"),
+		taxFrom(imposto.child("ISSQN"), "ISSQN", "vBC", "vAliq", "vISSQN"),
+	)
+}
+
+// taxFrom monta um tributo se a tag de valor existir no grupo
+func taxFrom(node *xmlNode, taxType, baseTag, rateTag, amountTag string) *models.FiscalTax {
+	if node == nil || node.child(amountTag) == nil {
+		return nil
+	}
+	tax := &models.FiscalTax{
+		Type:   taxType,
+		Amount: parseAmount(node.text(amountTag)),
+	}
+	if baseTag != "" {
+		tax.Base = parseAmount(node.text(baseTag))
+	}
+	if rateTag != "" {
+		tax.Rate = parseAmount(node.text(rateTag))
+	}
+	return tax
+}
+
+// collectTaxes descarta tributos ausentes
+func collectTaxes(taxes ...*models.FiscalTax) []models.FiscalTax {
+	result := []models.FiscalTax{}
+	for _, tax := range taxes {
+		if tax != nil {
+			result = append(result, *tax)
+		}
+	}
+	return result
+}
+
+// withoutZero remove tributos zerados dos totais do documento
+func withoutZero(taxes []models.FiscalTax) []models.FiscalTax {
+	result := []models.FiscalTax{}
+	for _, tax := range taxes {
+		if tax.Amount != 0 {
+			result = append(result, tax)
+		}
+	}
+	return result
+}
+
+// parseAuthorization extrai o protocolo de autorização (infProt)
+func parseAuthorization(prot *xmlNode, keyTag string) *models.FiscalAuthorization {
+	if prot == nil {
+		return nil
+	}
+	return &models.FiscalAuthorization{
+		Protocol:     prot.text("nProt"),
+		Status:       prot.text("cStat"),
+		Message:      prot.text("xMotivo"),
+		AuthorizedAt: prot.text("dhRecbto"),
+		AccessKey:    prot.text(keyTag),
+	}
+}
+
+// verifyAccessKey confere a chave de acesso de 44 dígitos (NF-e/CT-e) contra o conteúdo do XML.
+// Layout: cUF(2) AAMM(4) CNPJ/CPF(14) mod(2) serie(3) número(9) tpEmis(1) código numérico(8) DV(1)
+func verifyAccessKey(doc *models.FiscalDocument, key string, ide *xmlNode, numberTag, codeTag string) {
+	doc.AccessKey = key
+	if len(key) != 44 || !isDigits(key) {
+		setKeyVerdict(doc, []string{fmt.Sprintf("chave de acesso deve ter 44 dígitos (recebida: %q)", key)})
+		return
+	}
+
+	issuedAt := firstNonEmpty(ide.text("dhEmi"), ide.text("dEmi"))
+	yearMonth := ""
+	if len(issuedAt) >= 7 {
+		yearMonth = issuedAt[2:4] + issuedAt[5:7]
+	}
+
+	var issues []string
+	issues = append(issues, checkKeyDigit(key)...)
+	issues = append(issues, compareKeyPart("código da UF", key[0:2], ide.text("cUF"), 2)...)
+	issues = append(issues, compareKeyPart("ano/mês de emissão", key[2:6], yearMonth, 4)...)
+	issues = append(issues, compareKeyPart("CNPJ/CPF do emitente", key[6:20], doc.Issuer.Document, 14)...)
+	issues = append(issues, compareKeyPart("modelo", key[20:22], ide.text("mod"), 2)...)
+	issues = append(issues, compareKeyPart("série", key[22:25], ide.text("serie"), 3)...)
+	issues = append(issues, compareKeyPart("número", key[25:34], ide.text(numberTag), 9)...)
+	issues = append(issues, compareKeyPart("tipo de emissão", key[34:35], ide.text("tpEmis"), 1)...)
+	issues = append(issues, compareKeyPart("código numérico", key[35:43], ide.text(codeTag), 8)...)
+	issues = append(issues, compareKeyPart("dígito verificador", key[43:44], ide.text("cDV"), 1)...)
+	if doc.Authorization != nil && doc.Authorization.AccessKey != "" && doc.Authorization.AccessKey != key {
+		issues = append(issues, fmt.Sprintf("chave do protocolo de autorização (%s) difere da chave do documento", doc.Authorization.AccessKey))
+	}
+	setKeyVerdict(doc, issues)
+}
+
+// checkKeyDigit valida o dígito verificador (módulo 11, pesos 2 a 9) da chave
+func checkKeyDigit(key string) []string {
+	body, dv := key[:len(key)-1], int(key[len(key)-1]-'0')
+	if expected := accessKeyDigit(body); expected != dv {
+		return []string{fmt.Sprintf("dígito verificador da chave inválido (esperado %d, recebido %d)", expected, dv)}
+	}
+	return nil
+}
+
+// accessKeyDigit calcula o DV módulo 11 usado nas chaves de acesso
+func accessKeyDigit(digits string) int {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return 0
+	}
+	return 11 - rest
+}
+
+// compareKeyPart compara um trecho da chave com o valor do XML (ignorado se o XML não trouxer o campo)
+func compareKeyPart(label, keyPart, xmlValue string, width int) []string {
+	if xmlValue == "" {
+		return nil
+	}
+	expected := leftPad(xmlValue, width)
+	if expected != keyPart {
+		return []string{fmt.Sprintf("%s diverge entre chave (%s) e XML (%s)", label, keyPart, xmlValue)}
+	}
+	return nil
+}
+
+// setKeyVerdict registra o resultado da verificação da chave
+func setKeyVerdict(doc *models.FiscalDocument, issues []string) {
+	valid := len(issues) == 0
+	doc.AccessKeyValid = &valid
+	doc.Issues = append(doc.Issues, issues...)
+}
+
+// formatFiscalDocument gera o texto legível do documento fiscal
+func formatFiscalDocument(doc *models.FiscalDocument) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s nº %s", doc.Type, doc.Number)
+	if doc.Series != "" {
+		fmt.Fprintf(&b, " série %s", doc.Series)
+	}
+	if doc.Model != "" {
+		fmt.Fprintf(&b, " (modelo %s)", doc.Model)
+	}
+	b.WriteString("\n")
+
+	if doc.AccessKey != "" {
+		status := "válida"
+		if doc.AccessKeyValid != nil && !*doc.AccessKeyValid {
+			status = "INVÁLIDA"
+		}
+		fmt.Fprintf(&b, "Chave de acesso: %s (%s)\n", doc.AccessKey, status)
+	}
+	if doc.VerificationCode != "" {
+		fmt.Fprintf(&b, "Código de verificação: %s\n", doc.VerificationCode)
+	}
+	if doc.IssuedAt != "" {
+		fmt.Fprintf(&b, "Emissão: %s\n", doc.IssuedAt)
+	}
+	if doc.Operation != "" {
+		fmt.Fprintf(&b, "Natureza da operação: %s\n", doc.Operation)
+	}
+	fmt.Fprintf(&b, "Emitente: %s\n", formatParty(doc.Issuer))
+	if doc.Recipient != nil {
+		fmt.Fprintf(&b, "Destinatário: %s\n", formatParty(*doc.Recipient))
+	}
+
+	if len(doc.Items) > 0 {
+		b.WriteString("Itens:\n")
+		for _, item := range doc.Items {
+			if item.Quantity > 0 {
+				fmt.Fprintf(&b, "  %d. %s - %g %s x %.2f = %.2f\n", item.Number, item.Description, item.Quantity, item.Unit, item.UnitPrice, item.Total)
+			} else {
+				fmt.Fprintf(&b, "  %d. %s = %.2f\n", item.Number, item.Description, item.Total)
+			}
+		}
+	}
+	if len(doc.Taxes) > 0 {
+		b.WriteString("Tributos:\n")
+		for _, tax := range doc.Taxes {
+			fmt.Fprintf(&b, "  %s: %.2f\n", tax.Type, tax.Amount)
+		}
+	}
+	fmt.Fprintf(&b, "Valor total: %.2f\n", doc.Totals.Total)
+
+	if doc.Authorization != nil {
+		fmt.Fprintf(&b, "Protocolo de autorização: %s (%s %s)\n", doc.Authorization.Protocol, doc.Authorization.Status, doc.Authorization.Message)
+	}
+	for _, issue := range doc.Issues {
+		fmt.Fprintf(&b, "Atenção: %s\n", issue)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// formatParty formata uma parte como "Nome - CNPJ 000..."
+func formatParty(party models.FiscalParty) string {
+	if party.Document == "" {
+		return party.Name
+	}
+	return fmt.Sprintf("%s - %s %s", party.Name, party.DocumentType, party.Document)
+}
+
+// parseAmount converte valores monetários do XML (ponto decimal; aceita vírgula decimal com ponto
+// de milhar, como "1.234,56", em NFS-e municipais). Valores ilegíveis valem 0 e ficam no log.
+func parseAmount(value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	normalized := value
+	if strings.Contains(normalized, ",") {
+		normalized = strings.ReplaceAll(normalized, ".", "")
+		normalized = strings.Replace(normalized, ",", ".", 1)
+	}
+	amount, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		slog.Warn("valor monetário ilegível no XML, considerado zero", "value", value, "error", err)
+		return 0
+	}
+	return amount
+}
+
+func leftPad(value string, width int) string {
+	if len(value) >= width {
+		return value
+	}
+	return strings.Repeat("0", width-len(value)) + value
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
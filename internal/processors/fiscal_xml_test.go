@@ -0,0 +1,235 @@
+package processors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"backend-fileprocessing/internal/models"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func processXML(t *testing.T, content string) *Result {
+	t.Helper()
+	result, err := NewXMLProcessor().ProcessStructured(context.Background(), strings.NewReader(content), "documento.xml")
+	if err != nil {
+		t.Fatalf("ProcessStructured: %v", err)
+	}
+	if result.Document == nil {
+		t.Fatal("documento fiscal não reconhecido")
+	}
+	return result
+}
+
+func requireValidKey(t *testing.T, doc *models.FiscalDocument) {
+	t.Helper()
+	if doc.AccessKeyValid == nil || !*doc.AccessKeyValid || len(doc.Issues) != 0 {
+		t.Fatalf("chave %s deveria ser válida: %v", doc.AccessKey, doc.Issues)
+	}
+}
+
+func TestNFe(t *testing.T) {
+	result := processXML(t, fixture(t, "nfe.xml"))
+	doc := result.Document
+
+	requireValidKey(t, doc)
+	if doc.Type != "NF-e" || doc.Model != "55" || doc.Number != "1234" || doc.Series != "1" || doc.AccessKey != "35240111222333000181550010000012341000012349" {
+		t.Fatalf("identificação inesperada: %+v", doc)
+	}
+	if doc.Issuer.Document != "11222333000181" || doc.Issuer.DocumentType != "CNPJ" || doc.Issuer.Address.City != "São Paulo" {
+		t.Fatalf("emitente inesperado: %+v", doc.Issuer)
+	}
+	if doc.Recipient == nil || doc.Recipient.DocumentType != "CPF" || doc.Recipient.Name != "Ana Souza" {
+		t.Fatalf("destinatário inesperado: %+v", doc.Recipient)
+	}
+	if len(doc.Items) != 2 || doc.Items[0].Quantity != 10 || doc.Items[0].UnitPrice != 2.5 || doc.Items[1].Total != 30 {
+		t.Fatalf("itens inesperados: %+v", doc.Items)
+	}
+	if taxes := doc.Items[0].Taxes; len(taxes) != 3 || taxes[0].Type != "ICMS" || taxes[0].Rate != 18 || taxes[0].Amount != 4.5 {
+		t.Fatalf("tributos do item inesperados: %+v", taxes)
+	}
+	// Tributos zerados (vST) não entram nos totais
+	if len(doc.Taxes) != 3 || doc.Totals.Total != 60 || doc.Totals.Freight != 5 {
+		t.Fatalf("totais inesperados: %+v %+v", doc.Taxes, doc.Totals)
+	}
+	if doc.Authorization == nil || doc.Authorization.Protocol != "135240000012345" || doc.Authorization.Status != "100" {
+		t.Fatalf("autorização inesperada: %+v", doc.Authorization)
+	}
+	if !strings.Contains(result.Text, "Chave de acesso: 35240111222333000181550010000012341000012349 (válida)") {
+		t.Fatalf("texto inesperado: %q", result.Text)
+	}
+}
+
+func TestNFeAccessKeyMismatch(t *testing.T) {
+	valid := fixture(t, "nfe.xml")
+	key := "35240111222333000181550010000012341000012349"
+
+	cases := []struct {
+		name  string
+		old   string
+		new   string
+		issue string
+	}{
+		{"dígito verificador", key, key[:43] + "8", "dígito verificador da chave inválido (esperado 9, recebido 8)"},
+		{"CNPJ do emitente", "<CNPJ>11222333000181</CNPJ>", "<CNPJ>11444777000161</CNPJ>", "CNPJ/CPF do emitente diverge"},
+		{"ano/mês de emissão", "<dhEmi>2024-01-15", "<dhEmi>2024-02-15", "ano/mês de emissão diverge entre chave (2401) e XML (2402)"},
+		{"tamanho", `Id="NFe` + key, `Id="NFe` + key[:40], "chave de acesso deve ter 44 dígitos"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := strings.Replace(valid, tc.old, tc.new, 1)
+			if content == valid {
+				t.Fatalf("fixture sem %q", tc.old)
+			}
+			doc := processXML(t, content).Document
+			if doc.AccessKeyValid == nil || *doc.AccessKeyValid {
+				t.Fatalf("chave deveria ser inválida: %+v", doc.Issues)
+			}
+			found := false
+			for _, issue := range doc.Issues {
+				found = found || strings.Contains(issue, tc.issue)
+			}
+			if !found {
+				t.Fatalf("esperava %q em %v", tc.issue, doc.Issues)
+			}
+		})
+	}
+}
+
+func TestCTe(t *testing.T) {
+	doc := processXML(t, fixture(t, "cte.xml")).Document
+
+	requireValidKey(t, doc)
+	if doc.Type != "CT-e" || doc.Model != "57" || doc.Number != "567" {
+		t.Fatalf("identificação inesperada: %+v", doc)
+	}
+	if len(doc.Items) != 2 || doc.Items[1].Description != "Pedágio" || doc.Items[1].Total != 50 || doc.Items[0].CFOP != "5353" {
+		t.Fatalf("componentes da prestação inesperados: %+v", doc.Items)
+	}
+	if len(doc.Taxes) != 1 || doc.Taxes[0].Amount != 42 || doc.Totals.Total != 350 {
+		t.Fatalf("totais inesperados: %+v %+v", doc.Taxes, doc.Totals)
+	}
+	if doc.Recipient == nil || doc.Recipient.Document != "11444777000161" {
+		t.Fatalf("destinatário inesperado: %+v", doc.Recipient)
+	}
+}
+
+func TestNFSeNacional(t *testing.T) {
+	doc := processXML(t, fixture(t, "nfse_nacional.xml")).Document
+
+	requireValidKey(t, doc)
+	if doc.Type != "NFS-e" || doc.Namespace != nsNFSeNacional || doc.Number != "42" || doc.IssuedAt != "2024-03-05T13:55:00-03:00" {
+		t.Fatalf("identificação inesperada: %+v", doc)
+	}
+	if doc.Issuer.Address == nil || doc.Issuer.Address.Street != "Rua Augusta" || doc.Issuer.Address.ZipCode != "01305000" {
+		t.Fatalf("emitente inesperado: %+v", doc.Issuer)
+	}
+	if doc.Recipient == nil || doc.Recipient.Address.CityCode != "3304557" || doc.Recipient.Address.Street != "Rua Direita" {
+		t.Fatalf("tomador inesperado: %+v", doc.Recipient)
+	}
+	if len(doc.Items) != 1 || doc.Items[0].Code != "010101" || doc.Items[0].Total != 1000 {
+		t.Fatalf("serviço inesperado: %+v", doc.Items)
+	}
+	if len(doc.Taxes) != 1 || doc.Taxes[0].Type != "ISSQN" || doc.Taxes[0].Amount != 50 || doc.Totals.Total != 950 {
+		t.Fatalf("totais inesperados: %+v %+v", doc.Taxes, doc.Totals)
+	}
+
+	// Número da nota diferente do que está na chave
+	doc = processXML(t, strings.Replace(fixture(t, "nfse_nacional.xml"), "<nNFSe>42</nNFSe>", "<nNFSe>43</nNFSe>", 1)).Document
+	if doc.AccessKeyValid == nil || *doc.AccessKeyValid || len(doc.Issues) != 1 || !strings.Contains(doc.Issues[0], "número da NFS-e") {
+		t.Fatalf("divergência de número não detectada: %v", doc.Issues)
+	}
+}
+
+func TestNFSeABRASFLatin1(t *testing.T) {
+	content := fixture(t, "nfse_abrasf_latin1.xml")
+	if !strings.Contains(content, "Servi\xe7o") {
+		t.Fatal("fixture deveria estar em ISO-8859-1")
+	}
+	result := processXML(t, content)
+	doc := result.Document
+
+	// ABRASF não tem chave de acesso
+	if doc.AccessKey != "" || doc.AccessKeyValid != nil {
+		t.Fatalf("NFS-e ABRASF não deveria ter chave: %+v", doc)
+	}
+	if doc.Type != "NFS-e" || doc.Namespace != nsNFSeABRASF || doc.Number != "789" || doc.VerificationCode != "AB12-CD34" {
+		t.Fatalf("identificação inesperada: %+v", doc)
+	}
+	if doc.Issuer.Name != "Manutenção Predial Ltda" || doc.Issuer.Document != "11222333000181" || doc.Issuer.Address.Street != "Rua São João" {
+		t.Fatalf("prestador inesperado: %+v", doc.Issuer)
+	}
+	if doc.Recipient == nil || doc.Recipient.Name != "José Conceição" || doc.Recipient.DocumentType != "CPF" {
+		t.Fatalf("tomador inesperado: %+v", doc.Recipient)
+	}
+	// Valores com vírgula decimal
+	if len(doc.Items) != 1 || doc.Items[0].Description != "Serviço de manutenção elétrica" || doc.Items[0].Total != 2000 {
+		t.Fatalf("serviço inesperado: %+v", doc.Items)
+	}
+	if doc.Totals.Total != 1960 || len(doc.Taxes) != 2 || doc.Taxes[0].Amount != 40 || doc.Taxes[1].Type != "PIS" {
+		t.Fatalf("totais inesperados: %+v %+v", doc.Taxes, doc.Totals)
+	}
+	if !strings.Contains(result.Text, "Manutenção Predial Ltda - CNPJ 11222333000181") {
+		t.Fatalf("texto inesperado: %q", result.Text)
+	}
+}
+
+func TestNonFiscalXML(t *testing.T) {
+	result, err := NewXMLProcessor().ProcessStructured(context.Background(), strings.NewReader("<pedido><cliente>Ana</cliente><total>10</total></pedido>"), "pedido.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Document != nil || !strings.Contains(result.Text, "Ana") {
+		t.Fatalf("XML comum deveria virar só texto: %+v", result)
+	}
+}
+
+func TestFiscalNamespaceWithoutDocumentIsGenericText(t *testing.T) {
+	cases := []struct {
+		fixture string
+		text    string
+	}{
+		{"nfe_evento_cancelamento.xml", "Pedido cancelado pelo cliente antes da entrega"},
+		{"nfe_evento_cce.xml", "Rua das Flores, 100, fundos"},
+		// Lote de RPS em Windows-1252, com aspas e travessão da faixa 0x80–0x9F
+		{"nfse_abrasf_lote_rps.xml", "Serviço “manutenção preventiva” – abril"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			result, err := NewXMLProcessor().ProcessStructured(context.Background(), strings.NewReader(fixture(t, tc.fixture)), tc.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Document != nil || !strings.Contains(result.Text, tc.text) {
+				t.Fatalf("esperava só o texto com %q: %+v", tc.text, result)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	for value, want := range map[string]float64{
+		"1234.56":   1234.56,
+		"2000,00":   2000,
+		"1.234,56":  1234.56,
+		" 10 ":      10,
+		"":          0,
+		"R$ 1,00":   0,
+		"1.234.567": 0,
+		"12.345,6":  12345.6,
+	} {
+		if got := parseAmount(value); got != want {
+			t.Errorf("parseAmount(%q) = %v, esperado %v", value, got, want)
+		}
+	}
+}
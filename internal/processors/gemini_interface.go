@@ -1,12 +1,14 @@
 package processors
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 // GeminiExtractor interface para extrair texto de arquivos usando Gemini
 // Isso evita ciclo de importação
 type GeminiExtractor interface {
-	ExtractTextFromPDF(fileReader io.Reader, filename string) (string, error)
-	ExtractTextFromFile(fileReader io.Reader, filename string) (string, error)
+	ExtractTextFromPDF(ctx context.Context, fileReader io.Reader, filename string) (string, error)
+	ExtractTextFromFile(ctx context.Context, fileReader io.Reader, filename string) (*Result, error)
 	IsAvailable() bool
 }
-
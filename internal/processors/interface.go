@@ -1,8 +1,88 @@
 package processors
 
-import "io"
+import (
+	"context"
+	"errors"
+	"io"
+
+	"backend-fileprocessing/internal/models"
+)
 
 // FileProcessor interface para processadores de arquivo
 type FileProcessor interface {
-	Process(file io.Reader, filename string) (string, error)
+	Process(ctx context.Context, file io.Reader, filename string) (string, error)
+}
+
+// Result resultado completo de um processamento (texto + dados estruturados)
+type Result struct {
+	Text     string
+	Pages    []models.Page
+	Document *models.FiscalDocument
+	Failures []models.ChunkFailure
+	// Usage tokens consumidos no Gemini, por modelo (vazio em extrações nativas)
+	Usage []models.TokenUsage
+}
+
+// CodedError erro com código próprio para a resposta da API (ex.: bloqueios do Gemini)
+type CodedError interface {
+	error
+	ErrorCode() string
+}
+
+// UsageError falha que consumiu tokens no Gemini (resposta bloqueada, truncada ou vazia, blocos de
+// PDF perdidos): o consumo também é cobrado do cliente
+type UsageError struct {
+	Err   error
+	Usage []models.TokenUsage
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// WithUsage anexa o consumo à falha; sem consumo devolve err como está
+func WithUsage(err error, usage []models.TokenUsage) error {
+	if err == nil || len(usage) == 0 {
+		return err
+	}
+	return &UsageError{Err: err, Usage: usage}
+}
+
+// FailureUsage consumo de tokens carregado pela falha (vazio quando não houve)
+func FailureUsage(err error) []models.TokenUsage {
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return usageErr.Usage
+	}
+	return nil
+}
+
+// MergeUsage soma o consumo de tokens agrupando por modelo
+func MergeUsage(total []models.TokenUsage, more []models.TokenUsage) []models.TokenUsage {
+	for _, usage := range more {
+		merged := false
+		for i := range total {
+			if total[i].Model == usage.Model {
+				total[i].InputTokens += usage.InputTokens
+				total[i].OutputTokens += usage.OutputTokens
+				total[i].TotalTokens += usage.TotalTokens
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			total = append(total, usage)
+		}
+	}
+	return total
+}
+
+// StructuredProcessor processador que, além do texto, devolve dados estruturados
+type StructuredProcessor interface {
+	FileProcessor
+	ProcessStructured(ctx context.Context, file io.Reader, filename string) (*Result, error)
 }
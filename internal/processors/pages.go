@@ -0,0 +1,51 @@
+package processors
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// pageSeparator separador usado entre páginas no texto completo
+const pageSeparator = "\n\n"
+
+// JoinPages monta o texto completo a partir do texto de cada página (na ordem) e calcula os offsets
+func JoinPages(pageTexts []string) (string, []models.Page) {
+	pages := make([]models.Page, len(pageTexts))
+	for i, text := range pageTexts {
+		pages[i] = models.Page{Number: i + 1, Text: text}
+	}
+	return joinPageList(pages)
+}
+
+// joinPageList monta o texto completo preservando a numeração já atribuída a cada página
+func joinPageList(pages []models.Page) (string, []models.Page) {
+	var b strings.Builder
+	joined := make([]models.Page, 0, len(pages))
+	offset := 0
+
+	for i, pg := range pages {
+		if i > 0 {
+			b.WriteString(pageSeparator)
+			offset += utf8.RuneCountInString(pageSeparator)
+		}
+		length := utf8.RuneCountInString(pg.Text)
+		joined = append(joined, models.Page{
+			Number:      pg.Number,
+			Text:        pg.Text,
+			StartOffset: offset,
+			EndOffset:   offset + length,
+		})
+		b.WriteString(pg.Text)
+		offset += length
+	}
+
+	return b.String(), joined
+}
+
+// singlePage resultado de documentos sem paginação (texto puro, XML)
+func singlePage(text string) *Result {
+	joined, pages := JoinPages([]string{text})
+	return &Result{Text: joined, Pages: pages}
+}
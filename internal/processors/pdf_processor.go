@@ -1,68 +1,195 @@
 package processors
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pdf"
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // PDFProcessor processador de arquivos PDF usando APENAS Google Gemini
 type PDFProcessor struct {
 	geminiExtractor GeminiExtractor
+	pagesPerChunk   int
+	maxParallel     int
 }
 
-// NewPDFProcessor cria novo processador de PDF
-func NewPDFProcessor(geminiExtractor GeminiExtractor) *PDFProcessor {
+// NewPDFProcessor cria novo processador de PDF. PDFs com mais de pagesPerChunk páginas são
+// divididos em blocos enviados ao Gemini em paralelo (no máximo maxParallel ao mesmo tempo);
+// pagesPerChunk <= 0 desativa a divisão.
+func NewPDFProcessor(geminiExtractor GeminiExtractor, pagesPerChunk, maxParallel int) *PDFProcessor {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
 	return &PDFProcessor{
 		geminiExtractor: geminiExtractor,
+		pagesPerChunk:   pagesPerChunk,
+		maxParallel:     maxParallel,
 	}
 }
 
 // Process processa arquivo PDF usando APENAS Google Gemini
-func (p *PDFProcessor) Process(file io.Reader, filename string) (string, error) {
-	log.Printf("📄 Processando PDF: %s", filename)
+func (p *PDFProcessor) Process(ctx context.Context, file io.Reader, filename string) (string, error) {
+	result, err := p.ProcessStructured(ctx, file, filename)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
 
+// ProcessStructured processa arquivo PDF usando APENAS Google Gemini, mantendo o texto separado por página
+func (p *PDFProcessor) ProcessStructured(ctx context.Context, file io.Reader, filename string) (*Result, error) {
 	// Verificar se Gemini está disponível
 	if p.geminiExtractor == nil || !p.geminiExtractor.IsAvailable() {
-		return "", fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada. Configure a variável de ambiente GEMINI_API_KEY")
+		return nil, fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada. Configure a variável de ambiente GEMINI_API_KEY")
 	}
 
 	// Criar arquivo temporário para poder reler
+	_, tempSpan := tracing.Start(ctx, "tempfile.write")
 	tempFile, err := os.CreateTemp("", "temp_*.pdf")
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar arquivo temporário: %v", err)
+		tracing.End(tempSpan, err)
+		return nil, fmt.Errorf("erro ao criar arquivo temporário: %v", err)
 	}
 	defer os.Remove(tempFile.Name())
 	defer tempFile.Close()
 
 	// Copiar conteúdo do arquivo
-	_, err = io.Copy(tempFile, file)
+	written, err := io.Copy(tempFile, file)
+	tempSpan.SetAttributes(attribute.Int64("file.size", written))
+	tracing.End(tempSpan, err)
 	if err != nil {
-		return "", fmt.Errorf("erro ao copiar arquivo: %v", err)
+		return nil, fmt.Errorf("erro ao copiar arquivo: %v", err)
+	}
+
+	// PDFs grandes: dividir em blocos de páginas e processar em paralelo
+	if p.pagesPerChunk > 0 {
+		_, readSpan := tracing.Start(ctx, "tempfile.read")
+		data, err := os.ReadFile(tempFile.Name())
+		tracing.End(readSpan, err)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao reler arquivo: %v", err)
+		}
+		doc, err := pdf.Open(data)
+		if err != nil {
+			slog.WarnContext(ctx, "não foi possível dividir o PDF, enviando o arquivo inteiro", "error", err)
+		} else if doc.PageCount() > p.pagesPerChunk {
+			return p.processChunks(ctx, doc, filename)
+		}
 	}
 
 	// Processar com Gemini (APENAS!)
-	log.Printf("🤖 Processando PDF com Google Gemini (gratuito)...")
+	slog.DebugContext(ctx, "processando PDF com o Gemini")
 
 	// Ler arquivo novamente para passar para Gemini
 	fileReader, err := os.Open(tempFile.Name())
 	if err != nil {
-		return "", fmt.Errorf("erro ao reabrir arquivo para Gemini: %v", err)
+		return nil, fmt.Errorf("erro ao reabrir arquivo para Gemini: %v", err)
 	}
 	defer fileReader.Close()
 
-	geminiText, err := p.geminiExtractor.ExtractTextFromFile(fileReader, filename)
+	result, err := p.geminiExtractor.ExtractTextFromFile(ctx, fileReader, filename)
 	if err != nil {
-		return "", fmt.Errorf("erro ao processar PDF com Gemini: %v", err)
+		return nil, fmt.Errorf("erro ao processar PDF com Gemini: %w", err)
 	}
 
-	if len(strings.TrimSpace(geminiText)) < 10 {
-		return "", fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)")
+	if len(strings.TrimSpace(result.Text)) < 10 {
+		return nil, WithUsage(fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)"), result.Usage)
 	}
 
-	log.Printf("✅ Gemini extraiu texto com sucesso: %d caracteres", len(geminiText))
-	return strings.TrimSpace(geminiText), nil
+	slog.InfoContext(ctx, "texto extraído do PDF", "chars", len(result.Text), "pages", len(result.Pages))
+	return result, nil
 }
 
+// processChunks envia os blocos de páginas ao Gemini com paralelismo limitado e remonta o texto
+// na ordem das páginas. Blocos com falha são reportados em Result.Failures sem derrubar o documento.
+func (p *PDFProcessor) processChunks(ctx context.Context, doc *pdf.Document, filename string) (*Result, error) {
+	chunks, err := doc.Split(p.pagesPerChunk)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao dividir PDF: %v", err)
+	}
+	slog.InfoContext(ctx, "PDF dividido em blocos", "pages", doc.PageCount(), "chunks", len(chunks), "pages_per_chunk", p.pagesPerChunk, "parallel", p.maxParallel)
+
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	results := make([]*Result, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.maxParallel)
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			chunk := chunks[i]
+			chunkName := fmt.Sprintf("%s_p%d-%d.pdf", base, chunk.FirstPage, chunk.LastPage)
+			chunkCtx, span := tracing.Start(ctx, "PDFProcessor.chunk", trace.WithAttributes(
+				attribute.Int("pdf.first_page", chunk.FirstPage),
+				attribute.Int("pdf.last_page", chunk.LastPage),
+			))
+			slog.DebugContext(chunkCtx, "enviando bloco ao Gemini", "first_page", chunk.FirstPage, "last_page", chunk.LastPage)
+			results[i], errs[i] = p.geminiExtractor.ExtractTextFromFile(chunkCtx, bytes.NewReader(chunk.Data), chunkName)
+			tracing.End(span, errs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var pages []models.Page
+	var failures []models.ChunkFailure
+	var usage []models.TokenUsage
+	for i, chunk := range chunks {
+		if errs[i] != nil {
+			slog.ErrorContext(ctx, "falha no bloco de páginas", "first_page", chunk.FirstPage, "last_page", chunk.LastPage, "error", errs[i])
+			failure := models.ChunkFailure{
+				FirstPage: chunk.FirstPage,
+				LastPage:  chunk.LastPage,
+				Error:     errs[i].Error(),
+			}
+			var coded CodedError
+			if errors.As(errs[i], &coded) {
+				failure.Code = coded.ErrorCode()
+			}
+			failures = append(failures, failure)
+			usage = MergeUsage(usage, FailureUsage(errs[i]))
+			continue
+		}
+		usage = MergeUsage(usage, results[i].Usage)
+
+		chunkPages := results[i].Pages
+		if len(chunkPages) == chunk.LastPage-chunk.FirstPage+1 {
+			for j, pg := range chunkPages {
+				pages = append(pages, models.Page{Number: chunk.FirstPage + j, Text: pg.Text})
+			}
+		} else {
+			// Gemini não respeitou os delimitadores: o bloco inteiro fica na primeira página do intervalo
+			pages = append(pages, models.Page{Number: chunk.FirstPage, Text: results[i].Text})
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, WithUsage(fmt.Errorf("erro ao processar PDF com Gemini: todos os %d blocos falharam (primeiro erro: %w)", len(chunks), errs[0]), usage)
+	}
+
+	text, pages := joinPageList(pages)
+	if len(strings.TrimSpace(text)) < 10 {
+		return nil, WithUsage(fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)"), usage)
+	}
+
+	slog.InfoContext(ctx, "texto extraído do PDF", "chars", len(text), "pages", len(pages), "failed_chunks", len(failures))
+	return &Result{Text: text, Pages: pages, Failures: failures, Usage: usage}, nil
+}
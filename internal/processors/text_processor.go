@@ -1,8 +1,10 @@
 package processors
 
 import (
+	"context"
 	"io"
-	"log"
+	"log/slog"
+	"strings"
 )
 
 // TextProcessor processador de arquivos de texto
@@ -14,15 +16,29 @@ func NewTextProcessor() *TextProcessor {
 }
 
 // Process processa arquivo de texto
-func (p *TextProcessor) Process(file io.Reader, filename string) (string, error) {
-	log.Printf("📝 Processando texto: %s", filename)
+func (p *TextProcessor) Process(ctx context.Context, file io.Reader, filename string) (string, error) {
+	result, err := p.ProcessStructured(ctx, file, filename)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
 
+// ProcessStructured processa arquivo de texto; quebras de página (form feed) separam as páginas
+func (p *TextProcessor) ProcessStructured(ctx context.Context, file io.Reader, filename string) (*Result, error) {
 	content, err := io.ReadAll(file)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	text := string(content)
-	log.Printf("✅ Texto processado com sucesso: %d caracteres", len(text))
-	return text, nil
+	result := singlePage(text)
+	if strings.Contains(text, "\f") {
+		pageTexts := strings.Split(strings.TrimSuffix(text, "\f"), "\f")
+		joined, pages := JoinPages(pageTexts)
+		result = &Result{Text: joined, Pages: pages}
+	}
+
+	slog.InfoContext(ctx, "texto processado", "chars", len(result.Text), "pages", len(result.Pages))
+	return result, nil
 }
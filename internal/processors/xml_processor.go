@@ -0,0 +1,58 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// XMLProcessor processador de XML com leitura nativa de NF-e, CT-e e NFS-e (sem Gemini)
+type XMLProcessor struct{}
+
+// NewXMLProcessor cria novo processador de XML
+func NewXMLProcessor() *XMLProcessor {
+	return &XMLProcessor{}
+}
+
+// Process processa arquivo XML e retorna apenas o texto
+func (p *XMLProcessor) Process(ctx context.Context, file io.Reader, filename string) (string, error) {
+	result, err := p.ProcessStructured(ctx, file, filename)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// ProcessStructured processa arquivo XML; documentos fiscais retornam também os dados estruturados
+func (p *XMLProcessor) ProcessStructured(ctx context.Context, file io.Reader, filename string) (*Result, error) {
+	root, err := parseXMLTree(file)
+	if err != nil {
+		return nil, fmt.Errorf("XML inválido: %v", err)
+	}
+
+	doc, err := parseFiscalDocument(root)
+	if errors.Is(err, errNotFiscal) {
+		// XML comum: devolver apenas o conteúdo textual
+		text := root.allText()
+		if text == "" {
+			return nil, fmt.Errorf("XML não contém texto")
+		}
+		slog.InfoContext(ctx, "XML genérico processado", "chars", len(text))
+		return singlePage(text), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.AccessKeyValid != nil && !*doc.AccessKeyValid {
+		slog.WarnContext(ctx, "chave de acesso não confere com o XML", "document_type", doc.Type, "number", doc.Number, "issues", doc.Issues)
+	}
+
+	text := formatFiscalDocument(doc)
+	slog.InfoContext(ctx, "documento fiscal processado", "document_type", doc.Type, "number", doc.Number, "items", len(doc.Items), "total", doc.Totals.Total)
+	result := singlePage(text)
+	result.Document = doc
+	return result, nil
+}
@@ -0,0 +1,235 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// xmlNode nó de uma árvore XML simplificada (namespaces resolvidos, busca por nome local)
+type xmlNode struct {
+	Space    string
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*xmlNode
+}
+
+// parseXMLTree lê o XML completo para uma árvore de nós
+func parseXMLTree(r io.Reader) (*xmlNode, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader
+
+	var root *xmlNode
+	var stack []*xmlNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{
+				Space: t.Name.Space,
+				Name:  t.Name.Local,
+				Attrs: make(map[string]string, len(t.Attr)),
+			}
+			for _, attr := range t.Attr {
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("XML com mais de um elemento raiz")
+				}
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("XML vazio")
+	}
+	return root, nil
+}
+
+// charsetReader suporta XMLs em ISO-8859-1/Windows-1252, comuns em NFS-e municipais
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso8859-1", "latin1", "windows-1252", "cp1252":
+		return &windows1252Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("charset não suportado: %s", charset)
+}
+
+// windows1252 caracteres da faixa 0x80–0x9F no Windows-1252, onde o ISO-8859-1 tem só controles;
+// posições sem caractere ficam com o controle correspondente
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// windows1252Reader converte bytes Windows-1252 para UTF-8; também serve para ISO-8859-1, que
+// difere só na faixa 0x80–0x9F, na prática usada com o mapeamento do Windows
+type windows1252Reader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (l *windows1252Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			copied := copy(p[n:], l.pending)
+			l.pending = l.pending[copied:]
+			n += copied
+			continue
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		r := rune(b)
+		if b >= 0x80 && b <= 0x9f {
+			r = windows1252[b-0x80]
+		}
+		buf := make([]byte, utf8.UTFMax)
+		size := utf8.EncodeRune(buf, r)
+		l.pending = buf[:size]
+	}
+	return n, nil
+}
+
+// child retorna o primeiro filho direto com o nome local informado
+func (n *xmlNode) child(name string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// all retorna todos os filhos diretos com o nome local informado
+func (n *xmlNode) all(name string) []*xmlNode {
+	if n == nil {
+		return nil
+	}
+	var nodes []*xmlNode
+	for _, c := range n.Children {
+		if c.Name == name {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// path percorre filhos diretos em sequência (nil se algum não existir)
+func (n *xmlNode) path(names ...string) *xmlNode {
+	current := n
+	for _, name := range names {
+		current = current.child(name)
+	}
+	return current
+}
+
+// text retorna o texto do nó no caminho informado (vazio se não existir)
+func (n *xmlNode) text(names ...string) string {
+	node := n.path(names...)
+	if node == nil {
+		return ""
+	}
+	return strings.TrimSpace(node.Text)
+}
+
+// attr retorna o valor de um atributo
+func (n *xmlNode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	return n.Attrs[name]
+}
+
+// first retorna o primeiro filho direto, qualquer que seja o nome (ex.: ICMS00, ICMS20...)
+func (n *xmlNode) first() *xmlNode {
+	if n == nil || len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// find busca em profundidade o primeiro descendente com o nome local informado
+func (n *xmlNode) find(name string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findSpace busca o primeiro nó (incluindo o próprio) cujo namespace satisfaça o filtro
+func (n *xmlNode) findSpace(match func(space string) bool) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	if match(n.Space) {
+		return n
+	}
+	for _, c := range n.Children {
+		if found := c.findSpace(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// allText concatena todo o texto da árvore, um trecho por linha
+func (n *xmlNode) allText() string {
+	var lines []string
+	var walk func(node *xmlNode)
+	walk = func(node *xmlNode) {
+		if text := strings.TrimSpace(node.Text); text != "" {
+			lines = append(lines, text)
+		}
+		for _, c := range node.Children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(lines, "\n")
+}
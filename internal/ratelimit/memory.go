@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval intervalo entre limpezas de baldes ociosos e volumes de dias anteriores
+const sweepInterval = 10 * time.Minute
+
+// bucket balde de tokens de uma chave
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  int
+}
+
+// dailyBytes volume enviado por uma chave em um dia (UTC)
+type dailyBytes struct {
+	day   string
+	bytes int64
+}
+
+// MemoryStore contadores em memória (uma única instância do serviço)
+type MemoryStore struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*bucket
+	running   map[string]int
+	volume    map[string]*dailyBytes
+	lastSweep time.Time
+}
+
+// NewMemoryStore cria o backend em memória
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+		running: make(map[string]int),
+		volume:  make(map[string]*dailyBytes),
+	}
+}
+
+// Take consome um token do balde da chave
+func (m *MemoryStore) Take(key string, rate float64, burst int) (bool, int, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last, b.rate, b.burst = now, rate, burst
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Acquire ocupa uma vaga de processamento simultâneo
+func (m *MemoryStore) Acquire(key string, limit int) (func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running[key] >= limit {
+		return nil, false
+	}
+	m.running[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.running[key]--; m.running[key] <= 0 {
+				delete(m.running, key)
+			}
+		})
+	}, true
+}
+
+// AddBytes soma bytes ao volume do dia se o total couber no limite
+func (m *MemoryStore) AddBytes(key string, n, limit int64) (int64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	volume := m.today(key)
+	if volume.bytes+n > limit {
+		return volume.bytes, false
+	}
+	// Devolução depois da virada do dia: o volume já foi zerado
+	volume.bytes = max(volume.bytes+n, 0)
+	return volume.bytes, true
+}
+
+// Bytes volume do dia da chave
+func (m *MemoryStore) Bytes(key string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.today(key).bytes
+}
+
+// today contador do dia corrente, zerado na virada do dia em UTC
+func (m *MemoryStore) today(key string) *dailyBytes {
+	day := m.now().UTC().Format("2006-01-02")
+	volume, ok := m.volume[key]
+	if !ok || volume.day != day {
+		volume = &dailyBytes{day: day}
+		m.volume[key] = volume
+	}
+	return volume
+}
+
+// sweep remove baldes que já voltaram a ficar cheios e volumes de dias anteriores (chamado com o lock)
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= float64(b.burst) {
+			delete(m.buckets, key)
+		}
+	}
+	day := now.UTC().Format("2006-01-02")
+	for key, volume := range m.volume {
+		if volume.day != day {
+			delete(m.volume, key)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestStore(now *time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := New(Config{RequestsPerMinute: 60, Burst: 3}, newTestStore(&now))
+
+	for i := 0; i < 3; i++ {
+		if allowed, remaining, _ := limiter.Allow("a"); !allowed || remaining != 2-i {
+			t.Fatalf("requisição %d: allowed=%v remaining=%d", i+1, allowed, remaining)
+		}
+	}
+	allowed, _, retryAfter := limiter.Allow("a")
+	if allowed || retryAfter != time.Second {
+		t.Fatalf("balde vazio deveria recusar por 1s: allowed=%v retryAfter=%v", allowed, retryAfter)
+	}
+	if allowed, _, _ := limiter.Allow("b"); !allowed {
+		t.Fatal("cada chave tem o próprio balde")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if allowed, _, _ := limiter.Allow("a"); !allowed {
+		t.Fatal("o balde deveria ter reposto um token")
+	}
+	if allowed, _, retryAfter := limiter.Allow("a"); allowed || retryAfter != 500*time.Millisecond {
+		t.Fatalf("esperava espera de 500ms, veio %v", retryAfter)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter := New(Config{MaxConcurrent: 2}, nil)
+
+	first, ok1 := limiter.Acquire("a")
+	_, ok2 := limiter.Acquire("a")
+	if !ok1 || !ok2 {
+		t.Fatal("duas vagas deveriam estar livres")
+	}
+	if _, ok := limiter.Acquire("a"); ok {
+		t.Fatal("terceiro processamento simultâneo deveria ser recusado")
+	}
+
+	first()
+	first() // liberar duas vezes não abre vaga extra
+	if _, ok := limiter.Acquire("a"); !ok {
+		t.Fatal("a vaga liberada deveria ser reutilizada")
+	}
+	if _, ok := limiter.Acquire("a"); ok {
+		t.Fatal("release repetido não pode liberar outra vaga")
+	}
+}
+
+func TestDailyBytes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+	limiter := New(Config{DailyBytes: 100}, newTestStore(&now))
+
+	if _, ok := limiter.ReserveBytes("a", 60); !ok {
+		t.Fatal("60 de 100 bytes deveria caber")
+	}
+	if used, ok := limiter.ReserveBytes("a", 50); ok || used != 60 {
+		t.Fatalf("110 de 100 bytes deveria ser recusado: used=%d ok=%v", used, ok)
+	}
+	limiter.RecordBytes("a", 50)
+	if used := limiter.UsedBytes("a"); used != 110 {
+		t.Fatalf("bytes sem Content-Length devem ser contabilizados: %d", used)
+	}
+	limiter.RefundBytes("a", 60)
+	if used := limiter.UsedBytes("a"); used != 50 {
+		t.Fatalf("a reserva devolvida deveria sair do volume: %d", used)
+	}
+
+	now = now.Add(2 * time.Hour)
+	limiter.RefundBytes("a", 50)
+	if used := limiter.UsedBytes("a"); used != 0 {
+		t.Fatalf("devolução de ontem não pode deixar o volume negativo: %d", used)
+	}
+	if _, ok := limiter.ReserveBytes("a", 100); !ok {
+		t.Fatal("a cota deveria ser renovada no dia seguinte")
+	}
+	if wait := UntilNextDay(now); wait != 23*time.Hour {
+		t.Fatalf("UntilNextDay = %v", wait)
+	}
+}
+
+func TestDisabledLimits(t *testing.T) {
+	limiter := New(Config{}, nil)
+	for i := 0; i < 1000; i++ {
+		if allowed, _, _ := limiter.Allow("a"); !allowed {
+			t.Fatal("sem RequestsPerMinute não há limite de taxa")
+		}
+	}
+	if _, ok := limiter.ReserveBytes("a", 1<<40); !ok {
+		t.Fatal("sem DailyBytes não há limite de volume")
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Store backend dos contadores. MemoryStore atende uma instância; para várias réplicas
+// implemente a interface sobre um armazenamento compartilhado (ex.: Redis).
+type Store interface {
+	// Take consome um token do balde da chave (taxa em tokens por segundo, capacidade burst).
+	// Sem token disponível, devolve em quanto tempo haverá um.
+	Take(key string, rate float64, burst int) (allowed bool, remaining int, retryAfter time.Duration)
+	// Acquire ocupa uma das limit vagas de processamento simultâneo da chave; release libera a vaga
+	Acquire(key string, limit int) (release func(), ok bool)
+	// AddBytes soma n bytes ao volume do dia (UTC) da chave se o total não passar de limit;
+	// n negativo devolve bytes, sem deixar o volume abaixo de zero
+	AddBytes(key string, n, limit int64) (used int64, ok bool)
+	// Bytes volume do dia (UTC) já contabilizado para a chave
+	Bytes(key string) int64
+}
+
+// Config limites por cliente; zero desativa o limite correspondente
+type Config struct {
+	// RequestsPerMinute taxa sustentada de requisições e Burst o pico aceito de uma vez
+	RequestsPerMinute int
+	Burst             int
+	// MaxConcurrent processamentos simultâneos
+	MaxConcurrent int
+	// DailyBytes volume diário enviado, em bytes
+	DailyBytes int64
+}
+
+// Limiter aplica os limites de Config sobre um Store
+type Limiter struct {
+	config Config
+	store  Store
+}
+
+// New cria o limitador; store nil usa o MemoryStore
+func New(config Config, store Store) *Limiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if config.Burst <= 0 {
+		config.Burst = config.RequestsPerMinute
+	}
+	return &Limiter{config: config, store: store}
+}
+
+// Config limites configurados
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Allow consome uma requisição da cota da chave
+func (l *Limiter) Allow(key string) (allowed bool, remaining int, retryAfter time.Duration) {
+	if l.config.RequestsPerMinute <= 0 {
+		return true, -1, 0
+	}
+	return l.store.Take(key, float64(l.config.RequestsPerMinute)/60, l.config.Burst)
+}
+
+// Acquire ocupa uma vaga de processamento simultâneo da chave
+func (l *Limiter) Acquire(key string) (release func(), ok bool) {
+	if l.config.MaxConcurrent <= 0 {
+		return func() {}, true
+	}
+	return l.store.Acquire(key, l.config.MaxConcurrent)
+}
+
+// ReserveBytes contabiliza n bytes no volume diário da chave, recusando se passar do limite
+func (l *Limiter) ReserveBytes(key string, n int64) (used int64, ok bool) {
+	if l.config.DailyBytes <= 0 {
+		return 0, true
+	}
+	return l.store.AddBytes(key, n, l.config.DailyBytes)
+}
+
+// RecordBytes contabiliza bytes já recebidos sem recusar (uploads sem Content-Length)
+func (l *Limiter) RecordBytes(key string, n int64) {
+	if l.config.DailyBytes > 0 {
+		l.store.AddBytes(key, n, math.MaxInt64)
+	}
+}
+
+// RefundBytes devolve bytes reservados para um upload recusado antes do processamento
+func (l *Limiter) RefundBytes(key string, n int64) {
+	if l.config.DailyBytes > 0 && n > 0 {
+		l.store.AddBytes(key, -n, math.MaxInt64)
+	}
+}
+
+// UsedBytes volume diário já contabilizado para a chave
+func (l *Limiter) UsedBytes(key string) int64 {
+	if l.config.DailyBytes <= 0 {
+		return 0
+	}
+	return l.store.Bytes(key)
+}
+
+// UntilNextDay tempo até a virada do dia em UTC (quando o volume diário é zerado)
+func UntilNextDay(now time.Time) time.Duration {
+	now = now.UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return tomorrow.Sub(now)
+}
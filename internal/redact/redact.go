@@ -0,0 +1,81 @@
+// Package redact remove segredos (chaves de API, tokens) de textos antes de irem para os logs.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// minSecretLength segredos muito curtos não são registrados (trocariam trechos comuns do texto)
+const minSecretLength = 8
+
+// mask texto colocado no lugar do segredo
+const mask = "[REDACTED]"
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// patterns segredos reconhecidos pelo formato, mesmo sem registro prévio
+var patterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// Chaves de API do Google
+	{regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`), mask},
+	// Parâmetros de query com credenciais (?key=..., &access_token=...)
+	{regexp.MustCompile(`([?&](?:key|api_key|apikey|access_token|token)=)[^&\s"']+`), "${1}" + mask},
+	// Headers de autenticação copiados para mensagens de erro
+	{regexp.MustCompile(`(?i)((?:x-goog-api-key|x-api-key|authorization)["']?\s*[:=]\s*["']?(?:bearer\s+)?)[^\s"',}]+`), "${1}" + mask},
+}
+
+// Register registra um segredo conhecido para ser removido de todo texto que passar por String
+func Register(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range secrets {
+		if existing == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// String devolve o texto com segredos registrados e padrões conhecidos mascarados
+func String(text string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, mask)
+	}
+	mu.RUnlock()
+
+	for _, p := range patterns {
+		text = p.re.ReplaceAllString(text, p.repl)
+	}
+	return text
+}
+
+// writer aplica String em cada escrita
+type writer struct {
+	out io.Writer
+}
+
+// NewWriter envolve out (ex.: saída do pacote log) mascarando segredos em cada linha escrita
+func NewWriter(out io.Writer) io.Writer {
+	return &writer{out: out}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,187 @@
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Poppler rasterização (pdftoppm) e posições das palavras (pdftotext -bbox) com as ferramentas do Poppler
+type Poppler struct {
+	pdftoppm  string
+	pdftotext string
+	timeout   time.Duration
+}
+
+// NewPoppler usa os executáveis informados (nome no PATH ou caminho completo)
+func NewPoppler(pdftoppm, pdftotext string, timeout time.Duration) *Poppler {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return &Poppler{pdftoppm: pdftoppm, pdftotext: pdftotext, timeout: timeout}
+}
+
+// Rasterize converte cada página em PNG com pdftoppm
+func (p *Poppler) Rasterize(ctx context.Context, data []byte, dpi int) ([]image.Image, error) {
+	dir, input, err := writeTemp(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := p.run(ctx, p.pdftoppm, "-r", strconv.Itoa(dpi), "-png", input, filepath.Join(dir, "page")); err != nil {
+		return nil, err
+	}
+
+	// pdftoppm numera com zeros à esquerda conforme o total de páginas (page-1.png ou page-01.png)
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return pageNumber(files[i]) < pageNumber(files[j]) })
+
+	images := make([]image.Image, 0, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir página rasterizada: %v", err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao decodificar página rasterizada: %v", err)
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("pdftoppm não gerou nenhuma página")
+	}
+	return images, nil
+}
+
+// Words lê as palavras de cada página com pdftotext -bbox (coordenadas em pontos, convertidas
+// para frações da página)
+func (p *Poppler) Words(ctx context.Context, data []byte) ([][]Word, error) {
+	dir, input, err := writeTemp(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	output, err := p.run(ctx, p.pdftotext, "-bbox", input, "-")
+	if err != nil {
+		return nil, err
+	}
+	return parseBBox(output)
+}
+
+// run executa a ferramenta com o tempo máximo configurado
+func (p *Poppler) run(ctx context.Context, command string, args ...string) (output []byte, err error) {
+	_, span := tracing.Start(ctx, filepath.Base(command))
+	defer func() { tracing.End(span, err) }()
+
+	// O processo termina junto com a requisição (cliente desconectou) ou ao estourar o tempo máximo
+	runCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(runCtx, command, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s interrompido: requisição encerrada (%v)", command, ctx.Err())
+		}
+		if runCtx.Err() != nil {
+			return nil, fmt.Errorf("%s excedeu o tempo máximo de %v", command, p.timeout)
+		}
+		return nil, fmt.Errorf("erro ao executar %s: %v %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeTemp grava o PDF em um diretório temporário próprio
+func writeTemp(ctx context.Context, data []byte) (dir, path string, err error) {
+	_, span := tracing.Start(ctx, "tempfile.write", trace.WithAttributes(attribute.Int("file.size", len(data))))
+	defer func() { tracing.End(span, err) }()
+
+	dir, err = os.MkdirTemp("", "redaction-*")
+	if err != nil {
+		return "", "", fmt.Errorf("erro ao criar diretório temporário: %v", err)
+	}
+	path = filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("erro ao gravar arquivo temporário: %v", err)
+	}
+	return dir, path, nil
+}
+
+// pageNumber número da página no nome gerado pelo pdftoppm
+func pageNumber(path string) int {
+	name := strings.TrimSuffix(filepath.Base(path), ".png")
+	n, _ := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	return n
+}
+
+// parseBBox interpreta o XHTML do pdftotext -bbox:
+// <page width=".." height=".."><word xMin=".." yMin=".." xMax=".." yMax="..">texto</word>...</page>
+func parseBBox(data []byte) ([][]Word, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var pages [][]Word
+	var width, height float64
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := make(map[string]float64, len(start.Attr))
+		for _, attr := range start.Attr {
+			attrs[attr.Name.Local], _ = strconv.ParseFloat(attr.Value, 64)
+		}
+
+		switch start.Name.Local {
+		case "page":
+			width, height = attrs["width"], attrs["height"]
+			pages = append(pages, nil)
+		case "word":
+			var text string
+			if err := decoder.DecodeElement(&text, &start); err != nil {
+				return nil, fmt.Errorf("erro ao ler saída do pdftotext: %v", err)
+			}
+			if len(pages) == 0 || width <= 0 || height <= 0 {
+				continue
+			}
+			pages[len(pages)-1] = append(pages[len(pages)-1], Word{
+				Text: strings.TrimSpace(text),
+				Box:  Box{attrs["xMin"] / width, attrs["yMin"] / height, attrs["xMax"] / width, attrs["yMax"] / height},
+			})
+		}
+	}
+	if pages == nil {
+		return nil, fmt.Errorf("saída do pdftotext sem páginas")
+	}
+	return pages, nil
+}
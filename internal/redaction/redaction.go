@@ -0,0 +1,246 @@
+// Package redaction gera cópias tarjadas de PDFs e imagens: localiza as palavras de cada página
+// (posições nativas do PDF ou coordenadas pedidas ao modelo), encontra os dados pessoais entre
+// elas e pinta essas regiões de preto. PDFs são rasterizados, então o resultado não guarda texto.
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"strings"
+
+	// Decodificadores registrados em image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pdf"
+	"backend-fileprocessing/internal/pii"
+)
+
+// Box retângulo em frações da página (0 a 1), origem no canto superior esquerdo
+type Box struct {
+	X0, Y0, X1, Y1 float64
+}
+
+// Word palavra (ou trecho de linha) com a posição na página
+type Word struct {
+	Text string
+	Box  Box
+}
+
+// WordLocator localiza as palavras de uma imagem (OCR com coordenadas, ex.: Gemini)
+type WordLocator interface {
+	LocateWords(ctx context.Context, data []byte, mimeType string) ([]Word, *models.TokenUsage, error)
+}
+
+// PDFRenderer rasteriza páginas de PDF e lê as posições nativas das palavras
+type PDFRenderer interface {
+	// Rasterize converte cada página em imagem na resolução pedida
+	Rasterize(ctx context.Context, data []byte, dpi int) ([]image.Image, error)
+	// Words palavras de cada página; páginas escaneadas (sem camada de texto) vêm vazias
+	Words(ctx context.Context, data []byte) ([][]Word, error)
+}
+
+// Output arquivo tarjado
+type Output struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Pages       int
+	// Regions quantidade de regiões tarjadas
+	Regions int
+	// Usage tokens gastos localizando palavras com o modelo
+	Usage []models.TokenUsage
+}
+
+// imageTypes imagens que podem ser tarjadas (saída sempre PNG)
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+	".tiff": "image/tiff",
+}
+
+// Supported indica se o tipo de arquivo pode ser devolvido tarjado
+func Supported(fileType string) bool {
+	_, isImage := imageTypes[fileType]
+	return isImage || fileType == ".pdf"
+}
+
+// Redactor gera as cópias tarjadas
+type Redactor struct {
+	locator  WordLocator
+	renderer PDFRenderer
+	dpi      int
+}
+
+// New cria o gerador; dpi é a resolução da rasterização dos PDFs
+func New(locator WordLocator, renderer PDFRenderer, dpi int) *Redactor {
+	if dpi <= 0 {
+		dpi = 150
+	}
+	return &Redactor{locator: locator, renderer: renderer, dpi: dpi}
+}
+
+// Redact devolve a cópia tarjada do arquivo: PNG para imagens, PDF rasterizado para PDFs
+func (r *Redactor) Redact(ctx context.Context, data []byte, fileType string) (*Output, error) {
+	if fileType == ".pdf" {
+		return r.redactPDF(ctx, data)
+	}
+	mimeType, ok := imageTypes[fileType]
+	if !ok {
+		return nil, fmt.Errorf("tipo de arquivo sem suporte a tarja: %s", fileType)
+	}
+	return r.redactImage(ctx, data, mimeType)
+}
+
+func (r *Redactor) redactImage(ctx context.Context, data []byte, mimeType string) (*Output, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao decodificar imagem: %v", err)
+	}
+
+	output := &Output{ContentType: "image/png", Extension: ".png", Pages: 1}
+	words, err := r.locate(ctx, data, mimeType, output)
+	if err != nil {
+		return nil, err
+	}
+	boxes := SensitiveBoxes(words)
+	output.Regions = len(boxes)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Fill(img, boxes)); err != nil {
+		return nil, fmt.Errorf("erro ao gerar PNG tarjado: %v", err)
+	}
+	output.Data = buf.Bytes()
+	return output, nil
+}
+
+func (r *Redactor) redactPDF(ctx context.Context, data []byte) (*Output, error) {
+	if r.renderer == nil {
+		return nil, fmt.Errorf("rasterização de PDF não configurada")
+	}
+	images, err := r.renderer.Rasterize(ctx, data, r.dpi)
+	if err != nil {
+		return nil, err
+	}
+
+	// Posições nativas; sem elas (ou em páginas escaneadas) as palavras vêm do modelo
+	nativeWords, err := r.renderer.Words(ctx, data)
+	if err != nil {
+		slog.WarnContext(ctx, "posições nativas do PDF indisponíveis, usando o modelo em todas as páginas", "error", err)
+	}
+
+	output := &Output{ContentType: "application/pdf", Extension: ".pdf", Pages: len(images)}
+	pages := make([]pdf.ImagePage, 0, len(images))
+	for i, img := range images {
+		var words []Word
+		if i < len(nativeWords) {
+			words = nativeWords[i]
+		}
+		if len(words) == 0 {
+			var encoded bytes.Buffer
+			if err := png.Encode(&encoded, img); err != nil {
+				return nil, fmt.Errorf("erro ao codificar página %d: %v", i+1, err)
+			}
+			if words, err = r.locate(ctx, encoded.Bytes(), "image/png", output); err != nil {
+				return nil, fmt.Errorf("página %d: %v", i+1, err)
+			}
+		}
+
+		boxes := SensitiveBoxes(words)
+		output.Regions += len(boxes)
+		bounds := img.Bounds()
+		pages = append(pages, pdf.ImagePage{
+			Image:  Fill(img, boxes),
+			Width:  float64(bounds.Dx()) * 72 / float64(r.dpi),
+			Height: float64(bounds.Dy()) * 72 / float64(r.dpi),
+		})
+	}
+
+	if output.Data, err = pdf.WriteImages(pages, 85); err != nil {
+		return nil, fmt.Errorf("erro ao gerar PDF tarjado: %v", err)
+	}
+	return output, nil
+}
+
+// locate pede as palavras ao modelo, somando o consumo de tokens na saída
+func (r *Redactor) locate(ctx context.Context, data []byte, mimeType string, output *Output) ([]Word, error) {
+	if r.locator == nil {
+		return nil, fmt.Errorf("localização de palavras em imagens não configurada")
+	}
+	words, usage, err := r.locator.LocateWords(ctx, data, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao localizar palavras: %w", err)
+	}
+	if usage != nil {
+		output.Usage = append(output.Usage, *usage)
+	}
+	return words, nil
+}
+
+// SensitiveBoxes regiões das palavras que fazem parte de algum dado pessoal (a detecção roda
+// sobre as palavras unidas por espaço, então valores quebrados em várias palavras também contam)
+func SensitiveBoxes(words []Word) []Box {
+	var text strings.Builder
+	starts := make([]int, len(words))
+	ends := make([]int, len(words))
+	offset := 0
+	for i, word := range words {
+		if i > 0 {
+			text.WriteByte(' ')
+			offset++
+		}
+		starts[i] = offset
+		text.WriteString(word.Text)
+		offset += len([]rune(word.Text))
+		ends[i] = offset
+	}
+
+	spans := pii.Find(text.String())
+	var boxes []Box
+	for i, word := range words {
+		for _, span := range spans {
+			if starts[i] < span.End && span.Start < ends[i] {
+				boxes = append(boxes, word.Box)
+				break
+			}
+		}
+	}
+	return boxes
+}
+
+// Fill copia a imagem pintando as regiões de preto, com uma pequena margem para cobrir
+// imprecisões das coordenadas
+func Fill(img image.Image, boxes []Box) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+
+	black := image.NewUniform(color.Black)
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	for _, box := range boxes {
+		margin := (box.Y1 - box.Y0) * height * 0.15
+		rect := image.Rect(
+			bounds.Min.X+int(box.X0*width-margin),
+			bounds.Min.Y+int(box.Y0*height-margin),
+			bounds.Min.X+int(box.X1*width+margin+1),
+			bounds.Min.Y+int(box.Y1*height+margin+1),
+		).Intersect(bounds)
+		draw.Draw(out, rect, black, image.Point{}, draw.Src)
+	}
+	return out
+}
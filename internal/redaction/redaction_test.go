@@ -0,0 +1,169 @@
+package redaction
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pdf"
+)
+
+// words linha "CPF 529.982.247-25 confirmado" com cada palavra ocupando um terço da largura
+var words = []Word{
+	{Text: "CPF", Box: Box{0.0, 0.4, 0.3, 0.6}},
+	{Text: "529.982.247-25", Box: Box{0.35, 0.4, 0.65, 0.6}},
+	{Text: "confirmado", Box: Box{0.7, 0.4, 1.0, 0.6}},
+}
+
+type fakeLocator struct{ calls int }
+
+func (f *fakeLocator) LocateWords(context.Context, []byte, string) ([]Word, *models.TokenUsage, error) {
+	f.calls++
+	return words, &models.TokenUsage{Model: "gemini-2.0-flash", InputTokens: 10, TotalTokens: 10}, nil
+}
+
+type fakeRenderer struct {
+	pages int
+	words [][]Word
+}
+
+func (f *fakeRenderer) Rasterize(context.Context, []byte, int) ([]image.Image, error) {
+	images := make([]image.Image, f.pages)
+	for i := range images {
+		images[i] = whiteImage(200, 100)
+	}
+	return images, nil
+}
+
+func (f *fakeRenderer) Words(context.Context, []byte) ([][]Word, error) {
+	return f.words, nil
+}
+
+func whiteImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+func isBlack(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r == 0 && g == 0 && b == 0
+}
+
+func TestSensitiveBoxes(t *testing.T) {
+	boxes := SensitiveBoxes(words)
+	if len(boxes) != 1 || boxes[0] != words[1].Box {
+		t.Fatalf("só a palavra do CPF deveria ser tarjada: %+v", boxes)
+	}
+
+	// Valor quebrado em várias palavras
+	split := []Word{{Text: "(11)"}, {Text: "98765-4321"}, {Text: "ligar"}}
+	if boxes := SensitiveBoxes(split); len(boxes) != 2 {
+		t.Fatalf("telefone em duas palavras deveria tarjar as duas: %d", len(boxes))
+	}
+}
+
+func TestRedactImage(t *testing.T) {
+	var input bytes.Buffer
+	png.Encode(&input, whiteImage(200, 100))
+	locator := &fakeLocator{}
+
+	output, err := New(locator, nil, 0).Redact(context.Background(), input.Bytes(), ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.ContentType != "image/png" || output.Regions != 1 || len(output.Usage) != 1 {
+		t.Fatalf("saída inesperada: %+v", output)
+	}
+
+	img, err := png.Decode(bytes.NewReader(output.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isBlack(img.At(100, 50)) {
+		t.Fatal("a região do CPF deveria estar preta")
+	}
+	if isBlack(img.At(20, 50)) || isBlack(img.At(180, 50)) {
+		t.Fatal("o restante da linha não deveria ser tarjado")
+	}
+}
+
+func TestRedactPDF(t *testing.T) {
+	// Página 1 com texto nativo, página 2 escaneada (palavras vêm do modelo)
+	renderer := &fakeRenderer{pages: 2, words: [][]Word{words, nil}}
+	locator := &fakeLocator{}
+
+	output, err := New(locator, renderer, 72).Redact(context.Background(), []byte("%PDF"), ".pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Pages != 2 || output.Regions != 2 || locator.calls != 1 {
+		t.Fatalf("saída inesperada: pages=%d regions=%d chamadas ao modelo=%d", output.Pages, output.Regions, locator.calls)
+	}
+
+	doc, err := pdf.Open(output.Data)
+	if err != nil {
+		t.Fatalf("PDF gerado inválido: %v", err)
+	}
+	if doc.PageCount() != 2 {
+		t.Fatalf("páginas no PDF: %d", doc.PageCount())
+	}
+}
+
+func TestUnsupportedType(t *testing.T) {
+	if Supported(".docx") || !Supported(".pdf") || !Supported(".webp") {
+		t.Fatal("tipos suportados inesperados")
+	}
+	if _, err := New(nil, nil, 0).Redact(context.Background(), []byte("x"), ".txt"); err == nil {
+		t.Fatal("TXT não tem cópia tarjada")
+	}
+}
+
+func TestParseBBox(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><meta name="Producer" content="x"></head><body><doc>
+<page width="200.000000" height="100.000000">
+<word xMin="20.000000" yMin="40.000000" xMax="60.000000" yMax="60.000000">CPF</word>
+<word xMin="70.000000" yMin="40.000000" xMax="130.000000" yMax="60.000000">529.982.247-25</word>
+</page>
+<page width="200.000000" height="100.000000">
+</page>
+</doc></body></html>`
+	pages, err := parseBBox([]byte(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || len(pages[0]) != 2 || len(pages[1]) != 0 {
+		t.Fatalf("páginas inesperadas: %+v", pages)
+	}
+	if got := pages[0][1]; got.Text != "529.982.247-25" || got.Box != (Box{0.35, 0.4, 0.65, 0.6}) {
+		t.Fatalf("palavra inesperada: %+v", got)
+	}
+}
+
+func TestPopplerStopsWithTheRequest(t *testing.T) {
+	// Ferramenta falsa que demoraria bem mais que a requisição
+	tool := filepath.Join(t.TempDir(), "pdftotext")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\nexec sleep 5\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	poppler := NewPoppler(tool, tool, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := poppler.Words(ctx, []byte("%PDF-1.7")); err == nil {
+		t.Fatal("esperava erro com a requisição encerrada")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("o processo continuou após o fim da requisição: %v", elapsed)
+	}
+}
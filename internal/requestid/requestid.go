@@ -0,0 +1,64 @@
+// Package requestid identifica cada requisição (header X-Request-ID) para correlacionar a
+// resposta, as linhas de log e as chamadas feitas ao Gemini.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header header com o identificador da requisição, aceito do chamador e devolvido na resposta
+const Header = "X-Request-ID"
+
+// maxLength tamanho máximo de um id recebido do chamador
+const maxLength = 128
+
+// contextKey chave do id no context.Context
+type contextKey struct{}
+
+// New gera um id aleatório (32 dígitos hexadecimais)
+func New() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// Sem entropia do sistema não há como gerar um id único; a requisição segue sem ele
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Valid indica se o id recebido pode ser aceito: até 128 caracteres entre letras, dígitos e
+// "-", "_", ".", ":" (nada que quebre uma linha de log ou um header)
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext devolve um contexto com o id da requisição
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext id da requisição guardado no contexto ("" quando ausente)
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Inject repassa o id da requisição em uma chamada de saída
+func Inject(ctx context.Context, header http.Header) {
+	if id := FromContext(ctx); id != "" {
+		header.Set(Header, id)
+	}
+}
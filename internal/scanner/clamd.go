@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize tamanho de cada bloco enviado no INSTREAM (deve ficar abaixo do StreamMaxLength do clamd)
+const clamdChunkSize = 64 * 1024
+
+// Clamd cliente do protocolo do clamd (comando INSTREAM) via TCP ou socket Unix
+type Clamd struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamd cria o cliente; timeout limita a conexão e a verificação inteira
+func NewClamd(network, address string, timeout time.Duration) *Clamd {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Clamd{network: network, address: address, timeout: timeout}
+}
+
+// Name nome do scanner
+func (c *Clamd) Name() string {
+	return "clamd"
+}
+
+// Ping verifica se o clamd está respondendo
+func (c *Clamd) Ping() error {
+	reply, err := c.command("zPING\x00", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("resposta inesperada do clamd ao PING: %q", reply)
+	}
+	return nil
+}
+
+// Scan envia o conteúdo ao clamd em blocos e interpreta o veredito
+func (c *Clamd) Scan(r io.Reader) (*Result, error) {
+	reply, err := c.command("zINSTREAM\x00", r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Respostas: "stream: OK", "stream: <assinatura> FOUND" ou "<mensagem> ERROR"
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd recusou a verificação: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("resposta inesperada do clamd: %q", reply)
+	}
+}
+
+// command envia um comando (com o corpo do INSTREAM, se houver) e lê a resposta terminada em \0
+func (c *Clamd) command(cmd string, body io.Reader) (string, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return "", fmt.Errorf("erro ao conectar ao clamd em %s: %v", c.address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	writer := bufio.NewWriterSize(conn, clamdChunkSize+4)
+	if _, err := writer.WriteString(cmd); err != nil {
+		return "", fmt.Errorf("erro ao enviar comando ao clamd: %v", err)
+	}
+	if body != nil {
+		if err := writeChunks(writer, body); err != nil {
+			// O clamd fecha a conexão ao passar do StreamMaxLength; a resposta explica o motivo
+			if reply, readErr := readReply(conn); readErr == nil && reply != "" {
+				return reply, nil
+			}
+			return "", err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return "", fmt.Errorf("erro ao enviar dados ao clamd: %v", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler resposta do clamd: %v", err)
+	}
+	return reply, nil
+}
+
+// writeChunks envia o conteúdo como blocos <tamanho uint32 big-endian><dados>, terminando com um bloco vazio
+func writeChunks(w *bufio.Writer, body io.Reader) error {
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return fmt.Errorf("erro ao enviar dados ao clamd: %v", werr)
+			}
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return fmt.Errorf("erro ao enviar dados ao clamd: %v", werr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao ler arquivo para verificação: %v", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return fmt.Errorf("erro ao enviar dados ao clamd: %v", err)
+	}
+	return nil
+}
+
+// readReply lê a resposta até o \0 (ou o fim da conexão)
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
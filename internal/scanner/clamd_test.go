@@ -0,0 +1,79 @@
+package scanner_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-fileprocessing/internal/scanner"
+	"backend-fileprocessing/internal/scanner/clamdtest"
+)
+
+func newClamd(t *testing.T) (*scanner.Clamd, *clamdtest.Server) {
+	t.Helper()
+	srv := clamdtest.New(t)
+	s, err := scanner.New(srv.Address(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*scanner.Clamd), srv
+}
+
+func TestClamdVerdicts(t *testing.T) {
+	clamd, srv := newClamd(t)
+
+	if err := clamd.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	result, err := clamd.Scan(strings.NewReader("conteúdo limpo"))
+	if err != nil || result.Infected {
+		t.Fatalf("arquivo limpo: result=%+v err=%v", result, err)
+	}
+
+	result, err = clamd.Scan(strings.NewReader("prefixo " + clamdtest.EICAR))
+	if err != nil || !result.Infected || result.Signature != clamdtest.Signature {
+		t.Fatalf("EICAR: result=%+v err=%v", result, err)
+	}
+
+	srv.SetReply("INSTREAM size limit exceeded. ERROR")
+	if _, err := clamd.Scan(strings.NewReader("x")); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Fatalf("erro do clamd deveria ser repassado: %v", err)
+	}
+}
+
+func TestClamdStreamsInChunks(t *testing.T) {
+	clamd, srv := newClamd(t)
+
+	// Maior que um bloco do INSTREAM, com a assinatura no fim
+	data := append(bytes.Repeat([]byte("a"), 150*1024), clamdtest.EICAR...)
+	result, err := clamd.Scan(bytes.NewReader(data))
+	if err != nil || !result.Infected {
+		t.Fatalf("assinatura após vários blocos: result=%+v err=%v", result, err)
+	}
+	if scanned := srv.Scanned(); len(scanned) != 1 || !bytes.Equal(scanned[0], data) {
+		t.Fatal("o clamd deveria receber o conteúdo completo")
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	if s, err := scanner.New("", 0); err != nil || s.Name() != "none" {
+		t.Fatalf("sem endereço o scanner é o no-op: %v %v", s, err)
+	}
+	if s, err := scanner.New("unix:///run/clamav/clamd.ctl", 0); err != nil || s.Name() != "clamd" {
+		t.Fatalf("socket Unix: %v %v", s, err)
+	}
+	for _, address := range []string{"localhost:3310", "http://localhost:3310", "tcp://"} {
+		if _, err := scanner.New(address, 0); err == nil {
+			t.Errorf("endereço %q deveria ser recusado", address)
+		}
+	}
+}
+
+func TestClamdUnavailable(t *testing.T) {
+	s, _ := scanner.New("tcp://127.0.0.1:1", 200*time.Millisecond)
+	if _, err := s.Scan(strings.NewReader("x")); err == nil {
+		t.Fatal("clamd fora do ar deveria devolver erro")
+	}
+}
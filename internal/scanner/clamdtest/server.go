@@ -0,0 +1,116 @@
+// Package clamdtest clamd falso (TCP) para testes: responde PING e INSTREAM, marcando como
+// infectado qualquer conteúdo que contenha a assinatura de teste EICAR.
+package clamdtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// EICAR arquivo de teste padrão de antivírus (inofensivo)
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Signature assinatura devolvida para o EICAR
+const Signature = "Eicar-Test-Signature"
+
+// Server clamd falso
+type Server struct {
+	listener net.Listener
+
+	mu sync.Mutex
+	// scanned conteúdos recebidos via INSTREAM
+	scanned [][]byte
+	// reply resposta fixa (ex.: "INSTREAM size limit exceeded. ERROR"); vazio usa o veredito normal
+	reply string
+}
+
+// New inicia o servidor; ele é encerrado automaticamente no fim do teste
+func New(t testing.TB) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("clamdtest: %v", err)
+	}
+	s := &Server{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+// Address endereço no formato de CLAMD_ADDRESS
+func (s *Server) Address() string {
+	return "tcp://" + s.listener.Addr().String()
+}
+
+// SetReply força a resposta das próximas verificações
+func (s *Server) SetReply(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reply = reply
+}
+
+// Scanned conteúdos verificados até agora
+func (s *Server) Scanned() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.scanned...)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch strings.TrimSuffix(command, "\x00") {
+	case "zPING":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM":
+		var data bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+				return
+			}
+		}
+
+		s.mu.Lock()
+		s.scanned = append(s.scanned, data.Bytes())
+		reply := s.reply
+		s.mu.Unlock()
+
+		switch {
+		case reply != "":
+		case bytes.Contains(data.Bytes(), []byte(EICAR)):
+			reply = "stream: " + Signature + " FOUND"
+		default:
+			reply = "stream: OK"
+		}
+		conn.Write([]byte(reply + "\x00"))
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
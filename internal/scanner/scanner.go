@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Result resultado da verificação de um arquivo
+type Result struct {
+	// Infected arquivo com malware; Signature nome da assinatura detectada
+	Infected  bool
+	Signature string
+}
+
+// Scanner verificação de malware executada antes do processamento
+type Scanner interface {
+	// Name identifica o scanner no Info da resposta
+	Name() string
+	// Scan lê o conteúdo inteiro e devolve o veredito
+	Scan(r io.Reader) (*Result, error)
+}
+
+// Noop scanner padrão, sem verificação (Info registra o arquivo como não verificado)
+type Noop struct{}
+
+// Name nome do scanner
+func (Noop) Name() string {
+	return "none"
+}
+
+// Scan não verifica nada
+func (Noop) Scan(io.Reader) (*Result, error) {
+	return nil, nil
+}
+
+// New cria o scanner configurado: endereço clamd "tcp://host:3310" ou "unix:///caminho/clamd.sock";
+// vazio desativa a verificação
+func New(address string, timeout time.Duration) (Scanner, error) {
+	if address == "" {
+		return Noop{}, nil
+	}
+	network, addr, found := strings.Cut(address, "://")
+	if !found || (network != "tcp" && network != "unix") || addr == "" {
+		return nil, fmt.Errorf("endereço do clamd inválido %q: use tcp://host:porta ou unix:///caminho", address)
+	}
+	return NewClamd(network, addr, timeout), nil
+}
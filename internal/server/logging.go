@@ -0,0 +1,25 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/logging"
+	"backend-fileprocessing/internal/redact"
+)
+
+// setupLogging instala o log estruturado: JSON em produção e texto nos demais ambientes (salvo
+// LOG_FORMAT), no nível de LOG_LEVEL. Segredos (chave do Gemini, tokens em URLs) nunca chegam aos logs.
+func setupLogging(cfg *config.Config) {
+	format := cfg.LogFormat
+	if format == "" {
+		format = logging.FormatText
+		if cfg.Environment == "production" || cfg.Environment == "release" {
+			format = logging.FormatJSON
+		}
+	}
+	if err := logging.Setup(redact.NewWriter(os.Stderr), format, cfg.LogLevel); err != nil {
+		slog.Warn("LOG_LEVEL inválido, usando info", "error", err)
+	}
+}
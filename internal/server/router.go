@@ -1,9 +1,17 @@
 package server
 
 import (
+	"net/http"
+	"os"
+
+	"backend-fileprocessing/internal/auth"
 	"backend-fileprocessing/internal/config"
 	"backend-fileprocessing/internal/handlers"
+	"backend-fileprocessing/internal/logging"
+	"backend-fileprocessing/internal/metrics"
 	"backend-fileprocessing/internal/middleware"
+	"backend-fileprocessing/internal/ratelimit"
+	"backend-fileprocessing/internal/redact"
 	"backend-fileprocessing/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -13,38 +21,134 @@ import (
 
 // NewRouter configura e retorna um *gin.Engine pronto para uso.
 func NewRouter(cfg *config.Config) *gin.Engine {
+	router, _ := NewRouterWithCleanup(cfg)
+	return router
+}
+
+// NewRouterWithCleanup como NewRouter, devolvendo também a função que libera os recursos do
+// serviço de arquivos (artefatos gerados); chame depois que o servidor HTTP parar
+func NewRouterWithCleanup(cfg *config.Config) (*gin.Engine, func()) {
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Segredos (chave do Gemini, tokens em URLs) nunca chegam aos logs
+	setupLogging(cfg)
+	gin.DefaultWriter = redact.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = redact.NewWriter(os.Stderr)
+
 	router := gin.New()
+	router.MaxMultipartMemory = cfg.MultipartMemory
 
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
+	router.Use(middleware.Tracing())
 	router.Use(middleware.Recovery())
-	router.Use(middleware.CORS())
+	router.Use(middleware.Metrics())
 
-	fileService := services.NewFileService()
+	keyStore, err := auth.NewKeyStore(cfg.APIKeys, cfg.APIKeyStore)
+	if err != nil {
+		logging.Fatal("configuração de chaves de API inválida", "error", err)
+	}
+	tokenValidator, err := auth.NewTokenValidator(auth.TokenConfig{
+		Issuer:      cfg.OIDCIssuer,
+		Audiences:   cfg.OIDCAudiences,
+		JWKSURL:     cfg.OIDCJWKSURL,
+		JWKSFile:    cfg.OIDCJWKSFile,
+		JWKSTTL:     cfg.OIDCJWKSTTL,
+		TenantClaim: cfg.OIDCTenantClaim,
+		ScopesClaim: cfg.OIDCScopesClaim,
+	})
+	if err != nil {
+		logging.Fatal("configuração OIDC inválida", "error", err)
+	}
+	authMiddleware := middleware.Auth(auth.NewAuthenticator(keyStore, tokenValidator))
+
+	limiter := ratelimit.New(ratelimit.Config{
+		RequestsPerMinute: cfg.RateLimitRPM,
+		Burst:             cfg.RateLimitBurst,
+		MaxConcurrent:     cfg.MaxConcurrentJobs,
+		DailyBytes:        cfg.DailyUploadBytes,
+	}, nil)
+
+	usageService := services.NewUsageService(cfg)
+	fileService := services.NewFileService(cfg, usageService)
 
 	fileHandler := handlers.NewFileHandler(fileService)
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := handlers.NewHealthHandler(fileService)
+	usageHandler := handlers.NewUsageHandler(usageService)
 
-	setupRoutes(router, fileHandler, healthHandler)
+	// CORS por grupo: rotas públicas aceitam qualquer origem configurada sem credenciais;
+	// as rotas da API seguem a política completa de Config
+	stack := routeMiddleware{
+		auth:    authMiddleware,
+		limiter: limiter,
+		publicCORS: middleware.CORS(middleware.CORSPolicy{
+			AllowedOrigins: cfg.CORSPublicOrigins,
+			AllowedMethods: []string{"GET", "OPTIONS"},
+			AllowedHeaders: cfg.CORSAllowedHeaders,
+			ExposedHeaders: cfg.CORSExposedHeaders,
+			MaxAge:         cfg.CORSMaxAge,
+		}),
+		apiCORS: middleware.CORS(middleware.CORSPolicy{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowedMethods:   cfg.CORSAllowedMethods,
+			AllowedHeaders:   cfg.CORSAllowedHeaders,
+			ExposedHeaders:   cfg.CORSExposedHeaders,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		}),
+	}
 
-	return router
+	setupRoutes(router, stack, fileHandler, healthHandler, usageHandler)
+
+	// Métricas Prometheus fora de /api/v1, sem autenticação (restrinja o acesso na rede)
+	if cfg.MetricsEnabled {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+
+	return router, fileService.Close
+}
+
+// routeMiddleware middlewares aplicados por grupo de rotas
+type routeMiddleware struct {
+	auth       gin.HandlerFunc
+	limiter    *ratelimit.Limiter
+	publicCORS gin.HandlerFunc
+	apiCORS    gin.HandlerFunc
 }
 
-func setupRoutes(router *gin.Engine, fileHandler *handlers.FileHandler, healthHandler *handlers.HealthHandler) {
+func setupRoutes(router *gin.Engine, stack routeMiddleware, fileHandler *handlers.FileHandler, healthHandler *handlers.HealthHandler, usageHandler *handlers.UsageHandler) {
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	v1 := router.Group("/api/v1")
 	{
-		v1.GET("/health", healthHandler.HealthCheck)
-		v1.GET("/status", healthHandler.Status)
+		public := v1.Group("", stack.publicCORS)
+		{
+			public.GET("/health", healthHandler.HealthCheck)
+			public.GET("/files/supported-types", fileHandler.GetSupportedTypes)
+			preflight(public, "/health", "/files/supported-types")
+		}
 
-		files := v1.Group("/files")
+		// Rotas autenticadas (chave de API no header X-API-Key ou token OIDC em Authorization: Bearer),
+		// com limite de requisições por cliente. O CORS vem antes da autenticação para responder o preflight.
+		protected := v1.Group("", stack.apiCORS, stack.auth, middleware.RateLimit(stack.limiter))
 		{
-			files.POST("/process", fileHandler.ProcessFile)
-			files.GET("/supported-types", fileHandler.GetSupportedTypes)
+			protected.GET("/status", middleware.RequireScope(auth.ScopeAdmin), healthHandler.Status)
+			// Qualquer credencial lê o próprio consumo; o de outros clientes exige admin (no handler)
+			protected.GET("/usage", usageHandler.Report)
+			protected.POST("/files/process", middleware.RequireScope(auth.ScopeProcess), middleware.ProcessingQuota(stack.limiter), fileHandler.ProcessFile)
+			protected.GET("/files/artifacts/:id", middleware.RequireScope(auth.ScopeProcess), fileHandler.DownloadArtifact)
+			preflight(protected, "/status", "/usage", "/files/process", "/files/artifacts/:id")
 		}
 	}
 }
+
+// preflight registra rotas OPTIONS para o middleware de CORS do grupo responder o preflight
+func preflight(group *gin.RouterGroup, paths ...string) {
+	for _, path := range paths {
+		group.OPTIONS(path, func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	}
+}
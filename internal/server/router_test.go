@@ -0,0 +1,634 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-fileprocessing/internal/auth"
+	"backend-fileprocessing/internal/geminitest"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/server"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newRouter(t *testing.T) (*gin.Engine, *geminitest.Server) {
+	srv := geminitest.New(t)
+	return server.NewRouter(srv.Config()), srv
+}
+
+func upload(t *testing.T, router http.Handler, filename string, data []byte, client string) *httptest.ResponseRecorder {
+	return uploadWithHeader(t, router, filename, data, "X-Client-ID", client)
+}
+
+func uploadWithHeader(t *testing.T, router http.Handler, filename string, data []byte, header, value string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if filename != "" {
+		part, err := form.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/process", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func get(router http.Handler, path string) *httptest.ResponseRecorder {
+	return getWithKey(router, path, "")
+}
+
+func getWithKey(router http.Handler, path, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, target interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), target); err != nil {
+		t.Fatalf("resposta inválida (%v): %s", err, recorder.Body.String())
+	}
+}
+
+func TestProcessFileEndpoint(t *testing.T) {
+	router, srv := newRouter(t)
+	srv.Enqueue("", geminitest.Pages("Texto da primeira página", "Texto da segunda página"))
+
+	recorder := upload(t, router, "contrato.pdf", geminitest.PDF(2), "cliente-a")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response models.Response
+	decode(t, recorder, &response)
+	if !response.Success || len(response.Data.Pages) != 2 || len(response.Data.Info.Usage) != 1 {
+		t.Fatalf("resposta inesperada: %s", recorder.Body.String())
+	}
+}
+
+func TestProcessFileEndpointErrors(t *testing.T) {
+	router, srv := newRouter(t)
+	srv.Enqueue("", geminitest.SafetyBlock())
+
+	cases := []struct {
+		name     string
+		filename string
+		data     []byte
+		code     string
+	}{
+		{"sem arquivo", "", nil, "NO_FILE"},
+		{"tipo não suportado", "planilha.xlsx", []byte("xlsx"), "UNSUPPORTED_FILE_TYPE"},
+		{"bloqueio de segurança", "foto.png", []byte("png"), "GEMINI_SAFETY_BLOCKED"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := upload(t, router, tc.filename, tc.data, "")
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, esperado 400", recorder.Code)
+			}
+			var response models.Response
+			decode(t, recorder, &response)
+			if response.Error == nil || response.Error.Code != tc.code {
+				t.Fatalf("esperava %s: %s", tc.code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestStatusReportsModelsAndKeys(t *testing.T) {
+	router, _ := newRouter(t)
+	upload(t, router, "foto.png", []byte("png"), "")
+
+	recorder := get(router, "/api/v1/status")
+
+	var body struct {
+		Success bool                  `json:"success"`
+		Data    models.StatusResponse `json:"data"`
+	}
+	decode(t, recorder, &body)
+	if len(body.Data.Gemini) != 1 || body.Data.Gemini[0].State != "closed" || body.Data.Gemini[0].Successes != 1 {
+		t.Fatalf("saúde dos modelos inesperada: %+v", body.Data.Gemini)
+	}
+	if len(body.Data.GeminiKeys) != 1 || body.Data.GeminiKeys[0].Requests != 1 {
+		t.Fatalf("uso das chaves inesperado: %+v", body.Data.GeminiKeys)
+	}
+	if bytes.Contains(recorder.Body.Bytes(), []byte(geminitest.TestAPIKey)) {
+		t.Fatal("o status não pode expor a chave de API")
+	}
+}
+
+func TestUsageReportPerClient(t *testing.T) {
+	router, _ := newRouter(t)
+	upload(t, router, "a.png", []byte("png"), "cliente-a")
+	upload(t, router, "b.png", []byte("png"), "cliente-a")
+	upload(t, router, "notas.txt", []byte("texto simples"), "cliente-b")
+
+	var body struct {
+		Data models.UsageReport `json:"data"`
+	}
+	decode(t, get(router, "/api/v1/usage?client=cliente-a"), &body)
+
+	if body.Data.Totals.Requests != 2 || body.Data.Totals.InputTokens != 2*geminitest.PromptTokens {
+		t.Fatalf("totais inesperados: %+v", body.Data.Totals)
+	}
+	for _, item := range body.Data.Items {
+		if item.Client != "cliente-a" {
+			t.Fatalf("item de outro cliente no relatório: %+v", item)
+		}
+	}
+
+	if recorder := get(router, "/api/v1/usage?from=ontem"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("período inválido deveria responder 400, veio %d", recorder.Code)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.APIKeys = []string{
+		"parceiro:" + auth.HashKey("chave-parceiro") + ":process::1",
+		"operacao:" + auth.HashKey("chave-admin") + ":admin",
+		"antigo:" + auth.HashKey("chave-antiga") + ":process:2020-01-01",
+	}
+	router := server.NewRouter(cfg)
+
+	cases := []struct {
+		name   string
+		do     func() *httptest.ResponseRecorder
+		status int
+		code   string
+	}{
+		{"sem chave", func() *httptest.ResponseRecorder {
+			return upload(t, router, "notas.txt", []byte("texto"), "")
+		}, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"chave inválida", func() *httptest.ResponseRecorder {
+			return uploadWithHeader(t, router, "notas.txt", []byte("texto"), auth.APIKeyHeader, "chave-errada")
+		}, http.StatusUnauthorized, "INVALID_API_KEY"},
+		{"chave expirada", func() *httptest.ResponseRecorder {
+			return uploadWithHeader(t, router, "notas.txt", []byte("texto"), auth.APIKeyHeader, "chave-antiga")
+		}, http.StatusUnauthorized, "API_KEY_EXPIRED"},
+		{"acima do limite da chave", func() *httptest.ResponseRecorder {
+			return uploadWithHeader(t, router, "notas.txt", bytes.Repeat([]byte("a"), 2*1024*1024), auth.APIKeyHeader, "chave-parceiro")
+		}, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE"},
+		{"status sem escopo admin", func() *httptest.ResponseRecorder {
+			return getWithKey(router, "/api/v1/status", "chave-parceiro")
+		}, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"processamento autorizado", func() *httptest.ResponseRecorder {
+			return uploadWithHeader(t, router, "notas.txt", []byte("texto do parceiro"), auth.APIKeyHeader, "chave-parceiro")
+		}, http.StatusOK, ""},
+		{"status com admin", func() *httptest.ResponseRecorder {
+			return getWithKey(router, "/api/v1/status", "chave-admin")
+		}, http.StatusOK, ""},
+		{"health continua aberto", func() *httptest.ResponseRecorder {
+			return get(router, "/api/v1/health")
+		}, http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := tc.do()
+			if recorder.Code != tc.status {
+				t.Fatalf("status = %d, esperado %d: %s", recorder.Code, tc.status, recorder.Body.String())
+			}
+			if tc.code != "" {
+				var response models.Response
+				decode(t, recorder, &response)
+				if response.Error == nil || response.Error.Code != tc.code {
+					t.Fatalf("esperava %s: %s", tc.code, recorder.Body.String())
+				}
+			}
+		})
+	}
+
+	// O uso é atribuído ao id da chave, e clientes sem admin só veem o próprio consumo
+	var body struct {
+		Data models.UsageReport `json:"data"`
+	}
+	decode(t, getWithKey(router, "/api/v1/usage?client=outro", "chave-parceiro"), &body)
+	if body.Data.Client != "key:parceiro" || len(body.Data.Items) != 1 || body.Data.Items[0].Client != "key:parceiro" {
+		t.Fatalf("relatório inesperado: %+v", body.Data)
+	}
+}
+
+func TestRateLimitAndQuotas(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetDefault(geminitest.Text("texto extraído da imagem").After(300 * time.Millisecond))
+	cfg := srv.Config()
+	cfg.RateLimitRPM = 60
+	cfg.RateLimitBurst = 2
+	cfg.MaxConcurrentJobs = 1
+	router := server.NewRouter(cfg)
+
+	// Um processamento lento ocupa a única vaga do cliente
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- upload(t, router, "foto.png", []byte("png"), "") }()
+	time.Sleep(100 * time.Millisecond)
+
+	recorder := upload(t, router, "outra.png", []byte("png"), "")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+		t.Fatalf("segundo processamento simultâneo: status %d, Retry-After %q", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+	if first := <-done; first.Code != http.StatusOK {
+		t.Fatalf("primeiro processamento: status %d", first.Code)
+	}
+
+	// Burst de 2 já consumido: a próxima requisição estoura a taxa
+	recorder = get(router, "/api/v1/usage")
+	var response models.Response
+	decode(t, recorder, &response)
+	if recorder.Code != http.StatusTooManyRequests || response.Error.Code != "RATE_LIMIT_EXCEEDED" {
+		t.Fatalf("esperava RATE_LIMIT_EXCEEDED, veio %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("Retry-After") != "1" || recorder.Header().Get("X-RateLimit-Limit") != "60" {
+		t.Fatalf("headers inesperados: %v", recorder.Header())
+	}
+}
+
+func TestDailyUploadQuota(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.DailyUploadBytes = 4 * 1024
+	router := server.NewRouter(cfg)
+
+	// Uploads recusados antes do processamento não consomem a cota
+	if recorder := upload(t, router, "programa.exe", bytes.Repeat([]byte("a"), 3*1024), ""); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("tipo não suportado: status %d", recorder.Code)
+	}
+	for name, contentType := range map[string]string{"opção inválida": "multipart/form-data", "corpo sem arquivo": "text/plain"} {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("pii", "hash")
+		part, _ := form.CreateFormFile("file", "notas.txt")
+		part.Write(bytes.Repeat([]byte("a"), 3*1024))
+		form.Close()
+		if contentType == "multipart/form-data" {
+			contentType = form.FormDataContentType()
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/process", &body)
+		req.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d", name, recorder.Code)
+		}
+	}
+
+	if recorder := upload(t, router, "notas.txt", bytes.Repeat([]byte("a"), 2*1024), ""); recorder.Code != http.StatusOK {
+		t.Fatalf("primeiro upload: status %d", recorder.Code)
+	}
+	recorder := upload(t, router, "notas.txt", bytes.Repeat([]byte("a"), 3*1024), "")
+	var response models.Response
+	decode(t, recorder, &response)
+	if recorder.Code != http.StatusTooManyRequests || response.Error.Code != "DAILY_QUOTA_EXCEEDED" {
+		t.Fatalf("esperava DAILY_QUOTA_EXCEEDED, veio %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestCORSPoliciesPerRouteGroup(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.CORSAllowedOrigins = []string{"https://*.exemplo.com"}
+	cfg.CORSAllowedMethods = []string{"GET", "POST", "OPTIONS"}
+	cfg.CORSAllowedHeaders = []string{"Content-Type", "X-API-Key"}
+	cfg.CORSExposedHeaders = []string{"X-Request-ID", "Retry-After"}
+	cfg.CORSAllowCredentials = true
+	cfg.CORSPublicOrigins = []string{"*"}
+	router := server.NewRouter(cfg)
+
+	preflightRequest := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := preflightRequest("/api/v1/files/process", "https://painel.exemplo.com")
+	if recorder.Code != http.StatusNoContent ||
+		recorder.Header().Get("Access-Control-Allow-Origin") != "https://painel.exemplo.com" ||
+		recorder.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("preflight da API: status %d, headers %v", recorder.Code, recorder.Header())
+	}
+
+	if recorder := preflightRequest("/api/v1/files/process", "https://exemplo.com.evil.com"); recorder.Code != http.StatusForbidden {
+		t.Fatalf("origem fora da lista deveria ser recusada, veio %d", recorder.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	req.Header.Set("Origin", "https://qualquer.site")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Header().Get("Access-Control-Allow-Origin") != "*" || recorder.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("rotas públicas aceitam qualquer origem, sem credenciais: %v", recorder.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/usage", nil)
+	req.Header.Set("Origin", "https://painel.exemplo.com")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Fatalf("headers expostos ausentes: %v", recorder.Header())
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.MaxFileSize = 1024
+	cfg.MaxFileSizeByType = []string{".pdf=1"}
+	cfg.MultipartMemory = 512
+	router := server.NewRouter(cfg)
+
+	multipartBody := func(filename string, size int) (*bytes.Buffer, string) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", filename)
+		part.Write(bytes.Repeat([]byte("a"), size))
+		form.Close()
+		return &body, form.FormDataContentType()
+	}
+	send := func(filename string, size int, chunked bool) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(filename, size)
+		var req *http.Request
+		if chunked {
+			// Sem Content-Length: o limite só pode ser aplicado durante a leitura
+			req = httptest.NewRequest(http.MethodPost, "/api/v1/files/process", struct{ io.Reader }{body})
+			req.ContentLength = -1
+		} else {
+			req = httptest.NewRequest(http.MethodPost, "/api/v1/files/process", body)
+		}
+		req.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	cases := []struct {
+		name     string
+		filename string
+		size     int
+		chunked  bool
+		status   int
+	}{
+		{"dentro do limite global", "notas.txt", 1000, false, http.StatusOK},
+		{"acima do limite global", "notas.txt", 2000, false, http.StatusRequestEntityTooLarge},
+		{"limite próprio do PDF", "grande.pdf", 600 * 1024, false, http.StatusOK},
+		{"acima do limite do PDF", "grande.pdf", 2 * 1024 * 1024, false, http.StatusRequestEntityTooLarge},
+		{"sem Content-Length acima do teto", "grande.pdf", 2 * 1024 * 1024, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := send(tc.filename, tc.size, tc.chunked)
+			if recorder.Code != tc.status {
+				t.Fatalf("status = %d, esperado %d: %s", recorder.Code, tc.status, recorder.Body.String())
+			}
+			if tc.status == http.StatusRequestEntityTooLarge {
+				var response models.Response
+				decode(t, recorder, &response)
+				if response.Error == nil || response.Error.Code != "FILE_TOO_LARGE" {
+					t.Fatalf("esperava FILE_TOO_LARGE: %s", recorder.Body.String())
+				}
+			}
+		})
+	}
+
+	var body struct {
+		Data models.SupportedTypes `json:"data"`
+	}
+	decode(t, get(router, "/api/v1/files/supported-types"), &body)
+	if body.Data.MaxSizeBytes != 1024 || body.Data.MaxSizeByType[".pdf"] != 1024*1024 {
+		t.Fatalf("limites publicados não seguem a configuração: %+v", body.Data)
+	}
+}
+
+func TestRedactedImageDownload(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.ArtifactDir = t.TempDir()
+	router := server.NewRouter(cfg)
+
+	// Extração do texto e, em seguida, as palavras com coordenadas (0 a 1000)
+	srv.Enqueue("", geminitest.Text("CPF 529.982.247-25"))
+	srv.Enqueue("", geminitest.Text(`[{"text": "CPF", "box_2d": [400, 0, 600, 300]}, {"text": "529.982.247-25", "box_2d": [400, 350, 600, 650]}]`))
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	var data bytes.Buffer
+	png.Encode(&data, img)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "documento.png")
+	part.Write(data.Bytes())
+	form.WriteField("redact", "true")
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/process", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Client-ID", "cliente-a")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response models.Response
+	decode(t, recorder, &response)
+	artifact := response.Data.Redacted
+	if artifact == nil || artifact.Regions != 1 || artifact.ContentType != "image/png" || artifact.FileName != "documento-tarjado.png" {
+		t.Fatalf("cópia tarjada inesperada: %s", recorder.Body.String())
+	}
+
+	download := func(client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, artifact.URL, nil)
+		req.Header.Set("X-Client-ID", client)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := download("cliente-b"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("outro cliente não pode baixar a cópia: status %d", recorder.Code)
+	}
+	recorder = download("cliente-a")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("download: status %d, tipo %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	redacted, err := png.Decode(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := redacted.At(100, 50).RGBA(); r|g|b != 0 {
+		t.Fatal("a região do CPF deveria estar preta")
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router, srv := newRouter(t)
+	srv.Enqueue("", geminitest.Pages("Texto extraído"))
+
+	if recorder := upload(t, router, "metricas.pdf", geminitest.PDF(1), "cliente-a"); recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder := get(router, "/metrics")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	body := recorder.Body.String()
+	for _, series := range []string{
+		`fileprocessing_http_requests_total{method="POST",route="/api/v1/files/process",status="200"}`,
+		`fileprocessing_http_request_duration_seconds_bucket{method="POST",route="/api/v1/files/process",status="200"`,
+		`fileprocessing_processing_duration_seconds_count{file_type=".pdf",outcome="success",processor="pdf"}`,
+		`fileprocessing_gemini_attempts_total{api_version="v1beta",model="gemini-2.0-flash",status_code="200"}`,
+		`fileprocessing_bytes_processed_total{file_type=".pdf"}`,
+		`fileprocessing_jobs_in_flight 0`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Errorf("série ausente em /metrics: %s", series)
+		}
+	}
+}
+
+func TestTracingContinuesCallerTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	router, srv := newRouter(t)
+	srv.Enqueue("", geminitest.Pages("Texto rastreado"))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	response := uploadWithHeader(t, router, "rastreio.png", []byte("imagem"), "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", response.Code, response.Body.String())
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("span %s no trace %s, esperado o trace do chamador", span.Name(), got)
+		}
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"POST /api/v1/files/process", "FileService.ProcessFile", "processor.image", "tempfile.write", "gemini.tryModel", "gemini.generateContent"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("span %q não registrado", name)
+		}
+	}
+	if server := spans["POST /api/v1/files/process"]; server != nil && server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span do handler com pai %s, esperado o span do chamador", server.Parent().SpanID())
+	}
+
+	// A chamada ao Gemini leva adiante o mesmo trace, filha do span da tentativa
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 {
+		t.Fatalf("chamadas generateContent = %d, esperado 1", len(calls))
+	}
+	generate := spans["gemini.generateContent"]
+	if generate == nil {
+		t.FailNow()
+	}
+	want := "00-" + traceID + "-" + generate.SpanContext().SpanID().String() + "-01"
+	if got := calls[0].Header.Get("traceparent"); got != want {
+		t.Errorf("traceparent enviado ao Gemini = %q, esperado %q", got, want)
+	}
+}
+
+func TestRequestIDCorrelation(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	router, srv := newRouter(t)
+	srv.Enqueue("", geminitest.Pages("Texto correlacionado"))
+
+	// O id do chamador é devolvido e repassado ao Gemini
+	response := uploadWithHeader(t, router, "suporte.png", []byte("imagem"), "X-Request-ID", "chamado-4521")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", response.Code, response.Body.String())
+	}
+	if got := response.Header().Get("X-Request-ID"); got != "chamado-4521" {
+		t.Errorf("X-Request-ID da resposta = %q, esperado o do chamador", got)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 || calls[0].Header.Get("X-Request-ID") != "chamado-4521" {
+		t.Errorf("X-Request-ID não repassado ao Gemini: %+v", calls)
+	}
+
+	// Arquivos grandes passam pela Files API: início do upload, blocos e remoção também levam o id
+	cfg := srv.Config()
+	cfg.GeminiUploadThreshold = 1024
+	largeRouter := server.NewRouter(cfg)
+	before := len(srv.Calls())
+	response = uploadWithHeader(t, largeRouter, "grande.png", bytes.Repeat([]byte("x"), 4096), "X-Request-ID", "chamado-4522")
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", response.Code, response.Body.String())
+	}
+	var filesCalls int
+	for _, call := range srv.Calls()[before:] {
+		if call.Header.Get("X-Request-ID") != "chamado-4522" {
+			t.Errorf("%s %s sem o X-Request-ID", call.Method, call.Path)
+		}
+		if call.Header.Get("traceparent") == "" {
+			t.Errorf("%s %s sem traceparent", call.Method, call.Path)
+		}
+		if strings.Contains(call.Path, "upload") || strings.Contains(call.Path, "/files/") {
+			filesCalls++
+		}
+	}
+	if filesCalls < 3 {
+		t.Errorf("esperava início, envio e remoção pela Files API, houve %d chamadas", filesCalls)
+	}
+
+	// Sem id (ou com um id inválido) o serviço gera um, presente também no corpo do erro
+	for _, sent := range []string{"", "quebra\nde linha"} {
+		recorder := uploadWithHeader(t, router, "planilha.xlsx", []byte("xlsx"), "X-Request-ID", sent)
+		id := recorder.Header().Get("X-Request-ID")
+		if len(id) != 32 {
+			t.Fatalf("X-Request-ID gerado = %q, esperado 32 dígitos hexadecimais", id)
+		}
+		var body models.Response
+		decode(t, recorder, &body)
+		if body.Error == nil || body.Error.RequestID != id {
+			t.Errorf("requestId do erro diferente do header %q: %s", id, recorder.Body.String())
+		}
+	}
+}
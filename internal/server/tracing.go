@@ -0,0 +1,19 @@
+package server
+
+import (
+	"context"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/tracing"
+)
+
+// SetupTracing instala o tracing OpenTelemetry conforme a configuração; a função devolvida envia
+// os spans pendentes ao encerrar o processo
+func SetupTracing(cfg *config.Config) (func(context.Context) error, error) {
+	return tracing.Setup(tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// Artifact arquivo gerado por um processamento, disponível para download até expirar
+type Artifact struct {
+	ID          string
+	Path        string
+	FileName    string
+	ContentType string
+	// Client só o cliente que gerou o arquivo pode baixá-lo
+	Client    string
+	ExpiresAt time.Time
+}
+
+// ArtifactStore guarda os arquivos gerados em disco local por tempo limitado. O índice fica em
+// memória: o download precisa chegar à mesma instância que gerou o arquivo, e uma reinicialização
+// descarta os arquivos pendentes.
+type ArtifactStore struct {
+	dir string
+	ttl time.Duration
+
+	mu    sync.Mutex
+	items map[string]*Artifact
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewArtifactStore cria o diretório dos arquivos gerados, removendo os que sobraram de uma execução
+// anterior, e passa a apagar os expirados periodicamente; ttl define por quanto tempo ficam disponíveis
+func NewArtifactStore(dir string, ttl time.Duration) (*ArtifactStore, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "fileprocessing-artifacts")
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de artefatos: %v", err)
+	}
+	removeOrphans(dir)
+
+	s := &ArtifactStore{dir: dir, ttl: ttl, items: make(map[string]*Artifact), stop: make(chan struct{})}
+	go s.sweepLoop(min(ttl, time.Minute))
+	return s, nil
+}
+
+// removeOrphans apaga arquivos de artefatos sem índice (gravados antes de uma reinicialização);
+// só nomes no formato dos ids gerados, para não apagar nada alheio se o diretório for compartilhado
+func removeOrphans(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		slog.Warn("erro ao listar artefatos antigos", "dir", dir, "error", err)
+		return
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isArtifactID(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			slog.Warn("erro ao remover artefato antigo", "artifact", entry.Name(), "error", err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		slog.Info("artefatos de uma execução anterior removidos", "count", removed)
+	}
+}
+
+// isArtifactID indica se o nome tem o formato dos ids gerados em Put (32 dígitos hexadecimais)
+func isArtifactID(name string) bool {
+	if len(name) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// sweepLoop remove os expirados a cada intervalo, mesmo sem novos arquivos chegando
+func (s *ArtifactStore) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Put grava o arquivo e devolve a descrição para a resposta
+func (s *ArtifactStore) Put(client, fileName, contentType string, data []byte) (*models.Artifact, error) {
+	s.sweep()
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("erro ao gerar id do artefato: %v", err)
+	}
+	id := hex.EncodeToString(idBytes)
+	path := filepath.Join(s.dir, id)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("erro ao gravar artefato: %v", err)
+	}
+
+	artifact := &Artifact{
+		ID:          id,
+		Path:        path,
+		FileName:    fileName,
+		ContentType: contentType,
+		Client:      client,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	s.mu.Lock()
+	s.items[id] = artifact
+	s.mu.Unlock()
+
+	return &models.Artifact{
+		ID:          id,
+		URL:         "/api/v1/files/artifacts/" + id,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ExpiresAt:   artifact.ExpiresAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// Get devolve o artefato ainda válido do cliente (nil se não existe, expirou ou é de outro cliente)
+func (s *ArtifactStore) Get(id, client string, admin bool) *Artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	artifact, exists := s.items[id]
+	if !exists || time.Now().After(artifact.ExpiresAt) || (!admin && artifact.Client != client) {
+		return nil
+	}
+	return artifact
+}
+
+// sweep remove do disco os artefatos expirados
+func (s *ArtifactStore) sweep() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, artifact := range s.items {
+		if now.After(artifact.ExpiresAt) {
+			if err := os.Remove(artifact.Path); err != nil && !os.IsNotExist(err) {
+				slog.Warn("erro ao remover artefato expirado", "artifact", id, "error", err)
+			}
+			delete(s.items, id)
+		}
+	}
+}
+
+// Close encerra a limpeza periódica e remove todos os artefatos
+func (s *ArtifactStore) Close() {
+	s.closeOnce.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, artifact := range s.items {
+		os.Remove(artifact.Path)
+		delete(s.items, id)
+	}
+}
@@ -1,37 +1,71 @@
 package services
 
 import (
-    "fmt"
-    "io"
-    "log"
-    "path/filepath"
-    "strings"
-    "time"
-
-    "backend-fileprocessing/internal/models"
-    "backend-fileprocessing/internal/processors"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/logging"
+	"backend-fileprocessing/internal/metrics"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pii"
+	"backend-fileprocessing/internal/processors"
+	"backend-fileprocessing/internal/redaction"
+	"backend-fileprocessing/internal/scanner"
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // FileService serviço de processamento de arquivos usando APENAS Google Gemini
 type FileService struct {
 	geminiService *GeminiService
+	usageService  *UsageService
 	processors    map[string]processors.FileProcessor
+	// maxFileSize limite global e typeLimits limites próprios por extensão
+	maxFileSize int64
+	typeLimits  map[string]int64
+	// scanner verificação de malware antes do processamento; scanFailOpen processa mesmo com o scanner fora do ar
+	scanner      scanner.Scanner
+	scanFailOpen bool
+	// piiMode tratamento padrão de dados pessoais; piiRedactor aplica máscara e tokens
+	piiMode     pii.Mode
+	piiRedactor *pii.Redactor
+	// redactor gera cópias tarjadas, guardadas em artifacts até o download
+	redactor  *redaction.Redactor
+	artifacts *ArtifactStore
+}
+
+// ProcessOptions opções de processamento escolhidas na requisição
+type ProcessOptions struct {
+	// PIIMode tratamento de dados pessoais no texto; vazio usa PII_MODE
+	PIIMode pii.Mode
+	// Redact gera uma cópia do PDF ou da imagem com os dados pessoais tarjados
+	Redact bool
 }
 
-// NewFileService cria novo serviço de arquivos
-func NewFileService() *FileService {
+// NewFileService cria novo serviço de arquivos; o consumo de cada processamento é registrado em usageService
+func NewFileService(cfg *config.Config, usageService *UsageService) *FileService {
 	// Inicializar serviço Gemini (OBRIGATÓRIO!)
-	geminiService := NewGeminiService()
+	geminiService := NewGeminiService(cfg)
 	if !geminiService.IsAvailable() {
-		log.Printf("⚠️ ATENÇÃO: Gemini não disponível - GEMINI_API_KEY não configurada")
-		log.Printf("⚠️ Configure GEMINI_API_KEY para processar arquivos")
-	} else {
-		log.Printf("✅ Gemini configurado e disponível")
+		slog.Warn("Gemini não disponível: configure GEMINI_API_KEY para processar arquivos")
 	}
 
 	// Mapear processadores por tipo de arquivo - TODOS usam Gemini!
 	processorsMap := map[string]processors.FileProcessor{
-		".pdf":  processors.NewPDFProcessor(geminiService),
+		".pdf":  processors.NewPDFProcessor(geminiService, cfg.PDFChunkPages, cfg.PDFChunkConcurrency),
 		".png":  processors.NewImageProcessor(geminiService),
 		".jpg":  processors.NewImageProcessor(geminiService),
 		".jpeg": processors.NewImageProcessor(geminiService),
@@ -41,61 +75,438 @@ func NewFileService() *FileService {
 		".tiff": processors.NewImageProcessor(geminiService),
 		".txt":  processors.NewTextProcessor(),
 		".docx": processors.NewDocxProcessor(geminiService),
+		".xml":  processors.NewXMLProcessor(),
+	}
+
+	// Verificação de malware (sem CLAMD_ADDRESS os arquivos seguem sem verificação)
+	fileScanner, err := scanner.New(cfg.ClamdAddress, cfg.ClamdTimeout)
+	if err != nil {
+		logging.Fatal("configuração do scanner de malware inválida", "error", err)
+	}
+	if clamd, ok := fileScanner.(*scanner.Clamd); ok {
+		if err := clamd.Ping(); err != nil {
+			slog.Warn("clamd não respondeu", "address", cfg.ClamdAddress, "error", err)
+		} else {
+			slog.Info("verificação de malware ativa", "scanner", "clamd", "address", cfg.ClamdAddress)
+		}
+	}
+
+	// Dados pessoais: sem PII_TOKEN_KEY os tokens só são estáveis enquanto a instância estiver no ar
+	piiMode, err := pii.ParseMode(cfg.PIIMode)
+	if err != nil {
+		logging.Fatal("PII_MODE inválido", "error", err)
+	}
+	tokenKey := []byte(cfg.PIITokenKey)
+	if len(tokenKey) == 0 {
+		tokenKey = make([]byte, 32)
+		if _, err := rand.Read(tokenKey); err != nil {
+			logging.Fatal("erro ao gerar chave de tokenização de PII", "error", err)
+		}
+		slog.Warn("PII_TOKEN_KEY não configurada: tokens de PII mudam a cada reinício")
 	}
 
+	// Cópias tarjadas: posições nativas e rasterização do PDF pelo Poppler, palavras de imagens pelo Gemini
+	artifacts, err := NewArtifactStore(cfg.ArtifactDir, cfg.ArtifactTTL)
+	if err != nil {
+		logging.Fatal("erro ao preparar o diretório de artefatos", "error", err)
+	}
+	poppler := redaction.NewPoppler(cfg.PdftoppmPath, cfg.PdftotextPath, cfg.RedactionTimeout)
+
 	return &FileService{
 		geminiService: geminiService,
+		usageService:  usageService,
 		processors:    processorsMap,
+		maxFileSize:   cfg.MaxFileSize,
+		typeLimits:    parseSizeLimits(cfg.MaxFileSizeByType),
+		scanner:       fileScanner,
+		scanFailOpen:  cfg.ScanFailOpen,
+		piiMode:       piiMode,
+		piiRedactor:   pii.NewRedactor(tokenKey),
+		redactor:      redaction.New(geminiService, poppler, cfg.RedactionDPI),
+		artifacts:     artifacts,
 	}
 }
 
-// ProcessFile processa arquivo e extrai texto
-func (fs *FileService) ProcessFile(file io.Reader, filename string, size int64) (models.Response, error) {
-	startTime := time.Now()
+// parseSizeLimits interpreta entradas ".ext=MB" de MAX_FILE_SIZE_BY_TYPE
+func parseSizeLimits(entries []string) map[string]int64 {
+	limits := make(map[string]int64)
+	for _, entry := range entries {
+		ext, value, found := strings.Cut(entry, "=")
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		mb, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || ext == "" || err != nil || mb <= 0 {
+			slog.Warn("entrada inválida em MAX_FILE_SIZE_BY_TYPE ignorada (use .ext=MB)", "entry", entry)
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		limits[ext] = int64(mb) * 1024 * 1024
+	}
+	return limits
+}
+
+// MaxFileSize limite de upload para a extensão (limite próprio do tipo ou o global)
+func (fs *FileService) MaxFileSize(fileType string) int64 {
+	if limit, ok := fs.typeLimits[strings.ToLower(fileType)]; ok {
+		return limit
+	}
+	return fs.maxFileSize
+}
+
+// MaxUploadSize maior limite entre todos os tipos (teto do corpo da requisição, antes de saber o tipo)
+func (fs *FileService) MaxUploadSize() int64 {
+	limit := fs.maxFileSize
+	for _, typeLimit := range fs.typeLimits {
+		if typeLimit > limit {
+			limit = typeLimit
+		}
+	}
+	return limit
+}
+
+// ProcessFile processa arquivo e extrai texto; client identifica quem pagará o consumo de tokens.
+// ctx carrega o span da requisição, do qual descendem os spans do processamento.
+func (fs *FileService) ProcessFile(ctx context.Context, file io.Reader, filename string, size int64, client string, opts ProcessOptions) (models.Response, error) {
 	fileType := strings.ToLower(filepath.Ext(filename))
+	ctx = logging.With(ctx, logging.KeyClient, client, logging.KeyFileHash, logging.FileHash(filename))
+	ctx, span := tracing.Start(ctx, "FileService.ProcessFile", tracing.FileAttributes(fileType, size))
+	response, err := fs.processFile(ctx, file, filename, fileType, size, client, opts)
+	if err == nil && response.Error != nil {
+		span.SetAttributes(attribute.String("error.code", response.Error.Code))
+		span.SetStatus(codes.Error, response.Error.Message)
+	}
+	tracing.End(span, err)
+	return response, err
+}
+
+func (fs *FileService) processFile(ctx context.Context, file io.Reader, filename, fileType string, size int64, client string, opts ProcessOptions) (models.Response, error) {
+	startTime := time.Now()
 	info := models.NewInfo(filename, fileType, size)
 
-	log.Printf("📁 Processando arquivo: %s (%.2f MB)", filename, float64(size)/1024/1024)
+	metrics.JobsInFlight.Inc()
+	defer metrics.JobsInFlight.Dec()
 
 	// Verificar se tipo é suportado
 	processor, exists := fs.processors[fileType]
-    if !exists {
-        return models.NewErrorResponse(
-            "UNSUPPORTED_FILE_TYPE",
-            fmt.Sprintf("Tipo de arquivo não suportado: %s", fileType),
-            "Tipos suportados: .pdf, .png, .jpg, .jpeg, .gif, .bmp, .webp, .tiff, .txt, .docx",
-        ), nil
-    }
-
-	// Processar arquivo
-	text, err := processor.Process(file, filename)
-    if err != nil {
-        return models.NewErrorResponse(
-            "PROCESSING_ERROR",
-            fmt.Sprintf("Erro ao processar arquivo: %v", err),
-            "Verifique se o arquivo não está corrompido",
-        ), nil
-    }
+	if !exists {
+		slog.WarnContext(ctx, "tipo de arquivo não suportado", "file_type", fileType)
+		return models.NewErrorResponse(
+			"UNSUPPORTED_FILE_TYPE",
+			fmt.Sprintf("Tipo de arquivo não suportado: %s", fileType),
+			"Tipos suportados: .pdf, .png, .jpg, .jpeg, .gif, .bmp, .webp, .tiff, .txt, .docx, .xml",
+		), nil
+	}
+
+	ctx = logging.With(ctx, logging.KeyProcessor, processorName(processor))
+	slog.InfoContext(ctx, "processando arquivo", "file_type", fileType, "size_bytes", size)
+
+	if opts.Redact && !redaction.Supported(fileType) {
+		return models.NewErrorResponse(
+			"REDACTION_UNSUPPORTED",
+			fmt.Sprintf("Cópia tarjada não disponível para %s", fileType),
+			"A cópia tarjada pode ser gerada para PDF e imagens",
+		), nil
+	}
+
+	// Verificar malware antes de entregar o arquivo a qualquer processador
+	file, verdict, rejection := fs.scan(ctx, file)
+	info.Scan = verdict
+	if rejection != nil {
+		// O veredito (infectado ou erro do scanner) acompanha a recusa
+		if verdict != nil {
+			rejection.Data = &models.Data{Info: info}
+		}
+		return *rejection, nil
+	}
+
+	// A cópia tarjada parte do arquivo original, que o processador consome
+	var original []byte
+	if opts.Redact {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return models.NewErrorResponse(
+				"PROCESSING_ERROR",
+				fmt.Sprintf("Erro ao ler arquivo: %v", err),
+				"Tente enviar o arquivo novamente",
+			), nil
+		}
+		original = data
+		file = bytes.NewReader(data)
+	}
+
+	// Processar arquivo (processadores estruturados também devolvem dados do documento)
+	var result *processors.Result
+	var err error
+	processingStart := time.Now()
+	processorCtx, processorSpan := tracing.Start(ctx, "processor."+processorName(processor), tracing.FileAttributes(fileType, size))
+	if structured, ok := processor.(processors.StructuredProcessor); ok {
+		result, err = structured.ProcessStructured(processorCtx, file, filename)
+	} else {
+		var text string
+		text, err = processor.Process(processorCtx, file, filename)
+		result = &processors.Result{Text: text}
+	}
+	tracing.End(processorSpan, err)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	} else {
+		metrics.BytesProcessed.WithLabelValues(fileType).Add(float64(size))
+	}
+	metrics.ProcessingDuration.WithLabelValues(fileType, processorName(processor), outcome).Observe(time.Since(processingStart).Seconds())
+	if err != nil {
+		// Respostas bloqueadas, truncadas ou vazias e blocos perdidos também consumiram tokens
+		if usage := processors.FailureUsage(err); len(usage) > 0 {
+			fs.usageService.Record(client, usage)
+		}
+		// Bloqueios e respostas incompletas do Gemini têm código próprio
+		var coded processors.CodedError
+		if errors.As(err, &coded) {
+			details := "Verifique se o arquivo não está corrompido"
+			var geminiErr *GeminiResponseError
+			if errors.As(err, &geminiErr) && geminiErr.Details != "" {
+				details = geminiErr.Details
+			}
+			return models.NewErrorResponse(
+				coded.ErrorCode(),
+				fmt.Sprintf("Erro ao processar arquivo: %v", err),
+				details,
+			), nil
+		}
+		return models.NewErrorResponse(
+			"PROCESSING_ERROR",
+			fmt.Sprintf("Erro ao processar arquivo: %v", err),
+			"Verifique se o arquivo não está corrompido",
+		), nil
+	}
 
 	// Calcular tempo de processamento
 	processingTime := time.Since(startTime)
 	info.ProcessingTime = processingTime.String()
 
-	log.Printf("✅ Arquivo processado com sucesso: %d caracteres em %v", len(text), processingTime)
-    return models.NewSuccessResponse(text, info), nil
+	// Cópia tarjada
+	var redacted *redaction.Output
+	if opts.Redact {
+		redacted, err = fs.redactor.Redact(ctx, original, fileType)
+		if err != nil {
+			slog.ErrorContext(ctx, "erro ao gerar cópia tarjada", "error", err)
+			// A extração já consumiu tokens
+			fs.usageService.Record(client, result.Usage)
+			return models.NewErrorResponse(
+				"REDACTION_FAILED",
+				fmt.Sprintf("Erro ao gerar cópia tarjada: %v", err),
+				"Verifique se o pdftoppm/pdftotext (poppler-utils) está instalado para PDFs",
+			), nil
+		}
+		processingTime = time.Since(startTime)
+		info.ProcessingTime = processingTime.String()
+	}
+
+	// Consumo de tokens: usageMetadata do Gemini ou estimativa local nas extrações nativas
+	usage := result.Usage
+	if len(usage) == 0 {
+		usage = []models.TokenUsage{EstimateNative(result.Text)}
+	}
+	if redacted != nil {
+		usage = processors.MergeUsage(usage, redacted.Usage)
+	}
+	fs.usageService.Record(client, usage)
+	info.Usage = usage
+
+	slog.InfoContext(ctx, "arquivo processado", "chars", len(result.Text), "pages", len(result.Pages), "duration_ms", processingTime.Milliseconds())
+	response := models.NewSuccessResponse(result.Text, info)
+	response.Data.Pages = result.Pages
+	response.Data.Document = result.Document
+	response.Data.FailedChunks = result.Failures
+
+	// Dados pessoais no texto extraído
+	piiMode := opts.PIIMode
+	if piiMode == "" {
+		piiMode = fs.piiMode
+	}
+	if piiMode != pii.ModeOff {
+		fs.applyPII(response.Data, piiMode)
+		slog.InfoContext(ctx, "dados pessoais tratados", "pii_mode", string(piiMode), "findings", len(response.Data.PII.Findings))
+	}
+
+	if redacted != nil {
+		name := strings.TrimSuffix(filename, filepath.Ext(filename)) + "-tarjado" + redacted.Extension
+		_, putSpan := tracing.Start(ctx, "tempfile.write", trace.WithAttributes(attribute.Int("file.size", len(redacted.Data))))
+		artifact, err := fs.artifacts.Put(client, name, redacted.ContentType, redacted.Data)
+		tracing.End(putSpan, err)
+		if err != nil {
+			return models.Response{}, err
+		}
+		artifact.Pages = redacted.Pages
+		artifact.Regions = redacted.Regions
+		response.Data.Redacted = artifact
+		slog.InfoContext(ctx, "cópia tarjada gerada", "regions", redacted.Regions, "pages", redacted.Pages)
+	}
+	return response, nil
+}
+
+// processorName nome curto do processador para as métricas ("PDFProcessor" vira "pdf")
+func processorName(processor processors.FileProcessor) string {
+	name := fmt.Sprintf("%T", processor)
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.ToLower(strings.TrimSuffix(name, "Processor"))
+}
+
+// applyPII aplica o modo de PII ao texto, às páginas e às partes do XML fiscal, ajustando os offsets quando os tokens mudam o tamanho
+func (fs *FileService) applyPII(data *models.Data, mode pii.Mode) {
+	text, findings, convert := fs.piiRedactor.Apply(data.Text, mode)
+	data.Text = text
+
+	runes := []rune(text)
+	for i := range data.Pages {
+		page := &data.Pages[i]
+		page.StartOffset = convert(page.StartOffset)
+		page.EndOffset = convert(page.EndOffset)
+		page.Text = string(runes[page.StartOffset:page.EndOffset])
+	}
+	for i := range findings {
+		for _, page := range data.Pages {
+			if findings[i].Start >= page.StartOffset && findings[i].Start < page.EndOffset {
+				findings[i].Page = page.Number
+				break
+			}
+		}
+	}
+
+	if findings == nil {
+		findings = []models.PIIFinding{}
+	}
+	data.PII = &models.PIIReport{Mode: string(mode), Findings: findings}
+
+	if doc := data.Document; doc != nil {
+		fs.applyPartyPII(&doc.Issuer, mode)
+		if doc.Recipient != nil {
+			fs.applyPartyPII(doc.Recipient, mode)
+		}
+	}
+}
+
+// applyPartyPII aplica o modo ao documento, ao endereço e, para pessoas físicas, ao nome de uma
+// parte do XML fiscal; os tokens são os mesmos gerados para esses valores no texto
+func (fs *FileService) applyPartyPII(party *models.FiscalParty, mode pii.Mode) {
+	switch party.DocumentType {
+	case "CPF":
+		party.Document = fs.piiRedactor.Replace(pii.TypeCPF, party.Document, mode)
+		party.Name = fs.piiRedactor.Replace(pii.TypeName, party.Name, mode)
+		party.TradeName = fs.piiRedactor.Replace(pii.TypeName, party.TradeName, mode)
+	case "CNPJ":
+		party.Document = fs.piiRedactor.Replace(pii.TypeCNPJ, party.Document, mode)
+	default:
+		party.Document = fs.piiRedactor.Replace(strings.ToLower(party.DocumentType), party.Document, mode)
+	}
+	if address := party.Address; address != nil {
+		address.Street = fs.piiRedactor.Replace(pii.TypeAddress, address.Street, mode)
+		address.Number = fs.piiRedactor.Replace(pii.TypeAddress, address.Number, mode)
+		address.District = fs.piiRedactor.Replace(pii.TypeAddress, address.District, mode)
+		address.ZipCode = fs.piiRedactor.Replace(pii.TypeAddress, address.ZipCode, mode)
+	}
+}
+
+// scan verifica o arquivo e devolve um leitor posicionado no início para o processador; rejection
+// é preenchido quando o arquivo não deve ser processado, com o veredito quando o scanner foi consultado
+func (fs *FileService) scan(ctx context.Context, file io.Reader) (io.Reader, *models.ScanVerdict, *models.Response) {
+	if _, disabled := fs.scanner.(scanner.Noop); disabled {
+		return file, &models.ScanVerdict{Scanner: fs.scanner.Name(), Status: "not_scanned"}, nil
+	}
+
+	// O scanner consome o conteúdo: uploads multipart permitem voltar ao início, os demais ficam em memória
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			rejection := models.NewErrorResponse(
+				"PROCESSING_ERROR",
+				fmt.Sprintf("Erro ao ler arquivo: %v", err),
+				"Tente enviar o arquivo novamente",
+			)
+			return nil, nil, &rejection
+		}
+		content = bytes.NewReader(data)
+	}
+
+	start := time.Now()
+	result, err := fs.scanner.Scan(content)
+	verdict := &models.ScanVerdict{Scanner: fs.scanner.Name(), Duration: time.Since(start).String()}
+	if _, seekErr := content.Seek(0, io.SeekStart); seekErr != nil && err == nil {
+		err = fmt.Errorf("erro ao voltar ao início do arquivo: %v", seekErr)
+	}
+
+	if err != nil {
+		verdict.Status = "error"
+		if !fs.scanFailOpen {
+			slog.ErrorContext(ctx, "falha na verificação de malware", "error", err)
+			rejection := models.NewErrorResponse(
+				"SCAN_FAILED",
+				"Não foi possível verificar o arquivo contra malware",
+				"O serviço de verificação está indisponível; tente novamente mais tarde",
+			)
+			return nil, verdict, &rejection
+		}
+		slog.WarnContext(ctx, "verificação de malware falhou, processando mesmo assim (SCAN_FAIL_OPEN)", "error", err)
+		return content, verdict, nil
+	}
+
+	if result.Infected {
+		slog.WarnContext(ctx, "malware detectado", "signature", result.Signature)
+		verdict.Status = "infected"
+		verdict.Signature = result.Signature
+		rejection := models.NewErrorResponse(
+			"MALWARE_DETECTED",
+			"Arquivo recusado: malware detectado",
+			fmt.Sprintf("Assinatura: %s", result.Signature),
+		)
+		return nil, verdict, &rejection
+	}
+
+	verdict.Status = "clean"
+	return content, verdict, nil
 }
 
 // GetSupportedTypes retorna tipos de arquivo suportados
 func (fs *FileService) GetSupportedTypes() *models.SupportedTypes {
-	return &models.SupportedTypes{
-		Documents: []string{".pdf", ".txt", ".docx"},
-		Images:    []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp", ".tiff"},
-		MaxSize:   "25MB",
-		MaxSizeBytes: 25 * 1024 * 1024,
+	supported := &models.SupportedTypes{
+		Documents:    []string{".pdf", ".txt", ".docx", ".xml"},
+		Images:       []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp", ".tiff"},
+		MaxSize:      FormatSize(fs.maxFileSize),
+		MaxSizeBytes: fs.maxFileSize,
+	}
+	if len(fs.typeLimits) > 0 {
+		supported.MaxSizeByType = fs.typeLimits
 	}
+	return supported
+}
+
+// ModelHealth estado de saúde dos modelos Gemini (circuit breaker)
+func (fs *FileService) ModelHealth() []models.ModelHealth {
+	return fs.geminiService.ModelHealth()
+}
+
+// KeyUsage contadores de uso das chaves de API do Gemini
+func (fs *FileService) KeyUsage() []models.APIKeyUsage {
+	return fs.geminiService.KeyUsage()
+}
+
+// Artifact arquivo gerado para download (nil se não existe, expirou ou pertence a outro cliente)
+func (fs *FileService) Artifact(id, client string, admin bool) *Artifact {
+	return fs.artifacts.Get(id, client, admin)
 }
 
 // Close fecha recursos do serviço
 func (fs *FileService) Close() {
-	// Gemini não precisa de cleanup
+	// Gemini não precisa de cleanup; os artefatos gerados são descartados
+	fs.artifacts.Close()
+}
+
+// FormatSize tamanho legível em MB ("25MB", "0.50MB")
+func FormatSize(bytes int64) string {
+	if bytes%(1024*1024) == 0 {
+		return fmt.Sprintf("%dMB", bytes/1024/1024)
+	}
+	return fmt.Sprintf("%.2fMB", float64(bytes)/1024/1024)
 }
@@ -0,0 +1,620 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/geminitest"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/pii"
+	"backend-fileprocessing/internal/scanner/clamdtest"
+	"backend-fileprocessing/internal/services"
+)
+
+func newFileService(cfg *config.Config) (*services.FileService, *services.UsageService) {
+	usage := services.NewUsageService(cfg)
+	return services.NewFileService(cfg, usage), usage
+}
+
+func process(t *testing.T, fs *services.FileService, filename string, data []byte) models.Response {
+	t.Helper()
+	response, err := fs.ProcessFile(context.Background(), bytes.NewReader(data), filename, int64(len(data)), "tester", services.ProcessOptions{})
+	if err != nil {
+		t.Fatalf("ProcessFile(%s): %v", filename, err)
+	}
+	return response
+}
+
+func requireSuccess(t *testing.T, response models.Response) *models.Data {
+	t.Helper()
+	if !response.Success {
+		t.Fatalf("esperava sucesso, veio erro %+v", response.Error)
+	}
+	return response.Data
+}
+
+func requireError(t *testing.T, response models.Response, code string) {
+	t.Helper()
+	if response.Success || response.Error == nil {
+		t.Fatalf("esperava erro %s, veio sucesso", code)
+	}
+	if response.Error.Code != code {
+		t.Fatalf("código = %s, esperado %s (%s)", response.Error.Code, code, response.Error.Message)
+	}
+}
+
+func generateModels(calls []geminitest.Call) []string {
+	var result []string
+	for _, call := range calls {
+		result = append(result, call.Model)
+	}
+	return result
+}
+
+func TestProcessPDFReturnsPagesAndUsage(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.Pages("Primeira página do documento", "Segunda página do documento"))
+	fs, usage := newFileService(srv.Config())
+
+	data := requireSuccess(t, process(t, fs, "contrato.pdf", geminitest.PDF(2)))
+
+	if len(data.Pages) != 2 || data.Pages[1].Number != 2 || data.Pages[1].Text != "Segunda página do documento" {
+		t.Fatalf("páginas inesperadas: %+v", data.Pages)
+	}
+	if len(data.Info.Usage) != 1 || data.Info.Usage[0].InputTokens != geminitest.PromptTokens || data.Info.Usage[0].Estimated {
+		t.Fatalf("consumo inesperado: %+v", data.Info.Usage)
+	}
+
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 {
+		t.Fatalf("esperava 1 chamada, houve %d", len(calls))
+	}
+	if calls[0].APIKey != geminitest.TestAPIKey || calls[0].QueryKey != "" {
+		t.Fatalf("a chave deve ir só no header: header=%q query=%q", calls[0].APIKey, calls[0].QueryKey)
+	}
+
+	report, err := usage.Report("", "", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Totals.Requests != 1 || report.Totals.InputTokens != geminitest.PromptTokens {
+		t.Fatalf("relatório inesperado: %+v", report.Totals)
+	}
+}
+
+func TestFallsBackToNextModelOnNotFound(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("gemini-removido", geminitest.NotFound())
+	cfg := srv.Config()
+	cfg.GeminiModels = []string{"gemini-removido", "gemini-2.0-flash"}
+	fs, _ := newFileService(cfg)
+
+	requireSuccess(t, process(t, fs, "foto.png", []byte("png")))
+
+	got := generateModels(srv.GenerateCalls())
+	if strings.Join(got, ",") != "gemini-removido,gemini-2.0-flash" {
+		t.Fatalf("modelos chamados: %v", got)
+	}
+}
+
+func TestDiscoversModelsWhenNoneConfigured(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetModels("gemini-1.5-flash")
+	cfg := srv.Config()
+	cfg.GeminiModels = nil
+	fs, _ := newFileService(cfg)
+
+	requireSuccess(t, process(t, fs, "foto.png", []byte("png")))
+
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 || calls[0].Model != "gemini-1.5-flash" {
+		t.Fatalf("esperava uso do modelo listado, chamadas: %v", generateModels(calls))
+	}
+}
+
+func TestRetriesRateLimitAfterRetryDelay(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.RateLimited(200*time.Millisecond))
+	fs, _ := newFileService(srv.Config())
+
+	start := time.Now()
+	requireSuccess(t, process(t, fs, "foto.png", []byte("png")))
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("o retryDelay não foi respeitado: %v", elapsed)
+	}
+	if calls := srv.GenerateCalls(); len(calls) != 2 {
+		t.Fatalf("esperava 2 chamadas, houve %d", len(calls))
+	}
+}
+
+func TestRotatesKeyOnRateLimit(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.RateLimited(30*time.Second))
+	cfg := srv.Config()
+	cfg.GeminiAPIKeys = []string{"first-key-0123456789", "second-key-0123456789"}
+	fs, _ := newFileService(cfg)
+
+	start := time.Now()
+	requireSuccess(t, process(t, fs, "foto.png", []byte("png")))
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("deveria trocar de chave sem esperar o retryDelay: %v", elapsed)
+	}
+	calls := srv.GenerateCalls()
+	if len(calls) != 2 || calls[0].APIKey == calls[1].APIKey {
+		t.Fatalf("esperava duas chamadas com chaves diferentes: %+v", calls)
+	}
+
+	cooling := 0
+	for _, key := range fs.KeyUsage() {
+		if key.CooldownUntil != nil {
+			cooling++
+		}
+	}
+	if cooling != 1 {
+		t.Fatalf("esperava uma chave em cooldown: %+v", fs.KeyUsage())
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.ServerError(), geminitest.Unavailable())
+	fs, _ := newFileService(srv.Config())
+
+	requireSuccess(t, process(t, fs, "foto.png", []byte("png")))
+
+	if calls := srv.GenerateCalls(); len(calls) != 3 {
+		t.Fatalf("esperava 3 chamadas, houve %d", len(calls))
+	}
+}
+
+func TestBadRequestIsNotRetried(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.BadRequest("Unsupported MIME type"))
+	fs, _ := newFileService(srv.Config())
+
+	requireError(t, process(t, fs, "foto.png", []byte("png")), "PROCESSING_ERROR")
+
+	if calls := srv.GenerateCalls(); len(calls) != 1 {
+		t.Fatalf("esperava 1 chamada, houve %d", len(calls))
+	}
+}
+
+func TestBlockedResponsesHaveTheirOwnCodes(t *testing.T) {
+	cases := []struct {
+		name     string
+		response geminitest.Response
+		code     string
+	}{
+		{"safety", geminitest.SafetyBlock(), "GEMINI_SAFETY_BLOCKED"},
+		{"prompt", geminitest.PromptBlocked("SAFETY"), "GEMINI_PROMPT_BLOCKED"},
+		{"recitation", geminitest.Finish("RECITATION", ""), "GEMINI_RECITATION_BLOCKED"},
+		{"max tokens", geminitest.Finish("MAX_TOKENS", "texto cortado no meio"), "GEMINI_MAX_TOKENS"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := geminitest.New(t)
+			srv.Enqueue("", tc.response)
+			fs, usage := newFileService(srv.Config())
+
+			requireError(t, process(t, fs, "foto.png", []byte("png")), tc.code)
+
+			if calls := srv.GenerateCalls(); len(calls) != 1 {
+				t.Fatalf("bloqueios não devem ser repetidos: %d chamadas", len(calls))
+			}
+			// A entrada foi cobrada pelo Gemini mesmo sem texto
+			report, err := usage.Report("", "", "tester")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Totals.Requests != 1 || report.Totals.InputTokens != geminitest.PromptTokens {
+				t.Fatalf("consumo da resposta bloqueada não contabilizado: %+v", report.Totals)
+			}
+		})
+	}
+}
+
+func TestShortTextChargesTokens(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetDefault(geminitest.Pages("", "a"))
+	cfg := srv.Config()
+	cfg.PDFChunkPages = 2
+	fs, usage := newFileService(cfg)
+
+	requireError(t, process(t, fs, "relatorio.pdf", geminitest.PDF(4)), "PROCESSING_ERROR")
+
+	// Cada bloco passa da verificação do Gemini, mas o documento junto fica curto demais: o consumo continua valendo
+	report, err := usage.Report("", "", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Totals.InputTokens != 2*geminitest.PromptTokens {
+		t.Fatalf("consumo dos blocos com texto curto não contabilizado: %+v", report.Totals)
+	}
+}
+
+func TestSlowResponseStopsAtRetryBudget(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetDefault(geminitest.Text("resposta que chega tarde demais").After(3 * time.Second))
+	cfg := srv.Config()
+	cfg.GeminiRetryBudget = 300 * time.Millisecond
+	fs, _ := newFileService(cfg)
+
+	start := time.Now()
+	response := process(t, fs, "foto.png", []byte("png"))
+
+	if response.Success {
+		t.Fatal("esperava falha por estouro do orçamento de tempo")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("o orçamento de %v não foi respeitado: %v", cfg.GeminiRetryBudget, elapsed)
+	}
+}
+
+func TestCircuitBreakerSkipsFailingModel(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("gemini-instavel", geminitest.ServerError())
+	cfg := srv.Config()
+	cfg.GeminiModels = []string{"gemini-instavel", "gemini-2.0-flash"}
+	cfg.GeminiRetryMaxAttempts = 1
+	cfg.GeminiBreakerThreshold = 1
+	fs, _ := newFileService(cfg)
+
+	requireSuccess(t, process(t, fs, "a.png", []byte("png")))
+	requireSuccess(t, process(t, fs, "b.png", []byte("png")))
+
+	got := generateModels(srv.GenerateCalls())
+	if strings.Join(got, ",") != "gemini-instavel,gemini-2.0-flash,gemini-2.0-flash" {
+		t.Fatalf("o modelo com circuito aberto não deveria ser chamado: %v", got)
+	}
+	for _, health := range fs.ModelHealth() {
+		if health.Model == "gemini-instavel" && health.State != "open" {
+			t.Fatalf("circuito de gemini-instavel = %s, esperado open", health.State)
+		}
+	}
+}
+
+func TestLargeFilesGoThroughFilesAPI(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.GeminiUploadThreshold = 1024
+	fs, _ := newFileService(cfg)
+
+	requireSuccess(t, process(t, fs, "foto.png", bytes.Repeat([]byte("x"), 4096)))
+
+	calls := srv.GenerateCalls()
+	if len(calls) != 1 || !bytes.Contains(calls[0].Body, []byte("file_uri")) {
+		t.Fatalf("a geração deveria referenciar o arquivo enviado: %s", calls[0].Body)
+	}
+	if files := srv.Files(); len(files) != 0 {
+		t.Fatalf("arquivos enviados deveriam ser apagados após o uso: %+v", files)
+	}
+}
+
+var chunkName = regexp.MustCompile(`_p(\d+)-(\d+)\.pdf`)
+
+func TestSplitsLargePDFIntoChunks(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetResponder(func(call geminitest.Call) geminitest.Response {
+		match := chunkName.FindSubmatch(call.Body)
+		if match == nil {
+			return geminitest.BadRequest("bloco sem intervalo de páginas")
+		}
+		first, _ := strconv.Atoi(string(match[1]))
+		last, _ := strconv.Atoi(string(match[2]))
+		var pages []string
+		for page := first; page <= last; page++ {
+			pages = append(pages, fmt.Sprintf("Conteúdo da página %d", page))
+		}
+		return geminitest.Pages(pages...)
+	})
+	cfg := srv.Config()
+	cfg.PDFChunkPages = 2
+	cfg.PDFChunkConcurrency = 2
+	fs, _ := newFileService(cfg)
+
+	data := requireSuccess(t, process(t, fs, "relatorio.pdf", geminitest.PDF(5)))
+
+	if calls := srv.GenerateCalls(); len(calls) != 3 {
+		t.Fatalf("esperava 3 blocos, houve %d chamadas", len(calls))
+	}
+	if len(data.Pages) != 5 {
+		t.Fatalf("esperava 5 páginas, veio %d", len(data.Pages))
+	}
+	for i, page := range data.Pages {
+		if page.Number != i+1 || page.Text != fmt.Sprintf("Conteúdo da página %d", i+1) {
+			t.Fatalf("página fora de ordem: %+v", page)
+		}
+	}
+	if len(data.Info.Usage) != 1 || data.Info.Usage[0].InputTokens != 3*geminitest.PromptTokens {
+		t.Fatalf("o consumo dos blocos deveria ser somado: %+v", data.Info.Usage)
+	}
+}
+
+func TestPartialChunkFailureKeepsDocument(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetResponder(func(call geminitest.Call) geminitest.Response {
+		if bytes.Contains(call.Body, []byte("_p3-4.pdf")) {
+			return geminitest.SafetyBlock()
+		}
+		return geminitest.Pages("texto do bloco", "mais texto do bloco")
+	})
+	cfg := srv.Config()
+	cfg.PDFChunkPages = 2
+	fs, _ := newFileService(cfg)
+
+	data := requireSuccess(t, process(t, fs, "relatorio.pdf", geminitest.PDF(4)))
+
+	if len(data.FailedChunks) != 1 {
+		t.Fatalf("esperava 1 bloco com falha: %+v", data.FailedChunks)
+	}
+	failure := data.FailedChunks[0]
+	if failure.FirstPage != 3 || failure.LastPage != 4 || failure.Code != "GEMINI_SAFETY_BLOCKED" {
+		t.Fatalf("falha inesperada: %+v", failure)
+	}
+	// O bloco bloqueado também consumiu a entrada
+	if len(data.Info.Usage) != 1 || data.Info.Usage[0].InputTokens != 2*geminitest.PromptTokens {
+		t.Fatalf("consumo inesperado: %+v", data.Info.Usage)
+	}
+}
+
+func TestNativeTextDoesNotCallGemini(t *testing.T) {
+	srv := geminitest.New(t)
+	fs, _ := newFileService(srv.Config())
+
+	data := requireSuccess(t, process(t, fs, "notas.txt", []byte("conteúdo de texto simples")))
+
+	if calls := srv.GenerateCalls(); len(calls) != 0 {
+		t.Fatalf("TXT não deveria chamar o Gemini: %d chamadas", len(calls))
+	}
+	if len(data.Info.Usage) != 1 || data.Info.Usage[0].Model != "native" || !data.Info.Usage[0].Estimated {
+		t.Fatalf("consumo inesperado: %+v", data.Info.Usage)
+	}
+}
+
+func TestUnsupportedFileType(t *testing.T) {
+	srv := geminitest.New(t)
+	fs, _ := newFileService(srv.Config())
+
+	requireError(t, process(t, fs, "planilha.xlsx", []byte("xlsx")), "UNSUPPORTED_FILE_TYPE")
+}
+
+func TestScansFilesBeforeProcessing(t *testing.T) {
+	srv := geminitest.New(t)
+	clamd := clamdtest.New(t)
+	cfg := srv.Config()
+	cfg.ClamdAddress = clamd.Address()
+	fs, _ := newFileService(cfg)
+
+	data := requireSuccess(t, process(t, fs, "notas.txt", []byte("conteúdo limpo")))
+	if data.Text != "conteúdo limpo" {
+		t.Fatalf("o processador deveria receber o arquivo desde o início: %q", data.Text)
+	}
+	if scan := data.Info.Scan; scan == nil || scan.Scanner != "clamd" || scan.Status != "clean" {
+		t.Fatalf("veredito inesperado: %+v", scan)
+	}
+
+	response := process(t, fs, "documento.pdf", []byte(clamdtest.EICAR))
+	requireError(t, response, "MALWARE_DETECTED")
+	if response.Data == nil || response.Data.Info.Scan == nil || response.Data.Info.Scan.Status != "infected" || response.Data.Info.Scan.Signature != clamdtest.Signature {
+		t.Fatalf("a recusa deveria trazer o veredito: %+v", response.Data)
+	}
+	if calls := srv.GenerateCalls(); len(calls) != 0 {
+		t.Fatalf("arquivo infectado não pode chegar ao Gemini: %d chamadas", len(calls))
+	}
+}
+
+func TestScannerUnavailable(t *testing.T) {
+	srv := geminitest.New(t)
+	cfg := srv.Config()
+	cfg.ClamdAddress = "tcp://127.0.0.1:1"
+	cfg.ClamdTimeout = 200 * time.Millisecond
+
+	fs, _ := newFileService(cfg)
+	response := process(t, fs, "notas.txt", []byte("texto"))
+	requireError(t, response, "SCAN_FAILED")
+	if response.Data == nil || response.Data.Info.Scan == nil || response.Data.Info.Scan.Status != "error" {
+		t.Fatalf("a recusa deveria trazer o veredito: %+v", response.Data)
+	}
+
+	cfg.ScanFailOpen = true
+	fs, _ = newFileService(cfg)
+	data := requireSuccess(t, process(t, fs, "notas.txt", []byte("texto")))
+	if data.Info.Scan == nil || data.Info.Scan.Status != "error" {
+		t.Fatalf("veredito inesperado: %+v", data.Info.Scan)
+	}
+}
+
+func TestScanningDisabledByDefault(t *testing.T) {
+	srv := geminitest.New(t)
+	fs, _ := newFileService(srv.Config())
+
+	data := requireSuccess(t, process(t, fs, "notas.txt", []byte("texto")))
+	if scan := data.Info.Scan; scan == nil || scan.Scanner != "none" || scan.Status != "not_scanned" {
+		t.Fatalf("veredito inesperado: %+v", scan)
+	}
+}
+
+func TestTokenizesPIIAndKeepsPageOffsets(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.Enqueue("", geminitest.Pages("Titular CPF 529.982.247-25", "Contato: ana@exemplo.com"))
+	fs, _ := newFileService(srv.Config())
+
+	response, err := fs.ProcessFile(context.Background(), bytes.NewReader(geminitest.PDF(2)), "cadastro.pdf", 1024, "tester",
+		services.ProcessOptions{PIIMode: pii.ModeTokenize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := requireSuccess(t, response)
+
+	if data.PII == nil || data.PII.Mode != "tokenize" || len(data.PII.Findings) != 2 {
+		t.Fatalf("achados inesperados: %+v", data.PII)
+	}
+	if strings.Contains(data.Text, "529.982.247-25") || strings.Contains(data.Text, "ana@") {
+		t.Fatalf("dados pessoais no texto: %q", data.Text)
+	}
+	runes := []rune(data.Text)
+	for _, page := range data.Pages {
+		if string(runes[page.StartOffset:page.EndOffset]) != page.Text {
+			t.Fatalf("offsets da página %d não batem com o texto: %+v", page.Number, page)
+		}
+	}
+	if email := data.PII.Findings[1]; email.Type != "email" || email.Page != 2 || string(runes[email.Start:email.End]) != email.Replacement {
+		t.Fatalf("achado de e-mail inesperado: %+v", email)
+	}
+
+	// Sem opção na requisição vale PII_MODE (padrão off)
+	srv.Enqueue("", geminitest.Pages("CPF 529.982.247-25"))
+	data = requireSuccess(t, process(t, fs, "cadastro.pdf", geminitest.PDF(1)))
+	if data.PII != nil || !strings.Contains(data.Text, "529.982.247-25") {
+		t.Fatalf("PII_MODE=off não deveria alterar o texto: %+v", data.PII)
+	}
+}
+
+// invoiceXML NF-e com destinatário pessoa física
+const invoiceXML = `<?xml version="1.0" encoding="UTF-8"?>
+<nfeProc xmlns="http://www.portalfiscal.inf.br/nfe"><NFe><infNFe Id="NFe35240111222333000181550010000012341000012340" versao="4.00">
+<ide><cUF>35</cUF><natOp>Venda</natOp><mod>55</mod><serie>1</serie><nNF>1234</nNF><dhEmi>2024-01-15T10:00:00-03:00</dhEmi></ide>
+<emit><CNPJ>11222333000181</CNPJ><xNome>Loja Exemplo Ltda</xNome><enderEmit><xLgr>Avenida Paulista</xLgr><nro>1000</nro><xMun>São Paulo</xMun><UF>SP</UF><CEP>01310100</CEP></enderEmit></emit>
+<dest><CPF>52998224725</CPF><xNome>Ana Souza</xNome><enderDest><xLgr>Rua das Flores</xLgr><nro>123</nro><xBairro>Centro</xBairro><xMun>Campinas</xMun><UF>SP</UF><CEP>13010000</CEP></enderDest></dest>
+<det nItem="1"><prod><cProd>1</cProd><xProd>Caneta</xProd><qCom>2</qCom><vUnCom>5.00</vUnCom><vProd>10.00</vProd></prod></det>
+<total><ICMSTot><vProd>10.00</vProd><vNF>10.00</vNF></ICMSTot></total>
+</infNFe></NFe></nfeProc>`
+
+func TestPIIModeCoversFiscalDocument(t *testing.T) {
+	srv := geminitest.New(t)
+	fs, _ := newFileService(srv.Config())
+	processWith := func(mode pii.Mode) *models.Data {
+		response, err := fs.ProcessFile(context.Background(), strings.NewReader(invoiceXML), "nota.xml", int64(len(invoiceXML)), "tester",
+			services.ProcessOptions{PIIMode: mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return requireSuccess(t, response)
+	}
+
+	data := processWith(pii.ModeMask)
+	recipient := data.Document.Recipient
+	if recipient == nil || recipient.Document != "***********" || recipient.Name != "*** *****" || recipient.Address.Street != "*** *** ******" {
+		t.Fatalf("destinatário não mascarado: %+v %+v", recipient, recipient.Address)
+	}
+	if recipient.Address.City != "Campinas" || recipient.DocumentType != "CPF" {
+		t.Fatalf("cidade e tipo de documento deveriam ser mantidos: %+v", recipient)
+	}
+	if issuer := data.Document.Issuer; issuer.Document != "**************" || issuer.Name != "Loja Exemplo Ltda" {
+		t.Fatalf("emitente: CNPJ deveria ser mascarado e a razão social mantida: %+v", issuer)
+	}
+
+	data = processWith(pii.ModeTokenize)
+	if token := data.Document.Recipient.Document; !strings.HasPrefix(token, "[CPF_") || !strings.Contains(data.Text, token) {
+		t.Fatalf("o token do CPF no documento deveria ser o mesmo do texto: %q em %q", token, data.Text)
+	}
+
+	data = processWith(pii.ModeDetect)
+	if data.Document.Recipient.Document != "52998224725" || data.Document.Recipient.Name != "Ana Souza" {
+		t.Fatalf("detect não deveria alterar o documento: %+v", data.Document.Recipient)
+	}
+}
+
+func TestClientDisconnectStopsGeminiCalls(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetDefault(geminitest.Unavailable())
+	cfg := srv.Config()
+	cfg.GeminiRetryBaseDelay = 2 * time.Second
+	cfg.GeminiRetryMaxDelay = 2 * time.Second
+	fs, _ := newFileService(cfg)
+
+	// O cliente desiste durante a espera entre as tentativas
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	response, err := fs.ProcessFile(ctx, bytes.NewReader([]byte("png")), "foto.png", 3, "tester", services.ProcessOptions{})
+	if err != nil {
+		t.Fatalf("ProcessFile: %v", err)
+	}
+
+	if response.Success {
+		t.Fatal("esperava falha com a requisição cancelada")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("a espera entre tentativas ignorou o cancelamento: %v", elapsed)
+	}
+	if calls := srv.GenerateCalls(); len(calls) != 1 {
+		t.Fatalf("esperava 1 chamada antes do cancelamento, houve %d", len(calls))
+	}
+}
+
+func TestClientDisconnectCancelsGeminiCall(t *testing.T) {
+	srv := geminitest.New(t)
+	srv.SetDefault(geminitest.Text("resposta lenta").After(3 * time.Second))
+	fs, _ := newFileService(srv.Config())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	response, err := fs.ProcessFile(ctx, bytes.NewReader([]byte("png")), "foto.png", 3, "tester", services.ProcessOptions{})
+	if err != nil {
+		t.Fatalf("ProcessFile: %v", err)
+	}
+
+	if response.Success {
+		t.Fatal("esperava falha com a requisição cancelada")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("a chamada em andamento não foi cancelada: %v", elapsed)
+	}
+	if calls := srv.GenerateCalls(); len(calls) != 1 {
+		t.Fatalf("cancelamento não deve gerar novas tentativas nem fallback: %d chamadas", len(calls))
+	}
+}
+
+func TestArtifactStoreCleansUpWithoutNewUploads(t *testing.T) {
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, strings.Repeat("ab", 16))
+	unrelated := filepath.Join(dir, "notas.txt")
+	for _, path := range []string{orphan, unrelated} {
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := services.NewArtifactStore(dir, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewArtifactStore: %v", err)
+	}
+	defer store.Close()
+
+	// Arquivos de uma execução anterior somem ao iniciar; o que não é artefato fica
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("artefato órfão deveria ser removido na inicialização: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("arquivo alheio não deveria ser removido: %v", err)
+	}
+
+	artifact, err := store.Put("acme", "copia.png", "image/png", []byte("png"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	path := filepath.Join(dir, artifact.ID)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("artefato expirado não foi removido sem novos uploads")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend-fileprocessing/internal/requestid"
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// uploadChunkSize tamanho de cada bloco do upload resumível (múltiplo de 256 KiB)
+const uploadChunkSize = 8 * 1024 * 1024
+
+// filesAPIVersion versão da API em que a Files API está disponível
+const filesAPIVersion = "v1beta"
+
+// uploadMaxResumes quantas vezes um upload interrompido é retomado antes de desistir
+const uploadMaxResumes = 3
+
+// GeminiFileData referência a um arquivo enviado pela Files API
+type GeminiFileData struct {
+	MimeType string `json:"mime_type"`
+	FileURI  string `json:"file_uri"`
+}
+
+// GeminiFile metadados de um arquivo na Files API
+type GeminiFile struct {
+	Name     string `json:"name"`
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	State    string `json:"state"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// filePart monta a parte do arquivo para o generateContent: base64 inline para arquivos pequenos
+// ou referência via Files API acima do limite configurado. key é a chave que enviou o arquivo
+// (nil para inline) e deve ser usada no generateContent. cleanup remove o arquivo enviado.
+func (s *GeminiService) filePart(ctx context.Context, data []byte, mimeType, filename string) (part GeminiPart, key *apiKey, cleanup func(), err error) {
+	if s.uploadThreshold > 0 && int64(len(data)) > s.uploadThreshold {
+		slog.InfoContext(ctx, "arquivo acima do limite inline, enviando pela Files API", "bytes", len(data), "threshold_bytes", s.uploadThreshold)
+		key, err := s.keys.acquire()
+		if err != nil {
+			return GeminiPart{}, nil, nil, err
+		}
+		uploadCtx, span := tracing.Start(ctx, "gemini.uploadFile", trace.WithAttributes(attribute.Int("file.size", len(data))))
+		file, err := s.uploadFile(uploadCtx, key, data, mimeType, filename)
+		tracing.End(span, err)
+		if err != nil {
+			return GeminiPart{}, nil, nil, fmt.Errorf("erro ao enviar arquivo pela Files API: %v", err)
+		}
+		part = GeminiPart{FileData: &GeminiFileData{MimeType: mimeType, FileURI: file.URI}}
+		return part, key, func() { s.deleteFile(ctx, key, file.Name) }, nil
+	}
+
+	// Converter para base64
+	base64Content := base64.StdEncoding.EncodeToString(data)
+	base64Size := len(base64Content)
+	slog.DebugContext(ctx, "arquivo convertido para base64", "base64_chars", base64Size)
+
+	// Verificar tamanho (Gemini tem limite de ~20MB por requisição inline)
+	maxSize := 20 * 1024 * 1024 // 20MB
+	if base64Size > maxSize {
+		return GeminiPart{}, nil, nil, fmt.Errorf("arquivo muito grande para envio inline ao Gemini: %.2f MB (limite: 20MB)", float64(base64Size)/1024/1024)
+	}
+
+	part = GeminiPart{InlineData: &GeminiInlineData{MimeType: mimeType, Data: base64Content}}
+	return part, nil, func() {}, nil
+}
+
+// uploadFile envia o arquivo pelo protocolo resumível da Files API, retomando blocos que falharem
+func (s *GeminiService) uploadFile(ctx context.Context, key *apiKey, data []byte, mimeType, displayName string) (*GeminiFile, error) {
+	uploadURL, err := s.startUpload(ctx, key, len(data), mimeType, displayName)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, resumes := 0, 0
+	for {
+		end := offset + uploadChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		final := end == len(data)
+
+		file, err := s.uploadChunk(ctx, uploadURL, data[offset:end], offset, final)
+		if err != nil {
+			if resumes >= uploadMaxResumes {
+				return nil, err
+			}
+			resumes++
+			received, qerr := s.queryUpload(ctx, uploadURL)
+			if qerr != nil {
+				return nil, fmt.Errorf("%v (falha ao consultar upload: %v)", err, qerr)
+			}
+			slog.WarnContext(ctx, "falha no upload, retomando", "error", err, "offset", received, "resume", resumes, "max_resumes", uploadMaxResumes)
+			offset = received
+			continue
+		}
+
+		if final {
+			slog.InfoContext(ctx, "arquivo enviado pela Files API", "gemini_file", file.Name)
+			return s.waitFileActive(ctx, key, file)
+		}
+		offset = end
+	}
+}
+
+// startUpload inicia a sessão resumível e retorna a URL de upload
+func (s *GeminiService) startUpload(ctx context.Context, key *apiKey, size int, mimeType, displayName string) (string, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"file": map[string]string{"display_name": displayName},
+	})
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar JSON: %v", err)
+	}
+
+	req, err := newFilesRequest(ctx, "POST", fmt.Sprintf("%s/upload/%s/files", s.baseURL, filesAPIVersion), bytes.NewReader(metadata))
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.authorize(req, key)
+	req.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	req.Header.Set("X-Goog-Upload-Command", "start")
+	req.Header.Set("X-Goog-Upload-Header-Content-Length", strconv.Itoa(size))
+	req.Header.Set("X-Goog-Upload-Header-Content-Type", mimeType)
+
+	resp, err := s.filesClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("erro ao iniciar upload: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("erro ao iniciar upload (status %d): %s", resp.StatusCode, string(body))
+	}
+	uploadURL := resp.Header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return "", fmt.Errorf("resposta da Files API sem X-Goog-Upload-URL")
+	}
+	return uploadURL, nil
+}
+
+// uploadChunk envia um bloco; no bloco final a resposta traz os metadados do arquivo
+func (s *GeminiService) uploadChunk(ctx context.Context, uploadURL string, chunk []byte, offset int, final bool) (*GeminiFile, error) {
+	req, err := newFilesRequest(ctx, "POST", uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	command := "upload"
+	if final {
+		command = "upload, finalize"
+	}
+	req.Header.Set("X-Goog-Upload-Command", command)
+	req.Header.Set("X-Goog-Upload-Offset", strconv.Itoa(offset))
+	req.ContentLength = int64(len(chunk))
+
+	resp, err := s.filesClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao enviar bloco: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("erro ao enviar bloco (status %d): %s", resp.StatusCode, string(body))
+	}
+	if !final {
+		return nil, nil
+	}
+
+	var uploaded struct {
+		File GeminiFile `json:"file"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return nil, fmt.Errorf("erro ao parsear resposta do upload: %v", err)
+	}
+	if uploaded.File.Name == "" || uploaded.File.URI == "" {
+		return nil, fmt.Errorf("resposta do upload sem nome/URI do arquivo")
+	}
+	return &uploaded.File, nil
+}
+
+// queryUpload consulta quantos bytes o servidor já recebeu
+func (s *GeminiService) queryUpload(ctx context.Context, uploadURL string) (int, error) {
+	req, err := newFilesRequest(ctx, "POST", uploadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Goog-Upload-Command", "query")
+
+	resp, err := s.filesClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return strconv.Atoi(resp.Header.Get("X-Goog-Upload-Size-Received"))
+}
+
+// waitFileActive aguarda o processamento do arquivo (state PROCESSING -> ACTIVE)
+func (s *GeminiService) waitFileActive(ctx context.Context, key *apiKey, file *GeminiFile) (*GeminiFile, error) {
+	deadline := time.Now().Add(2 * time.Minute)
+	for file.State == "PROCESSING" {
+		if time.Now().After(deadline) {
+			s.deleteFile(ctx, key, file.Name)
+			return nil, fmt.Errorf("arquivo %s não ficou disponível a tempo na Files API", file.Name)
+		}
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			s.deleteFile(ctx, key, file.Name)
+			return nil, err
+		}
+
+		current, err := s.getFile(ctx, key, file.Name)
+		if err != nil {
+			s.deleteFile(ctx, key, file.Name)
+			return nil, err
+		}
+		file = current
+	}
+
+	if file.State == "FAILED" {
+		s.deleteFile(ctx, key, file.Name)
+		msg := "motivo desconhecido"
+		if file.Error != nil {
+			msg = file.Error.Message
+		}
+		return nil, fmt.Errorf("Files API falhou ao processar o arquivo: %s", msg)
+	}
+	return file, nil
+}
+
+// getFile consulta os metadados de um arquivo ("files/abc123")
+func (s *GeminiService) getFile(ctx context.Context, key *apiKey, name string) (*GeminiFile, error) {
+	req, err := newFilesRequest(ctx, "GET", fmt.Sprintf("%s/%s/%s", s.baseURL, filesAPIVersion, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.authorize(req, key)
+
+	resp, err := s.filesClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar arquivo: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("erro ao consultar arquivo (status %d): %s", resp.StatusCode, string(body))
+	}
+	var file GeminiFile
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, fmt.Errorf("erro ao parsear arquivo: %v", err)
+	}
+	return &file, nil
+}
+
+// deleteFile remove o arquivo da Files API após o uso (falhas apenas são registradas; o Gemini expira em 48h).
+// A remoção acontece mesmo com a requisição já cancelada.
+func (s *GeminiService) deleteFile(ctx context.Context, key *apiKey, name string) {
+	req, err := newFilesRequest(context.WithoutCancel(ctx), "DELETE", fmt.Sprintf("%s/%s/%s", s.baseURL, filesAPIVersion, name), nil)
+	if err != nil {
+		slog.WarnContext(ctx, "erro ao remover arquivo da Files API", "gemini_file", name, "error", err)
+		return
+	}
+	s.authorize(req, key)
+
+	resp, err := s.filesClient().Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "erro ao remover arquivo da Files API", "gemini_file", name, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		slog.WarnContext(ctx, "erro ao remover arquivo da Files API", "gemini_file", name, "status", resp.StatusCode)
+		return
+	}
+	slog.DebugContext(ctx, "arquivo removido da Files API", "gemini_file", name)
+}
+
+// newFilesRequest cria uma requisição da Files API ligada ao contexto, com o trace e o request ID
+func newFilesRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	tracing.Inject(ctx, req.Header)
+	requestid.Inject(ctx, req.Header)
+	return req, nil
+}
+
+// filesClient cliente HTTP das chamadas da Files API (uploads grandes podem demorar)
+func (s *GeminiService) filesClient() *http.Client {
+	return &http.Client{Timeout: 5 * time.Minute}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// Estados do circuit breaker de um modelo
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// modelHealth saúde de um modelo em uma versão da API
+type modelHealth struct {
+	model               string
+	apiVersion          string
+	state               string
+	consecutiveFailures int
+	successes           int64
+	failures            int64
+	lastError           string
+	lastSuccessAt       time.Time
+	lastFailureAt       time.Time
+	openUntil           time.Time
+	// probing indica que uma requisição de teste (half-open) está em andamento
+	probing bool
+}
+
+// healthTracker acompanha a saúde de cada modelo/versão em memória e abre o circuito
+// após falhas consecutivas, evitando percorrer modelos sabidamente quebrados
+type healthTracker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	models    map[string]*modelHealth
+	// lastWorking último par modelo/versão que respondeu com sucesso (tentado primeiro)
+	lastWorking *modelCandidate
+}
+
+// modelCandidate par modelo/versão da API a tentar
+type modelCandidate struct {
+	model      string
+	apiVersion string
+}
+
+func newHealthTracker(threshold int, cooldown time.Duration) *healthTracker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &healthTracker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		models:    make(map[string]*modelHealth),
+	}
+}
+
+func (t *healthTracker) entry(c modelCandidate) *modelHealth {
+	key := c.apiVersion + "/" + c.model
+	h, ok := t.models[key]
+	if !ok {
+		h = &modelHealth{model: c.model, apiVersion: c.apiVersion, state: breakerClosed}
+		t.models[key] = h
+	}
+	return h
+}
+
+// order devolve os candidatos disponíveis com o último que funcionou na frente e depois os
+// saudáveis (mantendo a ordem de preferência dentro de cada grupo). Circuitos abertos ficam
+// de fora até o fim do cooldown, quando uma única requisição de teste é liberada.
+// skipped conta os candidatos descartados.
+func (t *healthTracker) order(candidates []modelCandidate) (ordered []modelCandidate, skipped int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	rank := make(map[modelCandidate]int, len(candidates))
+	for _, c := range candidates {
+		h := t.entry(c)
+		if h.state == breakerOpen && !now.Before(h.openUntil) {
+			h.state = breakerHalfOpen
+		}
+
+		switch {
+		case h.state == breakerOpen, h.state == breakerHalfOpen && h.probing:
+			skipped++
+			continue
+		case h.state == breakerHalfOpen:
+			h.probing = true
+			rank[c] = 3
+		case t.lastWorking != nil && *t.lastWorking == c:
+			rank[c] = -1
+		case h.consecutiveFailures == 0 && h.successes > 0:
+			rank[c] = 0
+		case h.consecutiveFailures == 0:
+			rank[c] = 1
+		default:
+			rank[c] = 2
+		}
+		ordered = append(ordered, c)
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank[ordered[i]] < rank[ordered[j]]
+	})
+	return ordered, skipped
+}
+
+// release libera a requisição de teste reservada por order sem registrar resultado
+// (candidato não chegou a ser tentado)
+func (t *healthTracker) release(c modelCandidate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entry(c).probing = false
+}
+
+// recordSuccess fecha o circuito do modelo
+func (t *healthTracker) recordSuccess(c modelCandidate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.entry(c)
+	h.state = breakerClosed
+	h.consecutiveFailures = 0
+	h.successes++
+	h.lastSuccessAt = time.Now()
+	h.probing = false
+	t.lastWorking = &c
+}
+
+// recordFailure registra uma falha do modelo; ao atingir o limite (ou falhar o teste
+// half-open) o circuito é aberto por cooldown. Retorna true quando o circuito abriu agora.
+func (t *healthTracker) recordFailure(c modelCandidate, err error) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.entry(c)
+	h.consecutiveFailures++
+	h.failures++
+	h.lastError = err.Error()
+	h.lastFailureAt = time.Now()
+	h.probing = false
+
+	if h.state == breakerHalfOpen || h.consecutiveFailures >= t.threshold {
+		opened := h.state != breakerOpen
+		h.state = breakerOpen
+		h.openUntil = h.lastFailureAt.Add(t.cooldown)
+		return opened
+	}
+	return false
+}
+
+// snapshot estado atual de todos os modelos já vistos, para exposição no /status
+func (t *healthTracker) snapshot() []models.ModelHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	out := make([]models.ModelHealth, 0, len(t.models))
+	for _, h := range t.models {
+		state := h.state
+		if state == breakerOpen && !now.Before(h.openUntil) {
+			state = breakerHalfOpen
+		}
+		item := models.ModelHealth{
+			Model:               h.model,
+			APIVersion:          h.apiVersion,
+			State:               state,
+			ConsecutiveFailures: h.consecutiveFailures,
+			Successes:           h.successes,
+			Failures:            h.failures,
+			LastError:           h.lastError,
+		}
+		if !h.lastSuccessAt.IsZero() {
+			at := h.lastSuccessAt
+			item.LastSuccessAt = &at
+		}
+		if !h.lastFailureAt.IsZero() {
+			at := h.lastFailureAt
+			item.LastFailureAt = &at
+		}
+		if state == breakerOpen {
+			until := h.openUntil
+			item.OpenUntil = &until
+		}
+		out = append(out, item)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].APIVersion != out[j].APIVersion {
+			return out[i].APIVersion > out[j].APIVersion
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
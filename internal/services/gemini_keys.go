@@ -0,0 +1,201 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend-fileprocessing/internal/models"
+)
+
+// Estratégias de escolha da chave de API
+const (
+	keySelectionRoundRobin = "round-robin"
+	keySelectionLeastUsed  = "least-used"
+)
+
+// defaultKeyCooldown pausa de uma chave que recebeu 429 sem retryDelay
+const defaultKeyCooldown = time.Minute
+
+// keysExhaustedError todas as chaves estão em pausa por cota; trocar de modelo não adianta
+type keysExhaustedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *keysExhaustedError) Error() string {
+	return fmt.Sprintf("todas as chaves de API do Gemini estão em pausa por cota excedida. Tente novamente em %s", e.RetryAfter.Round(time.Second))
+}
+
+// apiKey chave de API do pool com seus contadores de uso
+type apiKey struct {
+	value string
+	// id identificador seguro para logs e /status (nunca a chave em si)
+	id            string
+	requests      int64
+	successes     int64
+	rateLimited   int64
+	failures      int64
+	cooldownUntil time.Time
+	lastUsedAt    time.Time
+}
+
+// keyPool conjunto de chaves usado em rodízio; chaves que recebem 429 ficam em pausa até o retryDelay
+type keyPool struct {
+	mu        sync.Mutex
+	keys      []*apiKey
+	selection string
+	next      int
+}
+
+func newKeyPool(values []string, selection string) *keyPool {
+	pool := &keyPool{selection: selection}
+	if selection != keySelectionLeastUsed {
+		pool.selection = keySelectionRoundRobin
+	}
+	for i, value := range values {
+		sum := sha256.Sum256([]byte(value))
+		pool.keys = append(pool.keys, &apiKey{
+			value: value,
+			id:    fmt.Sprintf("key-%d (%s)", i+1, hex.EncodeToString(sum[:])[:8]),
+		})
+	}
+	return pool
+}
+
+// size quantidade de chaves configuradas
+func (p *keyPool) size() int {
+	return len(p.keys)
+}
+
+// acquire escolhe uma chave fora de pausa conforme a estratégia e já conta a requisição.
+// Com todas em pausa, retorna erro com o tempo até a primeira ser liberada.
+func (p *keyPool) acquire() (*apiKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.keys) == 0 {
+		return nil, fmt.Errorf("nenhuma chave de API do Gemini configurada")
+	}
+
+	now := time.Now()
+	var chosen *apiKey
+	switch p.selection {
+	case keySelectionLeastUsed:
+		for _, key := range p.keys {
+			if now.Before(key.cooldownUntil) {
+				continue
+			}
+			if chosen == nil || key.requests < chosen.requests {
+				chosen = key
+			}
+		}
+	default:
+		for i := 0; i < len(p.keys); i++ {
+			key := p.keys[(p.next+i)%len(p.keys)]
+			if now.Before(key.cooldownUntil) {
+				continue
+			}
+			chosen = key
+			p.next = (p.next + i + 1) % len(p.keys)
+			break
+		}
+	}
+
+	if chosen == nil {
+		return nil, &keysExhaustedError{RetryAfter: p.soonestAvailable(now).Sub(now)}
+	}
+	chosen.requests++
+	chosen.lastUsedAt = now
+	return chosen, nil
+}
+
+// use conta uma requisição com uma chave já escolhida (fixada pela Files API)
+func (p *keyPool) use(key *apiKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key.requests++
+	key.lastUsedAt = time.Now()
+}
+
+// hasAvailable indica se alguma chave está fora de pausa
+func (p *keyPool) hasAvailable() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, key := range p.keys {
+		if !now.Before(key.cooldownUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *keyPool) soonestAvailable(now time.Time) time.Time {
+	soonest := time.Time{}
+	for _, key := range p.keys {
+		if soonest.IsZero() || key.cooldownUntil.Before(soonest) {
+			soonest = key.cooldownUntil
+		}
+	}
+	if soonest.Before(now) {
+		return now
+	}
+	return soonest
+}
+
+// recordSuccess conta uma resposta 200
+func (p *keyPool) recordSuccess(key *apiKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key.successes++
+}
+
+// recordRateLimited pausa a chave até o retryDelay informado pelo Gemini (ou o padrão)
+func (p *keyPool) recordRateLimited(key *apiKey, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = defaultKeyCooldown
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key.rateLimited++
+	if until := time.Now().Add(retryAfter); until.After(key.cooldownUntil) {
+		key.cooldownUntil = until
+	}
+}
+
+// recordFailure conta uma falha que não é de cota
+func (p *keyPool) recordFailure(key *apiKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key.failures++
+}
+
+// snapshot contadores de uso de cada chave, para exposição no /status
+func (p *keyPool) snapshot() []models.APIKeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	out := make([]models.APIKeyUsage, 0, len(p.keys))
+	for _, key := range p.keys {
+		usage := models.APIKeyUsage{
+			ID:          key.id,
+			Requests:    key.requests,
+			Successes:   key.successes,
+			RateLimited: key.rateLimited,
+			Failures:    key.failures,
+		}
+		if !key.lastUsedAt.IsZero() {
+			at := key.lastUsedAt
+			usage.LastUsedAt = &at
+		}
+		if now.Before(key.cooldownUntil) {
+			until := key.cooldownUntil
+			usage.CooldownUntil = &until
+		}
+		out = append(out, usage)
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"backend-fileprocessing/internal/metrics"
+)
+
+// defaultModels lista padrão de modelos para tentar (em ordem de preferência) quando a listagem falha
+var defaultModels = []string{
+	"gemini-1.5-flash-latest", // Versão latest
+	"gemini-1.5-pro-latest",   // Versão latest
+	"gemini-1.5-flash",        // Versão estável
+	"gemini-1.5-pro",          // Versão estável
+	"gemini-pro",              // Modelo básico
+	"gemini-1.0-pro",          // Modelo mais antigo
+	"gemini-1.5-flash-002",    // Versão específica mais recente
+	"gemini-1.5-pro-002",      // Versão específica mais recente
+}
+
+// errNoModelsListed listagem sem nenhum modelo utilizável
+var errNoModelsListed = errors.New("nenhum modelo Gemini utilizável na listagem")
+
+// modelsRetryInterval tempo em que a lista padrão substitui a listagem depois de uma falha,
+// antes de uma nova tentativa
+const modelsRetryInterval = time.Minute
+
+// modelCatalog cache da lista de modelos descoberta via GET /models. Dentro do TTL a lista é
+// reutilizada; depois dele a lista antiga continua servindo enquanto uma atualização roda em segundo plano.
+// Uma única busca roda por vez, compartilhada por todos os chamadores.
+type modelCatalog struct {
+	mu        sync.Mutex
+	fetch     func(ctx context.Context) ([]string, error)
+	ttl       time.Duration
+	fallback  []string
+	models    []string
+	fetchedAt time.Time
+	failedAt  time.Time
+	// loading fechado quando a busca em andamento termina (nil sem busca)
+	loading chan struct{}
+}
+
+func newModelCatalog(fetch func(ctx context.Context) ([]string, error), ttl time.Duration, fallback []string) *modelCatalog {
+	return &modelCatalog{fetch: fetch, ttl: ttl, fallback: fallback}
+}
+
+// fallbackModels lista padrão com o modelo configurado na frente
+func fallbackModels(defaultModel string) []string {
+	if defaultModel == "" {
+		return defaultModels
+	}
+	models := []string{defaultModel}
+	for _, model := range defaultModels {
+		if model != defaultModel {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// get devolve a lista de modelos em cache, esperando a busca apenas se ainda não há nenhuma
+// lista. Com a busca falhando, devolve a lista padrão (fallback) sem tentar de novo por
+// modelsRetryInterval.
+func (c *modelCatalog) get(ctx context.Context) []string {
+	c.mu.Lock()
+	if len(c.models) > 0 {
+		metrics.CacheHit("gemini_models", true)
+		models := c.models
+		if time.Since(c.fetchedAt) >= c.ttl {
+			c.startLoad(ctx)
+		}
+		c.mu.Unlock()
+		return models
+	}
+	if !c.failedAt.IsZero() && time.Since(c.failedAt) < modelsRetryInterval {
+		c.mu.Unlock()
+		metrics.CacheHit("gemini_models", true)
+		return c.fallback
+	}
+	loading := c.startLoad(ctx)
+	c.mu.Unlock()
+
+	metrics.CacheHit("gemini_models", false)
+	select {
+	case <-loading:
+	case <-ctx.Done():
+		return c.fallback
+	}
+	c.mu.Lock()
+	models := c.models
+	c.mu.Unlock()
+	if len(models) == 0 {
+		slog.WarnContext(ctx, "não foi possível listar modelos, usando a lista padrão")
+		return c.fallback
+	}
+	return models
+}
+
+// warmUp dispara a primeira busca em segundo plano
+func (c *modelCatalog) warmUp() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.models) == 0 {
+		c.startLoad(context.Background())
+	}
+}
+
+// startLoad dispara a busca em segundo plano, se nenhuma está em andamento, e devolve o canal
+// fechado quando ela termina; chamado com c.mu travado. A busca não é cancelada junto com a
+// requisição que a disparou, pois outras podem estar esperando por ela.
+func (c *modelCatalog) startLoad(ctx context.Context) chan struct{} {
+	if c.loading == nil {
+		c.loading = make(chan struct{})
+		go c.refresh(context.WithoutCancel(ctx), c.loading)
+	}
+	return c.loading
+}
+
+func (c *modelCatalog) refresh(ctx context.Context, done chan struct{}) {
+	if _, err := c.load(ctx); err != nil {
+		slog.WarnContext(ctx, "atualização da lista de modelos falhou", "error", err)
+	}
+	c.mu.Lock()
+	c.loading = nil
+	c.mu.Unlock()
+	close(done)
+}
+
+// load busca a lista na API e atualiza o cache
+func (c *modelCatalog) load(ctx context.Context) ([]string, error) {
+	models, err := c.fetch(ctx)
+	if err == nil && len(models) == 0 {
+		err = errNoModelsListed
+	}
+	if err != nil {
+		c.mu.Lock()
+		c.failedAt = time.Now()
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "modelos disponíveis encontrados", "models", models)
+	c.mu.Lock()
+	c.models = models
+	c.fetchedAt = time.Now()
+	c.failedAt = time.Time{}
+	c.mu.Unlock()
+	return models, nil
+}
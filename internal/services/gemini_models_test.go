@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestModelCatalogSharesFailedLoad(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	catalog := newModelCatalog(func(ctx context.Context) ([]string, error) {
+		fetches.Add(1)
+		<-release
+		return nil, errors.New("listagem indisponível")
+	}, time.Hour, []string{"padrao"})
+
+	// Chamadores simultâneos esperam a mesma busca
+	var wg sync.WaitGroup
+	results := make([][]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = catalog.get(context.Background())
+		}(i)
+	}
+	for deadline := time.Now().Add(time.Second); fetches.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for _, models := range results {
+		if !reflect.DeepEqual(models, []string{"padrao"}) {
+			t.Fatalf("esperava a lista padrão, veio %v", models)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("esperava 1 busca compartilhada, houve %d", fetches.Load())
+	}
+
+	// A falha fica em cache: sem nova busca até modelsRetryInterval
+	catalog.get(context.Background())
+	if fetches.Load() != 1 {
+		t.Fatalf("falha recente não deveria disparar nova busca: %d", fetches.Load())
+	}
+	catalog.mu.Lock()
+	catalog.failedAt = time.Now().Add(-modelsRetryInterval)
+	catalog.mu.Unlock()
+	catalog.get(context.Background())
+	if fetches.Load() != 2 {
+		t.Fatalf("esperava nova tentativa depois do intervalo, houve %d buscas", fetches.Load())
+	}
+}
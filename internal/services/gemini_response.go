@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// GeminiResponseError resposta 200 do Gemini sem texto utilizável (bloqueio, truncamento, vazia).
+// Code vai direto para o código de erro da API.
+type GeminiResponseError struct {
+	Code    string
+	Message string
+	// Reason finishReason ou blockReason devolvido pelo Gemini
+	Reason string
+	// Categories categorias de segurança que causaram o bloqueio
+	Categories []string
+	Details    string
+}
+
+func (e *GeminiResponseError) Error() string {
+	if e.Reason == "" {
+		return e.Message
+	}
+	if len(e.Categories) > 0 {
+		return fmt.Sprintf("%s (motivo: %s; categorias: %s)", e.Message, e.Reason, strings.Join(e.Categories, ", "))
+	}
+	return fmt.Sprintf("%s (motivo: %s)", e.Message, e.Reason)
+}
+
+// ErrorCode código do erro para a resposta da API
+func (e *GeminiResponseError) ErrorCode() string {
+	return e.Code
+}
+
+// newPromptBlockedError entrada (arquivo + prompt) recusada antes da geração
+func newPromptBlockedError(feedback *GeminiPromptFeedback) *GeminiResponseError {
+	return &GeminiResponseError{
+		Code:       "GEMINI_PROMPT_BLOCKED",
+		Message:    "o Gemini recusou processar o arquivo",
+		Reason:     feedback.BlockReason,
+		Categories: blockedCategories(feedback.SafetyRatings),
+		Details:    "O conteúdo do arquivo foi bloqueado pelos filtros do Gemini. Revise GEMINI_SAFETY_SETTINGS se o bloqueio for indevido",
+	}
+}
+
+// finishReasonError converte um finishReason diferente de STOP em erro. Motivos sem código
+// próprio só viram erro quando não há texto.
+func finishReasonError(ctx context.Context, candidate GeminiCandidate, textLength int) error {
+	reason := candidate.FinishReason
+	switch reason {
+	case "", "STOP", "FINISH_REASON_UNSPECIFIED":
+		return nil
+	case "MAX_TOKENS":
+		return &GeminiResponseError{
+			Code:    "GEMINI_MAX_TOKENS",
+			Message: "resposta do Gemini truncada pelo limite de tokens de saída",
+			Reason:  reason,
+			Details: "Aumente GEMINI_MAX_OUTPUT_TOKENS ou reduza PDF_CHUNK_PAGES para enviar menos páginas por chamada",
+		}
+	case "SAFETY", "IMAGE_SAFETY":
+		return &GeminiResponseError{
+			Code:       "GEMINI_SAFETY_BLOCKED",
+			Message:    "resposta do Gemini bloqueada pelos filtros de segurança",
+			Reason:     reason,
+			Categories: blockedCategories(candidate.SafetyRatings),
+			Details:    "Revise GEMINI_SAFETY_SETTINGS se o bloqueio for indevido",
+		}
+	case "RECITATION":
+		return &GeminiResponseError{
+			Code:    "GEMINI_RECITATION_BLOCKED",
+			Message: "resposta do Gemini bloqueada por reproduzir conteúdo protegido",
+			Reason:  reason,
+			Details: "O Gemini interrompe respostas que repetem textos publicados (livros, artigos)",
+		}
+	case "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return &GeminiResponseError{
+			Code:    "GEMINI_CONTENT_BLOCKED",
+			Message: "resposta do Gemini bloqueada por conteúdo proibido ou dados pessoais sensíveis",
+			Reason:  reason,
+		}
+	}
+
+	if textLength > 0 {
+		slog.WarnContext(ctx, "Gemini terminou a resposta antes do fim; usando o texto recebido", "finish_reason", reason)
+		return nil
+	}
+	return &GeminiResponseError{
+		Code:    "GEMINI_INCOMPLETE_RESPONSE",
+		Message: "Gemini terminou a resposta sem texto",
+		Reason:  reason,
+	}
+}
+
+// blockedCategories categorias marcadas como bloqueadas (ou de probabilidade alta, se nenhuma estiver marcada)
+func blockedCategories(ratings []GeminiSafetyRating) []string {
+	var blocked, high []string
+	for _, rating := range ratings {
+		if rating.Blocked {
+			blocked = append(blocked, rating.Category)
+		} else if rating.Probability == "HIGH" {
+			high = append(high, rating.Category)
+		}
+	}
+	if len(blocked) > 0 {
+		return blocked
+	}
+	return high
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy política de novas tentativas de uma chamada ao Gemini (independente do fallback entre modelos)
+type RetryPolicy struct {
+	// MaxAttempts tentativas por modelo/versão da API, incluindo a primeira
+	MaxAttempts int
+	// BaseDelay espera antes da segunda tentativa; dobra a cada nova tentativa
+	BaseDelay time.Duration
+	// MaxDelay teto da espera calculada pelo backoff
+	MaxDelay time.Duration
+	// Budget tempo total máximo de uma extração, somando tentativas e modelos
+	Budget time.Duration
+}
+
+// errRetryBudgetExceeded orçamento de tempo da extração esgotado
+var errRetryBudgetExceeded = errors.New("tempo máximo de tentativas com o Gemini esgotado")
+
+// canceledError a requisição terminou (cliente desconectou): novas chamadas ao Gemini seriam desperdício
+func canceledError(ctx context.Context) error {
+	return fmt.Errorf("requisição encerrada antes da resposta do Gemini: %w", context.Cause(ctx))
+}
+
+// sleepContext espera o intervalo ou até o contexto da requisição terminar
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return canceledError(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// geminiAPIError falha de uma chamada generateContent
+type geminiAPIError struct {
+	Model      string
+	APIVersion string
+	StatusCode int
+	Status     string
+	Message    string
+	// RetryAfter espera pedida pelo servidor (RetryInfo.retryDelay ou header Retry-After)
+	RetryAfter time.Duration
+	// Network erro de rede/timeout (sem resposta HTTP)
+	Network bool
+}
+
+func (e *geminiAPIError) Error() string {
+	switch {
+	case e.Network:
+		return fmt.Sprintf("erro ao fazer requisição: %s", e.Message)
+	case e.StatusCode == http.StatusNotFound:
+		return fmt.Sprintf("modelo %s não encontrado na API %s", e.Model, e.APIVersion)
+	case e.StatusCode == http.StatusTooManyRequests:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("cota excedida para modelo %s. Tente novamente em %s", e.Model, e.RetryAfter)
+		}
+		return fmt.Sprintf("cota excedida para modelo %s", e.Model)
+	case e.Status != "":
+		return fmt.Sprintf("erro da API Gemini: %s (status: %s, code: %d)", e.Message, e.Status, e.StatusCode)
+	}
+	return fmt.Sprintf("erro da API Gemini (status %d): %s", e.StatusCode, e.Message)
+}
+
+// retryable 429, 5xx e erros de rede podem ser repetidos
+func (e *geminiAPIError) retryable() bool {
+	return e.Network || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newGeminiAPIError monta o erro a partir da resposta HTTP (corpo já lido)
+func newGeminiAPIError(resp *http.Response, body []byte, model, apiVersion string) *geminiAPIError {
+	apiErr := &geminiAPIError{
+		Model:      model,
+		APIVersion: apiVersion,
+		StatusCode: resp.StatusCode,
+		Message:    string(body),
+	}
+
+	var errorResp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+		apiErr.Message = errorResp.Error.Message
+		apiErr.Status = errorResp.Error.Status
+		for _, detail := range errorResp.Error.Details {
+			if delay, err := time.ParseDuration(detail.RetryDelay); err == nil && delay > 0 {
+				apiErr.RetryAfter = delay
+				break
+			}
+		}
+	}
+
+	if apiErr.RetryAfter == 0 {
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return apiErr
+}
+
+// parseRetryAfter interpreta o header Retry-After (segundos ou data HTTP)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// backoff espera antes da tentativa attempt (2, 3...): exponencial com jitter,
+// nunca menor que a espera pedida pelo servidor
+func (p RetryPolicy) backoff(attempt int, serverDelay time.Duration) time.Duration {
+	delay := p.BaseDelay << uint(attempt-2)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	// Jitter: entre metade e o valor cheio, para não sincronizar tentativas concorrentes
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	if serverDelay > delay {
+		delay = serverDelay
+	}
+	return delay
+}
+
+// generateWithRetry chama um modelo repetindo falhas transitórias conforme a política,
+// sem ultrapassar o prazo total da extração
+func (s *GeminiService) generateWithRetry(ctx context.Context, request *generateRequest, apiVersion, model string, deadline time.Time) (*geminiOutput, error) {
+	var lastErr error
+	for attempt := 1; attempt <= s.retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			var apiErr *geminiAPIError
+			serverDelay := time.Duration(0)
+			if errors.As(lastErr, &apiErr) {
+				serverDelay = apiErr.RetryAfter
+			}
+			delay := s.retry.backoff(attempt, serverDelay)
+			if apiErr != nil && apiErr.StatusCode == http.StatusTooManyRequests && request.key == nil && s.keys.hasAvailable() {
+				// 429 pausou só a chave usada: tentar de imediato com outra chave do pool
+				delay = 0
+			}
+			if time.Now().Add(delay).After(deadline) {
+				// Esperar estouraria o orçamento: devolver a falha para o fallback decidir
+				return nil, lastErr
+			}
+			slog.InfoContext(ctx, "nova tentativa", "attempt", attempt, "max_attempts", s.retry.MaxAttempts, "model", model, "api_version", apiVersion, "delay", delay.Round(time.Millisecond).String(), "error", lastErr)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		output, err := s.generateOnce(ctx, request, apiVersion, model, deadline)
+		if err == nil {
+			return output, nil
+		}
+		lastErr = err
+
+		var apiErr *geminiAPIError
+		if !errors.As(err, &apiErr) || !apiErr.retryable() {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
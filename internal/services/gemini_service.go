@@ -3,27 +3,71 @@ package services
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/metrics"
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/processors"
+	"backend-fileprocessing/internal/redact"
+	"backend-fileprocessing/internal/requestid"
+	"backend-fileprocessing/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GeminiService serviço para comunicação com Google Gemini API
 type GeminiService struct {
-	apiKey       string
-	apiURL       string
-	modelOptions []string
+	// keys pool de chaves de API usado em rodízio
+	keys *keyPool
+	// baseURL endpoint da API (sem barra final); configurável para apontar para um servidor local
+	baseURL     string
+	apiVersions []string
+	// generationConfig e safetySettings enviados em todas as requisições generateContent
+	generationConfig *GeminiGenerationConfig
+	safetySettings   []GeminiSafetySetting
+	// uploadThreshold arquivos maiores que isso (bytes) vão pela Files API em vez de base64 inline
+	uploadThreshold int64
+	retry           RetryPolicy
+	health          *healthTracker
+	// configuredModels lista ordenada de GEMINI_MODELS; vazia usa a descoberta em cache
+	configuredModels []string
+	catalog          *modelCatalog
+	httpClient       *http.Client
 }
 
 // GeminiRequest estrutura da requisição para Gemini
 type GeminiRequest struct {
-	Contents []GeminiContent `json:"contents"`
+	Contents         []GeminiContent         `json:"contents"`
+	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings   []GeminiSafetySetting   `json:"safetySettings,omitempty"`
+}
+
+// GeminiGenerationConfig parâmetros de geração (campos vazios ficam com o padrão do modelo)
+type GeminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            int      `json:"topK,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	// ResponseMimeType "application/json" nas chamadas que esperam JSON
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+}
+
+// GeminiSafetySetting limite de bloqueio para uma categoria de conteúdo
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
 }
 
 // GeminiContent conteúdo para enviar ao Gemini
@@ -31,9 +75,10 @@ type GeminiContent struct {
 	Parts []GeminiPart `json:"parts"`
 }
 
-// GeminiPart parte do conteúdo (pode ser texto ou dados inline)
+// GeminiPart parte do conteúdo (texto, dados inline ou arquivo da Files API)
 type GeminiPart struct {
 	InlineData *GeminiInlineData `json:"inline_data,omitempty"`
+	FileData   *GeminiFileData   `json:"file_data,omitempty"`
 	Text       string            `json:"text,omitempty"`
 }
 
@@ -45,12 +90,37 @@ type GeminiInlineData struct {
 
 // GeminiResponse resposta do Gemini
 type GeminiResponse struct {
-	Candidates []GeminiCandidate `json:"candidates"`
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
 }
 
 // GeminiCandidate candidato de resposta
 type GeminiCandidate struct {
-	Content GeminiResponseContent `json:"content"`
+	Content       GeminiResponseContent `json:"content"`
+	FinishReason  string                `json:"finishReason,omitempty"`
+	SafetyRatings []GeminiSafetyRating  `json:"safetyRatings,omitempty"`
+}
+
+// GeminiPromptFeedback avaliação da entrada; blockReason preenchido quando o prompt foi bloqueado
+type GeminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason,omitempty"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GeminiSafetyRating avaliação de segurança de uma categoria
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// GeminiUsageMetadata contagem de tokens da chamada
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount,omitempty"`
+	TotalTokenCount      int `json:"totalTokenCount"`
 }
 
 // GeminiResponseContent conteúdo da resposta
@@ -63,55 +133,113 @@ type GeminiResponsePart struct {
 	Text string `json:"text"`
 }
 
+// geminiOutput texto e consumo de tokens de uma chamada bem-sucedida
+type geminiOutput struct {
+	text  string
+	usage *models.TokenUsage
+}
+
+// generateRequest uma chamada generateContent com fallback entre modelos
+type generateRequest struct {
+	body     []byte
+	fileType string
+	// key chave fixada: arquivos da Files API só são visíveis ao projeto da chave que os enviou (nil usa o pool)
+	key *apiKey
+}
+
+// pagePromptInstructions instruções de delimitação de páginas enviadas em todos os prompts de extração
+const pagePromptInstructions = `Antes do texto de cada página, escreva uma linha contendo apenas o delimitador "=== PÁGINA N ===", onde N é o número da página começando em 1.
+Arquivos sem paginação (imagens, por exemplo) devem ter apenas "=== PÁGINA 1 ===".`
+
+// pageMarkerPattern reconhece o delimitador de página pedido no prompt (o grupo captura o número)
+var pageMarkerPattern = regexp.MustCompile(`(?im)^[ \t]*=+[ \t]*P[ÁA]GINA[ \t]+(\d+)[ \t]*=+[ \t]*$`)
+
 // NewGeminiService cria novo serviço Gemini
-func NewGeminiService() *GeminiService {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Printf("⚠️ GEMINI_API_KEY não configurada - funcionalidade Gemini desabilitada")
-	}
-
-	// Usar gemini-1.5-flash (sem -latest) - modelo correto para processar arquivos
-	// Formato: v1beta/models/MODEL_NAME:generateContent
-	apiURL := "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent"
-	
-	if apiKey != "" {
-		log.Printf("✅ Gemini configurado - API URL: %s", apiURL)
-		log.Printf("✅ API Key configurada (primeiros 10 chars): %s...", apiKey[:min(10, len(apiKey))])
-	} else {
-		log.Printf("⚠️ GEMINI_API_KEY não configurada")
-	}
-	
-	return &GeminiService{
-		apiKey: apiKey,
-		apiURL: apiURL,
-		modelOptions: []string{
-			"gemini-1.5-flash-latest",
-			"gemini-1.5-pro-latest",
-			"gemini-1.5-flash",
-			"gemini-1.5-pro",
-		},
+func NewGeminiService(cfg *config.Config) *GeminiService {
+	for _, key := range cfg.GeminiAPIKeys {
+		redact.Register(key)
 	}
-}
+	keys := newKeyPool(cfg.GeminiAPIKeys, cfg.GeminiKeySelection)
+	if keys.size() == 0 {
+		slog.Warn("GEMINI_API_KEY não configurada: funcionalidade Gemini desabilitada")
+	}
+
+	// Formato: {baseURL}/{versão}/models/MODEL_NAME:generateContent
+	baseURL := strings.TrimRight(cfg.GeminiBaseURL, "/")
+	apiVersions := cfg.GeminiAPIVersions
+	if len(apiVersions) == 0 {
+		apiVersions = []string{"v1beta", "v1"}
+	}
+
+	if keys.size() > 0 {
+		slog.Info("Gemini configurado", "endpoint", baseURL, "api_versions", apiVersions, "default_model", cfg.GeminiDefaultModel,
+			"keys", keys.size(), "key_selection", keys.selection)
+	}
+
+	retry := RetryPolicy{
+		MaxAttempts: cfg.GeminiRetryMaxAttempts,
+		BaseDelay:   cfg.GeminiRetryBaseDelay,
+		MaxDelay:    cfg.GeminiRetryMaxDelay,
+		Budget:      cfg.GeminiRetryBudget,
+	}
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+	if retry.Budget <= 0 {
+		retry.Budget = 5 * time.Minute
+	}
+
+	service := &GeminiService{
+		keys:             keys,
+		baseURL:          baseURL,
+		apiVersions:      apiVersions,
+		generationConfig: newGenerationConfig(cfg),
+		safetySettings:   newSafetySettings(cfg.GeminiSafetySettings),
+		uploadThreshold:  cfg.GeminiUploadThreshold,
+		retry:            retry,
+		health:           newHealthTracker(cfg.GeminiBreakerThreshold, cfg.GeminiBreakerCooldown),
+		configuredModels: cfg.GeminiModels,
+		// Cliente HTTP com timeout de 5 minutos (para processar arquivos grandes)
+		httpClient: &http.Client{Timeout: 5 * 60 * time.Second},
+	}
+	service.catalog = newModelCatalog(service.listAvailableModels, cfg.GeminiModelsTTL, fallbackModels(cfg.GeminiDefaultModel))
 
-func min(a, b int) int {
-	if a < b {
-		return a
+	if len(service.configuredModels) > 0 {
+		slog.Info("modelos Gemini configurados", "models", service.configuredModels)
+	} else if keys.size() > 0 {
+		service.catalog.warmUp()
 	}
-	return b
+	return service
+}
+
+// ModelHealth estado de saúde e do circuit breaker de cada modelo/versão já utilizado
+func (s *GeminiService) ModelHealth() []models.ModelHealth {
+	return s.health.snapshot()
+}
+
+// KeyUsage contadores de uso de cada chave de API do pool
+func (s *GeminiService) KeyUsage() []models.APIKeyUsage {
+	return s.keys.snapshot()
+}
+
+// authorize envia a chave de API no header x-goog-api-key (nunca na URL, que acaba em logs de proxy
+// e em mensagens de erro do http.Client)
+func (s *GeminiService) authorize(req *http.Request, key *apiKey) {
+	req.Header.Set("x-goog-api-key", key.value)
 }
 
 // IsAvailable verifica se o serviço está disponível
 func (s *GeminiService) IsAvailable() bool {
-	return s.apiKey != ""
+	return s.keys.size() > 0
 }
 
 // ExtractTextFromPDF extrai texto de PDF usando Gemini
-func (s *GeminiService) ExtractTextFromPDF(fileReader io.Reader, filename string) (string, error) {
+func (s *GeminiService) ExtractTextFromPDF(ctx context.Context, fileReader io.Reader, filename string) (string, error) {
 	if !s.IsAvailable() {
 		return "", fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
 	}
 
-	log.Printf("🤖 Enviando PDF para Google Gemini (gratuito)...")
+	slog.DebugContext(ctx, "enviando PDF ao Gemini")
 
 	// Ler arquivo completo em buffer
 	fileBuffer := new(bytes.Buffer)
@@ -120,30 +248,24 @@ func (s *GeminiService) ExtractTextFromPDF(fileReader io.Reader, filename string
 		return "", fmt.Errorf("erro ao ler arquivo: %v", err)
 	}
 
-	// Converter para base64
-	base64Content := base64.StdEncoding.EncodeToString(fileBuffer.Bytes())
+	// Arquivo inline (base64) ou via Files API, conforme o tamanho
+	filePart, fileKey, cleanup, err := s.filePart(ctx, fileBuffer.Bytes(), "application/pdf", filename)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
 
 	// Criar requisição para Gemini
-	requestBody := GeminiRequest{
-		Contents: []GeminiContent{
-			{
-				Parts: []GeminiPart{
-					{
-						InlineData: &GeminiInlineData{
-							MimeType: "application/pdf",
-							Data:     base64Content,
-						},
-					},
-					{
-						Text: fmt.Sprintf(`Extraia TODO o texto deste PDF (%s) e retorne APENAS o texto extraído, sem comentários ou explicações adicionais. 
+	requestBody := s.newRequest(
+		filePart,
+		GeminiPart{
+			Text: fmt.Sprintf(`Extraia TODO o texto deste PDF (%s) e retorne APENAS o texto extraído, sem comentários ou explicações adicionais. 
 Se o PDF contiver imagens escaneadas, descreva o conteúdo das imagens também.
+%s
 
-Retorne apenas o texto puro extraído do documento.`, filename),
-					},
-				},
-			},
+Retorne apenas o texto puro extraído do documento.`, filename, pagePromptInstructions),
 		},
-	}
+	)
 
 	// Converter para JSON
 	jsonData, err := json.Marshal(requestBody)
@@ -152,332 +274,494 @@ Retorne apenas o texto puro extraído do documento.`, filename),
 	}
 
 	// Usar a mesma lógica de tentar múltiplos modelos
-	return s.tryRequestWithModels(jsonData, "PDF")
+	output, err := s.tryRequestWithModels(ctx, &generateRequest{body: jsonData, fileType: "PDF", key: fileKey})
+	if err != nil {
+		return "", err
+	}
+	text, _ := processors.JoinPages(splitPageMarkers(output.text))
+	return text, nil
 }
 
-// ExtractTextFromFile extrai texto de qualquer arquivo usando Gemini (PDF, imagens, DOCX, etc)
-func (s *GeminiService) ExtractTextFromFile(fileReader io.Reader, filename string) (string, error) {
+// ExtractTextFromFile extrai texto de qualquer arquivo usando Gemini (PDF, imagens, DOCX, etc), separado por página
+func (s *GeminiService) ExtractTextFromFile(ctx context.Context, fileReader io.Reader, filename string) (*processors.Result, error) {
 	if !s.IsAvailable() {
-		return "", fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
+		return nil, fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
 	}
 
 	// Detectar tipo MIME baseado na extensão
 	mimeType := getMimeType(filename)
-	
-	log.Printf("🤖 Enviando arquivo %s (%s) para Google Gemini...", filename, mimeType)
+
+	slog.DebugContext(ctx, "enviando arquivo ao Gemini", "mime_type", mimeType)
 
 	// Ler arquivo completo em buffer
 	fileBuffer := new(bytes.Buffer)
 	_, err := io.Copy(fileBuffer, fileReader)
 	if err != nil {
-		return "", fmt.Errorf("erro ao ler arquivo: %v", err)
+		return nil, fmt.Errorf("erro ao ler arquivo: %v", err)
 	}
 
-	fileSize := fileBuffer.Len()
-	log.Printf("📊 Tamanho do arquivo: %d bytes (%.2f MB)", fileSize, float64(fileSize)/1024/1024)
-
-	// Converter para base64
-	base64Content := base64.StdEncoding.EncodeToString(fileBuffer.Bytes())
-	base64Size := len(base64Content)
-	log.Printf("📦 Arquivo convertido para base64: %d caracteres (%.2f MB)", base64Size, float64(base64Size)/1024/1024)
-
-	// Verificar tamanho (Gemini tem limite de ~20MB em base64)
-	maxSize := 20 * 1024 * 1024 // 20MB
-	if base64Size > maxSize {
-		return "", fmt.Errorf("arquivo muito grande para Gemini: %.2f MB (limite: 20MB)", float64(base64Size)/1024/1024)
+	// Arquivo inline (base64) ou via Files API, conforme o tamanho
+	filePart, fileKey, cleanup, err := s.filePart(ctx, fileBuffer.Bytes(), mimeType, filename)
+	if err != nil {
+		return nil, err
 	}
+	defer cleanup()
 
 	// Criar requisição para Gemini
-	requestBody := GeminiRequest{
-		Contents: []GeminiContent{
-			{
-				Parts: []GeminiPart{
-					{
-						InlineData: &GeminiInlineData{
-							MimeType: mimeType,
-							Data:     base64Content,
-						},
-					},
-					{
-						Text: fmt.Sprintf(`Extraia TODO o texto deste arquivo (%s) e retorne APENAS o texto extraído, sem comentários ou explicações adicionais.
+	requestBody := s.newRequest(
+		filePart,
+		GeminiPart{
+			Text: fmt.Sprintf(`Extraia TODO o texto deste arquivo (%s) e retorne APENAS o texto extraído, sem comentários ou explicações adicionais.
 Se o arquivo contiver imagens, descreva o conteúdo das imagens também.
 Se for um documento (PDF, DOCX), extraia todo o texto presente.
+%s
 
-Retorne apenas o texto puro extraído do documento.`, filename),
-					},
-				},
-			},
+Retorne apenas o texto puro extraído do documento.`, filename, pagePromptInstructions),
 		},
-	}
+	)
 
 	// Converter para JSON
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar JSON: %v", err)
+		return nil, fmt.Errorf("erro ao criar JSON: %v", err)
 	}
 
-	log.Printf("📤 Enviando requisição para Gemini API (tamanho JSON: %d bytes)...", len(jsonData))
+	slog.DebugContext(ctx, "requisição generateContent montada", "file_bytes", fileBuffer.Len(), "request_bytes", len(jsonData))
 
 	// Tentar diferentes modelos até encontrar um disponível
-	return s.tryRequestWithModels(jsonData, "arquivo")
+	output, err := s.tryRequestWithModels(ctx, &generateRequest{body: jsonData, fileType: "arquivo", key: fileKey})
+	if err != nil {
+		return nil, err
+	}
+
+	text, pages := processors.JoinPages(splitPageMarkers(output.text))
+	slog.DebugContext(ctx, "texto separado em páginas", "pages", len(pages))
+	result := &processors.Result{Text: text, Pages: pages}
+	if output.usage != nil {
+		result.Usage = []models.TokenUsage{*output.usage}
+	}
+	return result, nil
+}
+
+// newRequest monta a requisição generateContent com os parâmetros de geração configurados
+func (s *GeminiService) newRequest(parts ...GeminiPart) GeminiRequest {
+	return GeminiRequest{
+		Contents:         []GeminiContent{{Parts: parts}},
+		GenerationConfig: s.generationConfig,
+		SafetySettings:   s.safetySettings,
+	}
+}
+
+// safetyCategories categorias que recebem o limite quando GEMINI_SAFETY_SETTINGS traz só o limite
+var safetyCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+}
+
+// newGenerationConfig monta o generationConfig da configuração (nil se nada foi configurado)
+func newGenerationConfig(cfg *config.Config) *GeminiGenerationConfig {
+	if cfg.GeminiTemperature == nil && cfg.GeminiTopP == nil && cfg.GeminiTopK <= 0 && cfg.GeminiMaxOutputTokens <= 0 {
+		return nil
+	}
+	return &GeminiGenerationConfig{
+		Temperature:     cfg.GeminiTemperature,
+		TopP:            cfg.GeminiTopP,
+		TopK:            cfg.GeminiTopK,
+		MaxOutputTokens: cfg.GeminiMaxOutputTokens,
+	}
+}
+
+// newSafetySettings interpreta entradas "CATEGORIA=LIMITE" ou "LIMITE" (aplicado a todas as categorias)
+func newSafetySettings(entries []string) []GeminiSafetySetting {
+	var settings []GeminiSafetySetting
+	for _, entry := range entries {
+		category, threshold, found := strings.Cut(entry, "=")
+		if !found {
+			for _, category := range safetyCategories {
+				settings = append(settings, GeminiSafetySetting{Category: category, Threshold: strings.ToUpper(entry)})
+			}
+			continue
+		}
+		category, threshold = strings.TrimSpace(category), strings.TrimSpace(threshold)
+		if category == "" || threshold == "" {
+			slog.Warn("entrada inválida em GEMINI_SAFETY_SETTINGS ignorada", "entry", entry)
+			continue
+		}
+		settings = append(settings, GeminiSafetySetting{Category: strings.ToUpper(category), Threshold: strings.ToUpper(threshold)})
+	}
+	return settings
+}
+
+// splitPageMarkers separa a resposta do Gemini nas páginas indicadas pelos delimitadores. Sem
+// delimitadores, ou com números fora da sequência 1..n, a resposta inteira vira uma única página.
+func splitPageMarkers(rawText string) []string {
+	matches := pageMarkerPattern.FindAllStringSubmatchIndex(rawText, -1)
+	if len(matches) == 0 {
+		return []string{strings.TrimSpace(rawText)}
+	}
+	sequential := true
+	for i, match := range matches {
+		if number, err := strconv.Atoi(rawText[match[2]:match[3]]); err != nil || number != i+1 {
+			sequential = false
+			break
+		}
+	}
+	if !sequential {
+		// Páginas puladas, repetidas ou fora de ordem: sem como atribuir o texto, só tirar os delimitadores
+		return []string{strings.TrimSpace(pageMarkerPattern.ReplaceAllString(rawText, ""))}
+	}
+
+	// Texto antes do primeiro delimitador pertence à primeira página
+	preamble := strings.TrimSpace(rawText[:matches[0][0]])
+
+	pageTexts := make([]string, 0, len(matches))
+	for i, match := range matches {
+		end := len(rawText)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		pageTexts = append(pageTexts, strings.TrimSpace(rawText[match[1]:end]))
+	}
+	if preamble != "" {
+		pageTexts[0] = strings.TrimSpace(preamble + "\n" + pageTexts[0])
+	}
+	return pageTexts
 }
 
 // tryRequestWithModels tenta diferentes modelos até encontrar um disponível
-func (s *GeminiService) tryRequestWithModels(jsonData []byte, fileType string) (string, error) {
-	// Primeiro, tentar listar modelos disponíveis
-	availableModels, err := s.listAvailableModels()
-	if err == nil && len(availableModels) > 0 {
-		log.Printf("✅ Modelos disponíveis encontrados: %v", availableModels)
-		modelsToTry := availableModels
-		// Tentar com os modelos disponíveis
-		return s.tryModels(jsonData, fileType, modelsToTry)
-	}
-	
-	log.Printf("⚠️ Não foi possível listar modelos, tentando lista padrão...")
-	// Lista padrão de modelos para tentar (em ordem de preferência)
-	modelsToTry := []string{
-		"gemini-1.5-flash-latest",  // Versão latest
-		"gemini-1.5-pro-latest",    // Versão latest
-		"gemini-1.5-flash",         // Versão estável
-		"gemini-1.5-pro",           // Versão estável
-		"gemini-pro",               // Modelo básico
-		"gemini-1.0-pro",           // Modelo mais antigo
-		"gemini-1.5-flash-002",     // Versão específica mais recente
-		"gemini-1.5-pro-002",       // Versão específica mais recente
-	}
-	
-	return s.tryModels(jsonData, fileType, modelsToTry)
-}
-
-// tryModels tenta uma lista específica de modelos
-func (s *GeminiService) tryModels(jsonData []byte, fileType string, modelsToTry []string) (string, error) {
-	
+func (s *GeminiService) tryRequestWithModels(ctx context.Context, request *generateRequest) (*geminiOutput, error) {
+	// Lista explícita da configuração tem prioridade sobre a descoberta
+	if len(s.configuredModels) > 0 {
+		return s.tryModels(ctx, request, s.configuredModels)
+	}
+	return s.tryModels(ctx, request, s.catalog.get(ctx))
+}
+
+// tryModels tenta uma lista específica de modelos (fallback). Falhas transitórias de cada modelo
+// são repetidas antes por generateWithRetry; o prazo total vale para todas as tentativas.
+// Modelos saudáveis são tentados primeiro e modelos com circuito aberto são pulados.
+func (s *GeminiService) tryModels(ctx context.Context, request *generateRequest, modelsToTry []string) (*geminiOutput, error) {
+
 	var lastErr error
-	
+	deadline := time.Now().Add(s.retry.Budget)
+
 	// Tentar com diferentes versões da API
-	apiVersions := []string{"v1beta", "v1"}
-	
-	for _, apiVersion := range apiVersions {
+	var candidates []modelCandidate
+	for _, apiVersion := range s.apiVersions {
 		for _, model := range modelsToTry {
-			modelURL := fmt.Sprintf("https://generativelanguage.googleapis.com/%s/models/%s:generateContent", apiVersion, model)
-			log.Printf("🔄 Tentando modelo: %s na API %s (para %s)", model, apiVersion, fileType)
-			
-			// Fazer requisição HTTP
-			req, err := http.NewRequest("POST", fmt.Sprintf("%s?key=%s", modelURL, s.apiKey), bytes.NewBuffer(jsonData))
-			if err != nil {
-				log.Printf("❌ Erro ao criar requisição HTTP: %v", err)
-				lastErr = fmt.Errorf("erro ao criar requisição: %v", err)
-				continue
-			}
-
-			req.Header.Set("Content-Type", "application/json")
-			
-			// Adicionar contexto com timeout explícito
-			ctx := req.Context()
-			ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*60*time.Second)
-			defer cancel()
-			req = req.WithContext(ctxWithTimeout)
-
-			// Cliente HTTP com timeout de 5 minutos (para processar arquivos grandes)
-			client := &http.Client{
-				Timeout: 5 * 60 * time.Second, // 5 minutos
-			}
-			
-			log.Printf("📡 Fazendo requisição HTTP para Gemini (timeout: 5 minutos)...")
-			log.Printf("📡 URL: %s (modelo: %s, API: %s)", modelURL, model, apiVersion)
-			requestStartTime := time.Now()
-			resp, err := client.Do(req)
-			requestDuration := time.Since(requestStartTime)
-			
-			if err != nil {
-				log.Printf("❌ Erro HTTP ao fazer requisição para Gemini: %v (após %v)", err, requestDuration)
-				// Verificar se foi timeout
-				if requestDuration >= 5*60*time.Second {
-					log.Printf("⚠️ Timeout! Requisição demorou mais de 5 minutos")
-				}
-				lastErr = fmt.Errorf("erro ao fazer requisição: %v", err)
-				continue
-			}
-			defer resp.Body.Close()
+			candidates = append(candidates, modelCandidate{model: model, apiVersion: apiVersion})
+		}
+	}
 
-			log.Printf("📥 Resposta do Gemini recebida (status: %d) para modelo %s na API %s (tempo: %v)", resp.StatusCode, model, apiVersion, requestDuration)
+	ordered, skipped := s.health.order(candidates)
+	if skipped > 0 {
+		slog.WarnContext(ctx, "modelos com circuito aberto serão pulados", "skipped", skipped)
+	}
+	if len(ordered) == 0 {
+		return nil, fmt.Errorf("nenhum modelo Gemini disponível: todos os %d modelos estão com o circuito aberto após falhas repetidas. Tente novamente em instantes", len(candidates))
+	}
 
-			if resp.StatusCode == http.StatusOK {
-				// Sucesso! Usar este modelo
-				log.Printf("✅ Modelo %s funcionou na API %s!", model, apiVersion)
-				// Parsear resposta normalmente abaixo
-				return s.parseGeminiResponse(resp, model)
+	for i, candidate := range ordered {
+		model, apiVersion := candidate.model, candidate.apiVersion
+		if time.Now().After(deadline) {
+			for _, pending := range ordered[i:] {
+				s.health.release(pending)
 			}
-			
-			// Se não foi 200, ler o erro mas continuar tentando
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			errorMsg := string(bodyBytes)
-			
-			if resp.StatusCode == 404 {
-				// Modelo não encontrado nesta versão, continuar tentando
-				log.Printf("⚠️ Modelo %s não encontrado na API %s, continuando...", model, apiVersion)
-				lastErr = fmt.Errorf("modelo %s não encontrado na API %s", model, apiVersion)
-				continue
+			slog.WarnContext(ctx, "tempo máximo esgotado, parando de tentar modelos", "budget", s.retry.Budget.String())
+			return nil, fmt.Errorf("%w (%v). Último erro: %v", errRetryBudgetExceeded, s.retry.Budget, lastErr)
+		}
+
+		slog.InfoContext(ctx, "tentando modelo", "model", model, "api_version", apiVersion, "purpose", request.fileType)
+		attemptCtx, span := tracing.Start(ctx, "gemini.tryModel", trace.WithAttributes(
+			attribute.String("gemini.model", model),
+			attribute.String("gemini.api_version", apiVersion),
+		))
+		output, err := s.generateWithRetry(attemptCtx, request, apiVersion, model, deadline)
+		tracing.End(span, err)
+		if err == nil {
+			s.health.recordSuccess(candidate)
+			for _, pending := range ordered[i+1:] {
+				s.health.release(pending)
 			}
-			
-			if resp.StatusCode == 429 {
-				// Quota excedida - tentar próximo modelo (pode ser que outro modelo tenha quota disponível)
-				log.Printf("⚠️ Cota excedida para modelo %s na API %s, tentando próximo modelo...", model, apiVersion)
-				// Parsear mensagem de retry
-				var errorResp struct {
-					Error struct {
-						Message string `json:"message"`
-						Details []struct {
-							RetryInfo struct {
-								RetryDelay string `json:"retryDelay"`
-							} `json:"retryInfo"`
-						} `json:"details"`
-					} `json:"error"`
-				}
-				if err := json.Unmarshal(bodyBytes, &errorResp); err == nil {
-					retryDelay := "alguns segundos"
-					if len(errorResp.Error.Details) > 0 && errorResp.Error.Details[0].RetryInfo.RetryDelay != "" {
-						retryDelay = errorResp.Error.Details[0].RetryInfo.RetryDelay
-					}
-					lastErr = fmt.Errorf("cota excedida para modelo %s. Tente novamente em %s", model, retryDelay)
-				} else {
-					lastErr = fmt.Errorf("cota excedida para modelo %s", model)
-				}
-				continue // Tentar próximo modelo
+			return output, nil
+		}
+		lastErr = err
+
+		var apiErr *geminiAPIError
+		var keysErr *keysExhaustedError
+		if errors.As(err, &keysErr) {
+			// Todas as chaves em pausa: outro modelo usaria as mesmas chaves
+			s.health.release(candidate)
+			for _, pending := range ordered[i+1:] {
+				s.health.release(pending)
 			}
-			
-			// Outro erro (400, 403, etc) - parar e retornar
-			log.Printf("❌ Erro da API Gemini (status %d) com modelo %s na API %s: %s", resp.StatusCode, model, apiVersion, errorMsg)
-			
-			var errorResp struct {
-				Error struct {
-					Code    int    `json:"code"`
-					Message string `json:"message"`
-					Status  string `json:"status"`
-				} `json:"error"`
+			slog.WarnContext(ctx, "todas as chaves do Gemini em pausa", "error", err)
+			return nil, err
+		}
+		if !errors.As(err, &apiErr) || !(apiErr.retryable() || apiErr.StatusCode == http.StatusNotFound) {
+			// Erro local (montar requisição, parsear resposta) ou da requisição (400, 403, etc):
+			// não diz nada sobre a saúde do modelo - parar e retornar
+			s.health.release(candidate)
+			for _, pending := range ordered[i+1:] {
+				s.health.release(pending)
 			}
-			
-			if err := json.Unmarshal(bodyBytes, &errorResp); err == nil && errorResp.Error.Message != "" {
-				return "", fmt.Errorf("erro da API Gemini: %s (status: %s, code: %d)", errorResp.Error.Message, errorResp.Error.Status, errorResp.Error.Code)
+			if apiErr != nil {
+				slog.ErrorContext(ctx, "erro da API Gemini", "status", apiErr.StatusCode, "model", model, "api_version", apiVersion, "error", apiErr.Message)
 			}
-			
-			return "", fmt.Errorf("erro da API Gemini (status %d): %s", resp.StatusCode, errorMsg)
+			return nil, err
+		}
+
+		if s.health.recordFailure(candidate, err) {
+			slog.WarnContext(ctx, "circuito aberto após falhas consecutivas", "model", model, "api_version", apiVersion)
+		}
+		if apiErr.StatusCode == http.StatusNotFound {
+			// Modelo não encontrado nesta versão, continuar tentando
+			slog.WarnContext(ctx, "modelo não encontrado, tentando o próximo", "model", model, "api_version", apiVersion)
+		} else {
+			// Cota, 5xx ou rede persistentes neste modelo - tentar o próximo (outro modelo pode ter cota)
+			slog.WarnContext(ctx, "modelo falhou após novas tentativas, tentando o próximo", "model", model, "api_version", apiVersion, "error", err)
 		}
 	}
-	
+
 	// Se chegou aqui, nenhum modelo funcionou
 	if strings.Contains(lastErr.Error(), "cota excedida") || strings.Contains(lastErr.Error(), "quota") {
-		return "", fmt.Errorf("cota gratuita do Gemini foi excedida. Por favor: 1) Aguarde alguns minutos e tente novamente, 2) Verifique sua cota em https://ai.dev/usage?tab=rate-limit, 3) Considere usar uma API Key diferente ou upgrade do plano. Último erro: %v", lastErr)
+		return nil, fmt.Errorf("cota gratuita do Gemini foi excedida. Por favor: 1) Aguarde alguns minutos e tente novamente, 2) Verifique sua cota em https://ai.dev/usage?tab=rate-limit, 3) Adicione mais chaves em GEMINI_API_KEYS ou considere um upgrade do plano. Último erro: %v", lastErr)
+	}
+	return nil, fmt.Errorf("nenhum modelo Gemini disponível. Último erro: %v", lastErr)
+}
+
+// generateOnce faz uma única chamada generateContent a um modelo
+func (s *GeminiService) generateOnce(ctx context.Context, request *generateRequest, apiVersion, model string, deadline time.Time) (output *geminiOutput, err error) {
+	ctx, span := tracing.Start(ctx, "gemini.generateContent", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("gemini.model", model),
+		attribute.String("gemini.api_version", apiVersion),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	key := request.key
+	if key != nil {
+		s.keys.use(key)
+	} else {
+		if key, err = s.keys.acquire(); err != nil {
+			return nil, err
+		}
+	}
+
+	modelURL := fmt.Sprintf("%s/%s/models/%s:generateContent", s.baseURL, apiVersion, model)
+
+	// Timeout da tentativa: 5 minutos, limitado ao prazo total da extração; a chamada é
+	// cancelada junto com a requisição do cliente
+	reqCtx, cancel := context.WithTimeout(ctx, 5*60*time.Second)
+	defer cancel()
+	reqCtx, cancelDeadline := context.WithDeadline(reqCtx, deadline)
+	defer cancelDeadline()
+
+	// Fazer requisição HTTP
+	req, err := http.NewRequestWithContext(reqCtx, "POST", modelURL, bytes.NewBuffer(request.body))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+	requestid.Inject(ctx, req.Header)
+	s.authorize(req, key)
+
+	slog.DebugContext(ctx, "chamando generateContent", "model", model, "api_version", apiVersion, "key", key.id)
+	requestStartTime := time.Now()
+	resp, err := s.httpClient.Do(req)
+	requestDuration := time.Since(requestStartTime)
+
+	if err != nil && ctx.Err() != nil {
+		// Cliente desconectou: não é falha do modelo nem da chave
+		slog.WarnContext(ctx, "chamada ao Gemini cancelada", "model", model, "api_version", apiVersion, "duration_ms", requestDuration.Milliseconds())
+		metrics.GeminiAttempts.WithLabelValues(model, apiVersion, "canceled").Inc()
+		return nil, canceledError(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "erro de rede na chamada ao Gemini", "model", model, "api_version", apiVersion, "error", err, "duration_ms", requestDuration.Milliseconds())
+		metrics.GeminiAttempts.WithLabelValues(model, apiVersion, "network").Inc()
+		s.keys.recordFailure(key)
+		return nil, &geminiAPIError{Model: model, APIVersion: apiVersion, Network: true, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	slog.InfoContext(ctx, "resposta do Gemini", "status", resp.StatusCode, "model", model, "api_version", apiVersion, "duration_ms", requestDuration.Milliseconds())
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	metrics.GeminiAttempts.WithLabelValues(model, apiVersion, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resp.StatusCode == http.StatusOK {
+		// Sucesso! Usar este modelo
+		s.keys.recordSuccess(key)
+		return s.parseGeminiResponse(ctx, resp, model)
 	}
-	return "", fmt.Errorf("nenhum modelo Gemini disponível. Último erro: %v", lastErr)
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	apiErr := newGeminiAPIError(resp, bodyBytes, model, apiVersion)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// Cota da chave esgotada: pausar até o retryDelay; as próximas tentativas usam outra chave
+		slog.WarnContext(ctx, "chave em pausa por cota excedida", "key", key.id, "retry_after", apiErr.RetryAfter.String())
+		s.keys.recordRateLimited(key, apiErr.RetryAfter)
+	} else {
+		s.keys.recordFailure(key)
+	}
+	return nil, apiErr
 }
 
 // listAvailableModels lista os modelos disponíveis na API
-func (s *GeminiService) listAvailableModels() ([]string, error) {
+func (s *GeminiService) listAvailableModels(ctx context.Context) (modelNames []string, err error) {
+	ctx, span := tracing.Start(ctx, "gemini.listModels", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		span.SetAttributes(attribute.Int("gemini.models", len(modelNames)))
+		tracing.End(span, err)
+	}()
+
 	if !s.IsAvailable() {
 		return nil, fmt.Errorf("Gemini não está disponível")
 	}
-	
+
 	// Endpoint para listar modelos
-	listURL := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models?key=%s", s.apiKey)
-	
-	req, err := http.NewRequest("GET", listURL, nil)
+	listURL := fmt.Sprintf("%s/%s/models", s.baseURL, s.apiVersions[0])
+
+	// Timeout curto: a listagem também roda em segundo plano para atualizar o cache
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	req.Header.Set("Content-Type", "application/json")
-	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	tracing.Inject(ctx, req.Header)
+	requestid.Inject(ctx, req.Header)
+	key, err := s.keys.acquire()
+	if err != nil {
+		return nil, err
+	}
+	s.authorize(req, key)
+
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("⚠️ Erro ao listar modelos (status %d): %s", resp.StatusCode, string(bodyBytes))
+		slog.WarnContext(ctx, "erro ao listar modelos", "status", resp.StatusCode, "body", string(bodyBytes))
 		return nil, fmt.Errorf("erro ao listar modelos: status %d", resp.StatusCode)
 	}
-	
+
 	var modelsResponse struct {
 		Models []struct {
 			Name string `json:"name"`
 		} `json:"models"`
 	}
-	
+
 	if err := json.NewDecoder(resp.Body).Decode(&modelsResponse); err != nil {
 		return nil, err
 	}
-	
-	var modelNames []string
+
 	// Modelos prioritários (gratuitos e simples)
 	priorityModels := []string{
-		"gemini-flash-latest",      // Modelo gratuito mais rápido
-		"gemini-pro-latest",        // Modelo gratuito básico
-		"gemini-2.0-flash",         // Flash 2.0 (gratuito)
-		"gemini-2.0-flash-lite",    // Flash Lite (mais barato)
+		"gemini-flash-latest",   // Modelo gratuito mais rápido
+		"gemini-pro-latest",     // Modelo gratuito básico
+		"gemini-2.0-flash",      // Flash 2.0 (gratuito)
+		"gemini-2.0-flash-lite", // Flash Lite (mais barato)
 	}
-	
+
 	// Primeiro, adicionar modelos prioritários se estiverem na lista
 	foundModels := make(map[string]bool)
 	for _, model := range modelsResponse.Models {
 		name := strings.TrimPrefix(model.Name, "models/")
 		foundModels[name] = true
 	}
-	
+
 	// Adicionar modelos prioritários primeiro
+	added := make(map[string]bool)
 	for _, priority := range priorityModels {
 		if foundModels[priority] {
 			modelNames = append(modelNames, priority)
+			added[priority] = true
 		}
 	}
-	
+
 	// Depois, adicionar outros modelos gemini que não sejam de embedding/imagem
 	for _, model := range modelsResponse.Models {
 		name := strings.TrimPrefix(model.Name, "models/")
 		// Filtrar: apenas modelos gemini que não sejam embedding, imagem, ou outros tipos especiais
-		if strings.HasPrefix(name, "gemini-") && 
-		   !strings.Contains(name, "embedding") &&
-		   !strings.Contains(name, "image") &&
-		   !strings.Contains(name, "imagen") &&
-		   !strings.Contains(name, "text-embedding") &&
-		   !strings.Contains(name, "aqa") &&
-		   !strings.Contains(name, "robotics") &&
-		   !strings.Contains(name, "computer-use") &&
-		   !foundModels[name] { // Evitar duplicatas
+		if strings.HasPrefix(name, "gemini-") &&
+			!strings.Contains(name, "embedding") &&
+			!strings.Contains(name, "image") &&
+			!strings.Contains(name, "imagen") &&
+			!strings.Contains(name, "text-embedding") &&
+			!strings.Contains(name, "aqa") &&
+			!strings.Contains(name, "robotics") &&
+			!strings.Contains(name, "computer-use") &&
+			!added[name] { // Evitar duplicatas
 			// Adicionar no final (depois dos prioritários)
 			modelNames = append(modelNames, name)
 		}
 	}
-	
+
 	return modelNames, nil
 }
 
-// parseGeminiResponse parseia a resposta do Gemini
-func (s *GeminiService) parseGeminiResponse(resp *http.Response, modelName string) (string, error) {
+// parseGeminiResponse parseia a resposta do Gemini: junta todas as partes de texto, converte
+// bloqueios e respostas truncadas em GeminiResponseError e lê a contagem de tokens (anexada
+// também às falhas, que consomem tokens)
+func (s *GeminiService) parseGeminiResponse(ctx context.Context, resp *http.Response, modelName string) (*geminiOutput, error) {
 
 	// Parsear resposta
 	var geminiResp GeminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
-		return "", fmt.Errorf("erro ao parsear resposta: %v", err)
+		return nil, fmt.Errorf("erro ao parsear resposta: %v", err)
 	}
 
-	// Extrair texto da resposta
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("resposta do Gemini não contém texto")
+	var usage []models.TokenUsage
+	if metadata := geminiResp.UsageMetadata; metadata != nil {
+		usage = []models.TokenUsage{{
+			Model:        modelName,
+			InputTokens:  metadata.PromptTokenCount,
+			OutputTokens: metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount,
+			TotalTokens:  metadata.TotalTokenCount,
+		}}
 	}
 
-	extractedText := geminiResp.Candidates[0].Content.Parts[0].Text
-	extractedText = strings.TrimSpace(extractedText)
+	// Prompt bloqueado: não há candidatos
+	if feedback := geminiResp.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return nil, processors.WithUsage(newPromptBlockedError(feedback), usage)
+	}
+	if len(geminiResp.Candidates) == 0 {
+		return nil, processors.WithUsage(&GeminiResponseError{Code: "GEMINI_EMPTY_RESPONSE", Message: "resposta do Gemini não contém candidatos"}, usage)
+	}
+
+	// Texto pode vir dividido em várias partes
+	candidate := geminiResp.Candidates[0]
+	var builder strings.Builder
+	for _, part := range candidate.Content.Parts {
+		builder.WriteString(part.Text)
+	}
+	extractedText := strings.TrimSpace(builder.String())
 
+	if err := finishReasonError(ctx, candidate, len(extractedText)); err != nil {
+		return nil, processors.WithUsage(err, usage)
+	}
+	if extractedText == "" {
+		return nil, processors.WithUsage(&GeminiResponseError{Code: "GEMINI_EMPTY_RESPONSE", Message: "resposta do Gemini não contém texto"}, usage)
+	}
 	if len(extractedText) < 10 {
-		return "", fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)")
+		return nil, processors.WithUsage(fmt.Errorf("Gemini extraiu pouco texto (menos de 10 caracteres)"), usage)
+	}
+
+	output := &geminiOutput{text: extractedText}
+	if len(usage) > 0 {
+		output.usage = &usage[0]
 	}
 
-	log.Printf("✅ Gemini extraiu texto: %d caracteres", len(extractedText))
-	return extractedText, nil
+	slog.InfoContext(ctx, "Gemini extraiu texto", "model", modelName, "chars", len(extractedText), "parts", len(candidate.Content.Parts))
+	return output, nil
 }
 
 // getMimeType retorna MIME type baseado na extensão do arquivo
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPageMarkers(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{"sem delimitadores", "texto corrido\n", []string{"texto corrido"}},
+		{"sequência", "=== PÁGINA 1 ===\num\n=== PAGINA 2 ===\ndois", []string{"um", "dois"}},
+		{"preâmbulo", "Segue o texto:\n=== PÁGINA 1 ===\num\n=== PÁGINA 2 ===\ndois", []string{"Segue o texto:\num", "dois"}},
+		{"fora de ordem", "=== PÁGINA 2 ===\ndois\n=== PÁGINA 1 ===\num", []string{"dois\n\num"}},
+		{"página pulada", "=== PÁGINA 1 ===\num\n=== PÁGINA 3 ===\ntrês", []string{"um\n\ntrês"}},
+		{"repetida", "=== PÁGINA 1 ===\num\n=== PÁGINA 1 ===\nde novo", []string{"um\n\nde novo"}},
+		{"não começa em 1", "=== PÁGINA 2 ===\ndois", []string{"dois"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := splitPageMarkers(tc.raw); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("splitPageMarkers(%q) = %q, esperado %q", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"backend-fileprocessing/internal/models"
+	"backend-fileprocessing/internal/redaction"
+)
+
+// wordsPrompt pede as palavras com caixas no formato nativo do Gemini (box_2d, 0 a 1000)
+const wordsPrompt = `Localize TODAS as palavras de texto visíveis nesta imagem, na ordem de leitura.
+Responda APENAS com um array JSON, um item por palavra, no formato:
+[{"text": "palavra", "box_2d": [ymin, xmin, ymax, xmax]}]
+As coordenadas devem ser normalizadas de 0 a 1000 em relação ao tamanho da imagem.`
+
+// geminiWord item da resposta de LocateWords
+type geminiWord struct {
+	Text string    `json:"text"`
+	Box  []float64 `json:"box_2d"`
+}
+
+// LocateWords pede ao Gemini as palavras da imagem com a posição de cada uma (usado para tarjar
+// imagens e páginas escaneadas)
+func (s *GeminiService) LocateWords(ctx context.Context, data []byte, mimeType string) ([]redaction.Word, *models.TokenUsage, error) {
+	if !s.IsAvailable() {
+		return nil, nil, fmt.Errorf("Gemini não está disponível - GEMINI_API_KEY não configurada")
+	}
+
+	filePart, fileKey, cleanup, err := s.filePart(ctx, data, mimeType, "pagina")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	requestBody := s.newRequest(filePart, GeminiPart{Text: wordsPrompt})
+	generationConfig := GeminiGenerationConfig{}
+	if s.generationConfig != nil {
+		generationConfig = *s.generationConfig
+	}
+	generationConfig.ResponseMimeType = "application/json"
+	requestBody.GenerationConfig = &generationConfig
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao criar JSON: %v", err)
+	}
+
+	output, err := s.tryRequestWithModels(ctx, &generateRequest{body: jsonData, fileType: "localização de palavras", key: fileKey})
+	if err != nil {
+		return nil, nil, err
+	}
+	words, err := parseWords(output.text)
+	if err != nil {
+		return nil, output.usage, err
+	}
+	slog.InfoContext(ctx, "Gemini localizou palavras", "words", len(words))
+	return words, output.usage, nil
+}
+
+// parseWords interpreta o array JSON (com ou sem bloco de código markdown), ignorando itens sem caixa válida
+func parseWords(text string) ([]redaction.Word, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+
+	var items []geminiWord
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, fmt.Errorf("resposta do Gemini com palavras em formato inválido: %v", err)
+	}
+
+	words := make([]redaction.Word, 0, len(items))
+	for _, item := range items {
+		if len(item.Box) != 4 || strings.TrimSpace(item.Text) == "" {
+			continue
+		}
+		ymin, xmin, ymax, xmax := item.Box[0], item.Box[1], item.Box[2], item.Box[3]
+		if ymin >= ymax || xmin >= xmax {
+			continue
+		}
+		words = append(words, redaction.Word{
+			Text: item.Text,
+			Box:  redaction.Box{X0: xmin / 1000, Y0: ymin / 1000, X1: xmax / 1000, Y1: ymax / 1000},
+		})
+	}
+	return words, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/models"
+)
+
+// nativeModel "modelo" das extrações nativas (TXT, XML), com tokens estimados localmente
+const nativeModel = "native"
+
+// charsPerToken média usada na estimativa local de tokens
+const charsPerToken = 4
+
+// usageDateLayout formato dos dias no relatório
+const usageDateLayout = "2006-01-02"
+
+// modelPrice preço em USD por 1 milhão de tokens
+type modelPrice struct {
+	model  string
+	input  float64
+	output float64
+}
+
+// usageKey agregação por dia, cliente e modelo
+type usageKey struct {
+	day    string
+	client string
+	model  string
+}
+
+// usageCounters totais de uma agregação
+type usageCounters struct {
+	requests     int64
+	inputTokens  int64
+	outputTokens int64
+	costUSD      float64
+}
+
+// UsageService contabiliza tokens e custo por cliente e por dia (em memória, pelos últimos
+// retentionDays dias)
+type UsageService struct {
+	mu            sync.Mutex
+	prices        []modelPrice
+	usage         map[usageKey]*usageCounters
+	retentionDays int
+	// prunedDay último dia em que os dias fora da retenção foram descartados
+	prunedDay string
+	now       func() time.Time
+}
+
+// NewUsageService cria o contador de uso com os preços configurados em GEMINI_PRICES
+func NewUsageService(cfg *config.Config) *UsageService {
+	prices := parsePrices(cfg.GeminiPrices)
+	if len(prices) > 0 {
+		slog.Info("preços configurados", "models", len(prices))
+	}
+	retentionDays := cfg.UsageRetentionDays
+	if retentionDays < 1 {
+		retentionDays = 1
+	}
+	return &UsageService{
+		prices:        prices,
+		usage:         make(map[usageKey]*usageCounters),
+		retentionDays: retentionDays,
+		now:           time.Now,
+	}
+}
+
+// parsePrices interpreta entradas "modelo=entrada:saída" (USD por 1M de tokens)
+func parsePrices(entries []string) []modelPrice {
+	var prices []modelPrice
+	for _, entry := range entries {
+		model, values, found := strings.Cut(entry, "=")
+		input, output, foundOutput := strings.Cut(values, ":")
+		inputPrice, errInput := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		outputPrice, errOutput := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		model = strings.TrimSpace(model)
+		if !found || !foundOutput || model == "" || errInput != nil || errOutput != nil {
+			slog.Warn("entrada inválida em GEMINI_PRICES ignorada (use modelo=entrada:saída)", "entry", entry)
+			continue
+		}
+		prices = append(prices, modelPrice{model: model, input: inputPrice, output: outputPrice})
+	}
+	// Prefixo mais longo primeiro: "gemini-2.0-flash-lite" antes de "gemini-2.0-flash"
+	sort.SliceStable(prices, func(i, j int) bool {
+		return len(prices[i].model) > len(prices[j].model)
+	})
+	return prices
+}
+
+// cost custo em USD de um consumo; modelos sem preço custam zero
+func (u *UsageService) cost(usage models.TokenUsage) float64 {
+	for _, price := range u.prices {
+		if strings.HasPrefix(usage.Model, price.model) {
+			cost := float64(usage.InputTokens)*price.input/1e6 + float64(usage.OutputTokens)*price.output/1e6
+			return math.Round(cost*1e6) / 1e6
+		}
+	}
+	return 0
+}
+
+// EstimateNative estimativa local de tokens para extrações sem Gemini
+func EstimateNative(text string) models.TokenUsage {
+	tokens := (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+	return models.TokenUsage{
+		Model:        nativeModel,
+		OutputTokens: tokens,
+		TotalTokens:  tokens,
+		Estimated:    true,
+	}
+}
+
+// Record contabiliza o consumo de uma chamada para o cliente, preenchendo o custo de cada item
+func (u *UsageService) Record(client string, usage []models.TokenUsage) {
+	if client == "" {
+		client = "anonymous"
+	}
+	day := u.now().UTC().Format(usageDateLayout)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.prune(day)
+	for i := range usage {
+		usage[i].CostUSD = u.cost(usage[i])
+
+		key := usageKey{day: day, client: client, model: usage[i].Model}
+		counters, ok := u.usage[key]
+		if !ok {
+			counters = &usageCounters{}
+			u.usage[key] = counters
+		}
+		counters.requests++
+		counters.inputTokens += int64(usage[i].InputTokens)
+		counters.outputTokens += int64(usage[i].OutputTokens)
+		counters.costUSD += usage[i].CostUSD
+	}
+}
+
+// prune descarta os dias fora da janela de retenção, uma vez por dia; chamado com mu travado
+func (u *UsageService) prune(today string) {
+	if today == u.prunedDay {
+		return
+	}
+	u.prunedDay = today
+	day, _ := time.Parse(usageDateLayout, today)
+	oldest := day.AddDate(0, 0, -(u.retentionDays - 1)).Format(usageDateLayout)
+	for key := range u.usage {
+		if key.day < oldest {
+			delete(u.usage, key)
+		}
+	}
+}
+
+// Report relatório de uso entre from e to (inclusive, formato AAAA-MM-DD), opcionalmente de um cliente
+func (u *UsageService) Report(from, to, client string) (*models.UsageReport, error) {
+	today := u.now().UTC().Format(usageDateLayout)
+	if to == "" {
+		to = today
+	}
+	if from == "" {
+		from = to
+	}
+	for _, day := range []string{from, to} {
+		if _, err := time.Parse(usageDateLayout, day); err != nil {
+			return nil, fmt.Errorf("data inválida %q: use o formato AAAA-MM-DD", day)
+		}
+	}
+	if from > to {
+		return nil, fmt.Errorf("período inválido: %s é posterior a %s", from, to)
+	}
+
+	report := &models.UsageReport{From: from, To: to, Client: client, Currency: "USD"}
+
+	u.mu.Lock()
+	for key, counters := range u.usage {
+		if key.day < from || key.day > to || (client != "" && key.client != client) {
+			continue
+		}
+		report.Items = append(report.Items, models.UsageItem{
+			Date:         key.day,
+			Client:       key.client,
+			Model:        key.model,
+			Requests:     counters.requests,
+			InputTokens:  counters.inputTokens,
+			OutputTokens: counters.outputTokens,
+			CostUSD:      math.Round(counters.costUSD*1e6) / 1e6,
+		})
+	}
+	u.mu.Unlock()
+
+	sort.Slice(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		return a.Model < b.Model
+	})
+	for _, item := range report.Items {
+		report.Totals.Requests += item.Requests
+		report.Totals.InputTokens += item.InputTokens
+		report.Totals.OutputTokens += item.OutputTokens
+		report.Totals.CostUSD += item.CostUSD
+	}
+	report.Totals.CostUSD = math.Round(report.Totals.CostUSD*1e6) / 1e6
+	return report, nil
+}
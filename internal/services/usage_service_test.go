@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend-fileprocessing/internal/config"
+	"backend-fileprocessing/internal/models"
+)
+
+func TestUsageRetentionDropsOldDays(t *testing.T) {
+	usage := NewUsageService(&config.Config{UsageRetentionDays: 2})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	usage.now = func() time.Time { return now }
+
+	for day := 0; day < 5; day++ {
+		usage.Record("cliente", []models.TokenUsage{{Model: "gemini-2.0-flash", InputTokens: 10}})
+		now = now.AddDate(0, 0, 1)
+	}
+	now = now.AddDate(0, 0, -1)
+
+	// Só o dia atual e o anterior continuam em memória
+	if len(usage.usage) != 2 {
+		t.Fatalf("esperava 2 dias retidos, há %d agregações", len(usage.usage))
+	}
+	report, err := usage.Report("2026-03-01", "2026-03-05", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 2 || report.Items[0].Date != "2026-03-04" || report.Totals.InputTokens != 20 {
+		t.Fatalf("relatório inesperado: %+v", report)
+	}
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName nome do tracer usado em todos os spans do serviço
+const instrumentationName = "backend-fileprocessing"
+
+// propagator formato W3C (traceparent/tracestate e baggage), usado mesmo sem exportador para
+// repassar o trace do chamador adiante
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Exportadores aceitos em OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Config configuração do tracing
+type Config struct {
+	// Exporter none, otlp ou stdout
+	Exporter string
+	// Endpoint URL base do coletor OTLP/HTTP ("http://localhost:4318"); vazio usa o padrão do exportador
+	Endpoint    string
+	ServiceName string
+	// SampleRatio fração (0 a 1) dos traces iniciados aqui que são gravados; traces vindos do
+	// chamador seguem a decisão dele
+	SampleRatio float64
+}
+
+// Setup instala o propagador W3C e, com um exportador configurado, o provedor de traces global.
+// A função devolvida envia os spans pendentes e deve ser chamada ao encerrar.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			endpoint, err := otlpTracesURL(cfg.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("exportador de traces %q inválido: use none, otlp ou stdout", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exportador de traces: %v", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = instrumentationName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao montar o resource dos traces: %v", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio < 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	slog.Info("tracing ativo", "exporter", strings.ToLower(cfg.Exporter), "sample_ratio", ratio)
+	return provider.Shutdown, nil
+}
+
+// otlpTracesURL completa a URL base do coletor com o caminho padrão /v1/traces (como faz
+// OTEL_EXPORTER_OTLP_ENDPOINT); URLs que já trazem um caminho são usadas como estão
+func otlpTracesURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("endpoint OTLP inválido %q: use http(s)://host:porta", endpoint)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = "/v1/traces"
+	}
+	return parsed.String(), nil
+}
+
+// Start abre um span filho do span presente em ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End registra o erro (se houver) e encerra o span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract lê o contexto de trace (traceparent) dos headers de uma requisição recebida
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject escreve o contexto de trace do span atual nos headers de uma requisição enviada
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// FileAttributes atributos comuns aos spans que tratam um arquivo (o nome fica de fora por poder
+// conter dados pessoais)
+func FileAttributes(fileType string, size int64) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("file.type", fileType),
+		attribute.Int64("file.size", size),
+	)
+}
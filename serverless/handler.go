@@ -1,6 +1,7 @@
 package serverless
 
 import (
+	"log/slog"
 	"net/http"
 	"sync"
 
@@ -19,6 +20,9 @@ func initHandler() {
 	_ = godotenv.Load()
 	cfg := config.Load()
 	handler = server.NewRouter(cfg)
+	if _, err := server.SetupTracing(cfg); err != nil {
+		slog.Warn("tracing desativado", "error", err)
+	}
 }
 
 // NewHandler retorna um http.Handler inicializado para uso em ambientes serverless.